@@ -0,0 +1,45 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+)
+
+// anchors holds the times anchored VWAPs have been started from per product, in
+// the order they were started. It is safe for concurrent use, and a nil *anchors
+// holds none.
+type anchors struct {
+	mu    sync.Mutex
+	times map[coinbase.ProductID][]time.Time
+}
+
+func newAnchors() *anchors {
+	return &anchors{times: make(map[coinbase.ProductID][]time.Time)}
+}
+
+// add starts an anchored VWAP of productID from anchor.
+func (a *anchors) add(productID coinbase.ProductID, anchor time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.times[productID] = append(a.times[productID], anchor)
+}
+
+// since returns the anchors of productID after the first started, which are
+// already known.
+func (a *anchors) since(productID coinbase.ProductID, started int) []time.Time {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if started >= len(a.times[productID]) {
+		return nil
+	}
+
+	return append([]time.Time(nil), a.times[productID][started:]...)
+}
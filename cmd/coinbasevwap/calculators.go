@@ -0,0 +1,74 @@
+package main
+
+import (
+	"time"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+	"github.com/byatesrae/coinbase_vwap/internal/vwap"
+)
+
+// vwapUpdate is every VWAP of a product as of a trade, as output to a vwapSink.
+type vwapUpdate struct {
+	// When the trade happened.
+	at time.Time
+
+	windows []vwap.WindowVWAP
+
+	// If there's no session anchor, nil.
+	session *vwap.AnchorVWAP
+
+	// In the order they were started.
+	anchored []vwap.AnchorVWAP
+
+	// The latest spread, or nil if there's none.
+	spread *decimal.Decimal
+}
+
+// productCalculators calculates every VWAP of a product.
+type productCalculators struct {
+	productID coinbase.ProductID
+
+	// The VWAPs are rounded to a multiple of this.
+	increment decimal.Decimal
+
+	windows *vwap.MultiWindowVWAP
+
+	// If there's no session anchor, nil.
+	session *vwap.SessionVWAP
+
+	// Anchored VWAPs are started from these (if not nil) as they're added.
+	productAnchors *anchors
+	anchored       []*vwap.AnchoredVWAP
+
+	// If not nil, the latest spread (if any) is included.
+	productSpreads *spreads
+}
+
+// add records a new trade (see vwap.MultiWindowVWAP.Add) with every calculator,
+// returning the new VWAPs.
+func (p *productCalculators) add(at time.Time, side vwap.Side, units, unitPrice decimal.Decimal) vwapUpdate {
+	for _, anchor := range p.productAnchors.since(p.productID, len(p.anchored)) {
+		p.anchored = append(p.anchored, vwap.NewAnchoredVWAP(anchor, p.increment))
+	}
+
+	update := vwapUpdate{
+		at:      at,
+		windows: p.windows.Add(at, side, units, unitPrice),
+	}
+
+	if p.session != nil {
+		session := p.session.Add(at, units, unitPrice)
+		update.session = &session
+	}
+
+	for _, anchored := range p.anchored {
+		update.anchored = append(update.anchored, anchored.Add(at, units, unitPrice))
+	}
+
+	if spread, ok := p.productSpreads.get(p.productID, p.increment); ok {
+		update.spread = &spread
+	}
+
+	return update
+}
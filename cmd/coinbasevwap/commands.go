@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+)
+
+// productUpdater adds and removes products of the app's subscriptions while they
+// are running, starting the output for each product the first time it's added.
+// It is safe for concurrent use.
+type productUpdater struct {
+	printer      *vwapPrinter
+	subscription *coinbase.MatchesSubscription
+
+	// If not showing spreads, these are nil.
+	tickerSubscription *coinbase.TickerSubscription
+	productSpreads     *spreads
+
+	wg *sync.WaitGroup
+	w  io.Writer
+
+	mu      sync.Mutex
+	stopped bool
+
+	// Products whose VWAP (and spread) output has been started.
+	printing       map[coinbase.ProductID]bool
+	trackingSpread map[coinbase.ProductID]bool
+}
+
+// newProductUpdater creates a new productUpdater, for the subscription of printer
+// (and tickerSubscription, if not nil) whose output has already been started for
+// its products. wg is used as it is by printer.
+func newProductUpdater(printer *vwapPrinter, tickerSubscription *coinbase.TickerSubscription, wg *sync.WaitGroup, w io.Writer) *productUpdater {
+	p := &productUpdater{
+		printer:            printer,
+		subscription:       printer.subscription,
+		tickerSubscription: tickerSubscription,
+		productSpreads:     printer.productSpreads,
+		wg:                 wg,
+		w:                  w,
+		printing:           make(map[coinbase.ProductID]bool),
+		trackingSpread:     make(map[coinbase.ProductID]bool),
+	}
+
+	for _, productID := range printer.subscription.ProductIDs() {
+		p.printing[productID] = true
+	}
+
+	if tickerSubscription != nil {
+		for _, productID := range tickerSubscription.ProductIDs() {
+			p.trackingSpread[productID] = true
+		}
+	}
+
+	return p
+}
+
+// add subscribes to productID and starts its output.
+func (p *productUpdater) add(ctx context.Context, productID coinbase.ProductID) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopped {
+		return fmt.Errorf("stopped")
+	}
+
+	if err := p.subscription.Subscribe(ctx, productID); err != nil {
+		return fmt.Errorf("subscribe to matches: %w", err)
+	}
+
+	if !p.printing[productID] {
+		p.printing[productID] = true
+		p.printer.start(productID)
+	}
+
+	if p.tickerSubscription == nil {
+		return nil
+	}
+
+	if err := p.tickerSubscription.Subscribe(ctx, productID); err != nil {
+		return fmt.Errorf("subscribe to ticker: %w", err)
+	}
+
+	if !p.trackingSpread[productID] {
+		p.trackingSpread[productID] = true
+		startTrackingSpread(p.tickerSubscription, productID, p.productSpreads, p.wg, p.w)
+	}
+
+	return nil
+}
+
+// remove unsubscribes from productID. Its output stops, but is resumed if it's
+// added again.
+func (p *productUpdater) remove(ctx context.Context, productID coinbase.ProductID) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopped {
+		return fmt.Errorf("stopped")
+	}
+
+	if err := p.subscription.Unsubscribe(ctx, productID); err != nil {
+		return fmt.Errorf("unsubscribe from matches: %w", err)
+	}
+
+	if p.tickerSubscription == nil {
+		return nil
+	}
+
+	if err := p.tickerSubscription.Unsubscribe(ctx, productID); err != nil {
+		return fmt.Errorf("unsubscribe from ticker: %w", err)
+	}
+
+	return nil
+}
+
+// anchor starts an anchored VWAP of productID from at, which must have been added.
+// Only trades read from now are included, earlier trades after at aren't backfilled.
+func (p *productUpdater) anchor(productID coinbase.ProductID, at time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopped {
+		return fmt.Errorf("stopped")
+	}
+
+	if !p.printing[productID] {
+		return fmt.Errorf("not added")
+	}
+
+	p.printer.productAnchors.add(productID, at)
+
+	return nil
+}
+
+// stop stops any further products being added or removed, waiting for any in
+// progress. It should be invoked before the subscriptions are closed.
+func (p *productUpdater) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stopped = true
+}
+
+// readCommands reads commands from r, one per line, until r is exhausted, and
+// applies them with updater. Commands are either "add <product ID>", "remove
+// <product ID>" or "anchor <product ID> [time]" (RFC 3339, or now if omitted). The
+// outcome of each is output to w.
+func readCommands(r io.Reader, updater *productUpdater, w io.Writer) {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) != 2 && (len(fields) != 3 || fields[0] != "anchor") {
+			fmt.Fprintf(w, "COMMAND ERROR: expected \"add <product ID>\", \"remove <product ID>\" or \"anchor <product ID> [time]\", got %q\n", scanner.Text())
+			continue
+		}
+
+		productID := coinbase.ProductID(strings.ToUpper(fields[1]))
+
+		ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second*5)
+
+		var err error
+
+		switch fields[0] {
+		case "add":
+			err = updater.add(ctx, productID)
+		case "remove":
+			err = updater.remove(ctx, productID)
+		case "anchor":
+			at := time.Now()
+			if len(fields) == 3 {
+				at, err = time.Parse(time.RFC3339, fields[2])
+			}
+
+			if err != nil {
+				err = fmt.Errorf("parse anchor time: %w", err)
+			} else {
+				err = updater.anchor(productID, at)
+			}
+		default:
+			err = fmt.Errorf("unknown command %q", fields[0])
+		}
+
+		ctxCancel()
+
+		if err != nil {
+			fmt.Fprintf(w, "%q COMMAND ERROR: %v\n", productID, err)
+			continue
+		}
+
+		fmt.Fprintf(w, "%q COMMAND OK: %s\n", productID, fields[0])
+	}
+
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(w, "COMMAND ERROR: read commands: %v\n", err)
+	}
+}
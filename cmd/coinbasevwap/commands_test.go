@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+)
+
+func TestReadCommands(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(ctxCancel)
+
+	coinbaseClient := &coinbase.Client{Dialer: newSubscriptionsDialerFake(t)}
+
+	subscription, err := coinbaseClient.SubscribeToMatchesForProducts(ctx, []coinbase.ProductID{coinbase.ProductIDBtcUsd})
+	require.NoError(t, err, "SubscribeToMatchesForProducts")
+
+	wg := sync.WaitGroup{}
+	sb := stringBuilderMutex{}
+
+	printer := &vwapPrinter{
+		subscription:   subscription,
+		productAnchors: newAnchors(),
+		increments:     newQuoteIncrements(nil),
+		sink:           &textSink{w: &sb},
+		wg:             &wg,
+	}
+	printer.startAll()
+
+	updater := newProductUpdater(printer, nil, &wg, &sb)
+
+	// Do
+
+	readCommands(
+		strings.NewReader(
+			"add eth-usd\n\nremove BTC-USD\nremove ETH-USD\nTestABC\nTestDEF ETH-BTC\n"+
+				"anchor btc-usd 2022-10-01T12:00:00Z\nanchor ETH-BTC\nanchor BTC-USD TestABC\nadd ETH-BTC 2022-10-01T12:00:00Z\n",
+		),
+		updater,
+		&sb,
+	)
+
+	updater.stop()
+	require.NoError(t, subscription.Close(ctx), "Close")
+	wg.Wait()
+
+	// Assert
+
+	assert.Equal(
+		t,
+		"\"ETH-USD\" COMMAND OK: add\n"+
+			"\"BTC-USD\" COMMAND OK: remove\n"+
+			"\"ETH-USD\" COMMAND ERROR: unsubscribe from matches: unsubscribing from every product is not supported, use Close\n"+
+			"COMMAND ERROR: expected \"add <product ID>\", \"remove <product ID>\" or \"anchor <product ID> [time]\", got \"TestABC\"\n"+
+			"\"ETH-BTC\" COMMAND ERROR: unknown command \"TestDEF\"\n"+
+			"\"BTC-USD\" COMMAND OK: anchor\n"+
+			"\"ETH-BTC\" COMMAND ERROR: not added\n"+
+			"\"BTC-USD\" COMMAND ERROR: parse anchor time: parsing time \"TestABC\" as \"2006-01-02T15:04:05Z07:00\": cannot parse \"TestABC\" as \"2006\"\n"+
+			"COMMAND ERROR: expected \"add <product ID>\", \"remove <product ID>\" or \"anchor <product ID> [time]\", got \"add ETH-BTC 2022-10-01T12:00:00Z\"\n",
+		sb.sb.String(),
+		"Output",
+	)
+	assert.Equal(
+		t,
+		[]time.Time{time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)},
+		printer.productAnchors.since(coinbase.ProductIDBtcUsd, 0),
+		"Anchors",
+	)
+	assert.Equal(t, []coinbase.ProductID{coinbase.ProductIDEthUsd}, subscription.ProductIDs(), "ProductIDs")
+}
+
+// newSubscriptionsDialerFake creates a Dialer fake whose connections respond to
+// every subscribe or unsubscribe request with a "subscriptions" message, as the
+// server would, and read nothing else.
+func newSubscriptionsDialerFake(t *testing.T) *DialerMock {
+	t.Helper()
+
+	return &DialerMock{
+		DialContextFunc: func(_ context.Context, _ string, _ http.Header) (coinbase.Conn, *http.Response, error) {
+			var subscribed []coinbase.ProductID
+
+			messages := make(chan []byte, 10)
+
+			closed := make(chan struct{})
+			closedOnce := sync.Once{}
+
+			return &ConnMock{
+				WriteJSONFunc: func(v interface{}) error {
+					request, ok := v.(coinbase.SubscribeRequest)
+					if !ok {
+						return fmt.Errorf("test, unexpected request %T", v)
+					}
+
+					// Only the first channel (i.e. matches) is tracked.
+					for _, productID := range request.Channels[0].ProductIDs {
+						switch request.Type {
+						case "subscribe":
+							subscribed = append(subscribed, productID)
+						case "unsubscribe":
+							for a := range subscribed {
+								if subscribed[a] == productID {
+									subscribed = append(subscribed[:a], subscribed[a+1:]...)
+									break
+								}
+							}
+						}
+					}
+
+					b, err := json.Marshal(map[string]interface{}{
+						"type":     coinbase.MessageTypeSubscriptions,
+						"channels": []coinbase.SubscribeChannelRequest{{Name: coinbase.ChannelNameMatches, ProductIDs: subscribed}},
+					})
+					require.NoError(t, err, "marshal subscriptions")
+
+					messages <- b
+
+					return nil
+				},
+				ReadJSONFunc: func(v interface{}) error {
+					select {
+					case <-closed:
+						return fmt.Errorf("test, close called during ReadJSON")
+					case message := <-messages:
+						*v.(*json.RawMessage) = message
+
+						return nil
+					}
+				},
+				WriteControlFunc: func(messageType int, data []byte, deadline time.Time) error {
+					if messageType == websocket.CloseMessage {
+						closedOnce.Do(func() { close(closed) })
+					}
+
+					return nil
+				},
+				SetReadDeadlineFunc: func(t time.Time) error { return nil },
+				CloseFunc: func() error {
+					closedOnce.Do(func() { close(closed) })
+
+					return nil
+				},
+			}, nil, nil
+		},
+	}
+}
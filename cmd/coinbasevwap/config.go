@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+	"github.com/byatesrae/coinbase_vwap/internal/vwap"
+)
+
+// Environment variables used to configure the application.
+const (
+	// The Coinbase environment to connect to, e.g. "production" (default) or "sandbox".
+	envEnvironment = "COINBASE_VWAP_ENVIRONMENT"
+
+	// Overrides the websocket feed URL of the environment, e.g. to use a mock feed.
+	envFeedURL = "COINBASE_VWAP_FEED_URL"
+
+	// Overrides the REST API URL of the environment (used for backfill).
+	envRESTURL = "COINBASE_VWAP_REST_URL"
+
+	// A JSON file of API credentials to authenticate with. Alternatively, see
+	// coinbase.CredentialsFromEnv.
+	envCredentialsFile = "COINBASE_VWAP_CREDENTIALS_FILE"
+
+	// If true, the spread (from the ticker channel) is output alongside each VWAP.
+	envShowSpread = "COINBASE_VWAP_SHOW_SPREAD"
+
+	// If true, the buy and sell side VWAPs and volumes, and the volume imbalance, are
+	// output alongside each VWAP.
+	envShowSides = "COINBASE_VWAP_SHOW_SIDES"
+
+	// If true, the volume-weighted standard deviation and ±1/2/3σ bands are output
+	// alongside each VWAP.
+	envShowBands = "COINBASE_VWAP_SHOW_BANDS"
+
+	// The number of messages buffered for each product, see coinbase.Backpressure.
+	envReadBuffer = "COINBASE_VWAP_READ_BUFFER"
+
+	// What to do when a product's buffer is full, see coinbase.OverflowPolicy.
+	envOverflow = "COINBASE_VWAP_OVERFLOW"
+
+	// A comma separated list of the windows VWAPs are calculated over, each either
+	// a number of trades or a duration, e.g. "50,200,1000,1m,5m,1h". Defaults to "200".
+	envWindows = "COINBASE_VWAP_WINDOWS"
+
+	// The time of day, and optional location, a session VWAP starts from each day,
+	// e.g. "00:00" (UTC) or "09:30 America/New_York". If not set, there is none.
+	envSessionAnchor = "COINBASE_VWAP_SESSION_ANCHOR"
+
+	// The format VWAPs are output in, either "text" (default) or "json" (one object
+	// per line).
+	envOutput = "COINBASE_VWAP_OUTPUT"
+)
+
+// Output formats, see envOutput.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// appOptions are options for what the application outputs.
+type appOptions struct {
+	showSpread bool
+	showSides  bool
+	showBands  bool
+
+	// If empty, the last 200 trades.
+	windows []vwap.Window
+
+	// If nil, there's no session VWAP.
+	sessionAnchor *vwap.DailyAnchor
+
+	// If empty, outputText.
+	output string
+}
+
+// newAppOptions creates the appOptions of the application, configured from environment
+// variables read with getenv.
+func newAppOptions(getenv func(string) string) (appOptions, error) {
+	options := appOptions{}
+
+	if v := getenv(envShowSpread); v != "" {
+		showSpread, err := strconv.ParseBool(v)
+		if err != nil {
+			return appOptions{}, fmt.Errorf("%s: %w", envShowSpread, err)
+		}
+
+		options.showSpread = showSpread
+	}
+
+	if v := getenv(envShowSides); v != "" {
+		showSides, err := strconv.ParseBool(v)
+		if err != nil {
+			return appOptions{}, fmt.Errorf("%s: %w", envShowSides, err)
+		}
+
+		options.showSides = showSides
+	}
+
+	if v := getenv(envShowBands); v != "" {
+		showBands, err := strconv.ParseBool(v)
+		if err != nil {
+			return appOptions{}, fmt.Errorf("%s: %w", envShowBands, err)
+		}
+
+		options.showBands = showBands
+	}
+
+	if v := getenv(envWindows); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			window, err := vwap.ParseWindow(strings.TrimSpace(s))
+			if err != nil {
+				return appOptions{}, fmt.Errorf("%s: %w", envWindows, err)
+			}
+
+			options.windows = append(options.windows, window)
+		}
+	}
+
+	if v := getenv(envSessionAnchor); v != "" {
+		sessionAnchor, err := vwap.ParseDailyAnchor(v)
+		if err != nil {
+			return appOptions{}, fmt.Errorf("%s: %w", envSessionAnchor, err)
+		}
+
+		options.sessionAnchor = &sessionAnchor
+	}
+
+	switch v := getenv(envOutput); v {
+	case "", outputText, outputJSON:
+		options.output = v
+	default:
+		return appOptions{}, fmt.Errorf("%s: unknown output %q, expected %q or %q", envOutput, v, outputText, outputJSON)
+	}
+
+	return options, nil
+}
+
+// newCoinbaseClient creates the coinbase.Client used by the application, configured
+// from environment variables read with getenv.
+func newCoinbaseClient(getenv func(string) string) (*coinbase.Client, error) {
+	environment := coinbase.Environment(getenv(envEnvironment))
+
+	restURL := getenv(envRESTURL)
+	if restURL == "" {
+		var err error
+
+		restURL, err = environment.RESTURL()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", envEnvironment, err)
+		}
+	}
+
+	credentials, err := loadCredentials(getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	backpressure := coinbase.Backpressure{Overflow: coinbase.OverflowPolicy(getenv(envOverflow))}
+
+	if v := getenv(envReadBuffer); v != "" {
+		backpressure.BufferSize, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", envReadBuffer, err)
+		}
+	}
+
+	return &coinbase.Client{
+		Environment: environment,
+		FeedURL:     getenv(envFeedURL),
+		Credentials: credentials,
+
+		Reconnect: &coinbase.ReconnectPolicy{MaxAttempts: 10, Jitter: 0.2},
+		Backfill:  &coinbase.RESTClient{BaseURL: restURL},
+
+		LivenessTimeout: time.Second * 10,
+
+		Backpressure: backpressure,
+	}, nil
+}
+
+// loadCredentials loads API credentials from the file named by envCredentialsFile,
+// if set, otherwise from the environment. If there are none, nil is returned.
+func loadCredentials(getenv func(string) string) (*coinbase.Credentials, error) {
+	if path := getenv(envCredentialsFile); path != "" {
+		credentials, err := coinbase.LoadCredentialsFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", envCredentialsFile, err)
+		}
+
+		return credentials, nil
+	}
+
+	credentials, err := coinbase.CredentialsFromEnv(getenv)
+	if err != nil {
+		return nil, fmt.Errorf("load credentials from environment: %w", err)
+	}
+
+	return credentials, nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+	"github.com/byatesrae/coinbase_vwap/internal/vwap"
+)
+
+func TestNewCoinbaseClient(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name                 string
+		giveEnv              map[string]string
+		expectedEnvironment  coinbase.Environment
+		expectedFeedURL      string
+		expectedRESTURL      string
+		expectedCredentials  *coinbase.Credentials
+		expectedBackpressure coinbase.Backpressure
+		expectedErr          string
+	}{
+		{
+			name:            "default",
+			giveEnv:         map[string]string{},
+			expectedRESTURL: "https://api.exchange.coinbase.com",
+		},
+		{
+			name:                "sandbox",
+			giveEnv:             map[string]string{envEnvironment: "sandbox"},
+			expectedEnvironment: coinbase.EnvironmentSandbox,
+			expectedRESTURL:     "https://api-public.sandbox.exchange.coinbase.com",
+		},
+		{
+			name: "overrides",
+			giveEnv: map[string]string{
+				envEnvironment: "sandbox",
+				envFeedURL:     "ws://localhost:8080",
+				envRESTURL:     "http://localhost:8081",
+			},
+			expectedEnvironment: coinbase.EnvironmentSandbox,
+			expectedFeedURL:     "ws://localhost:8080",
+			expectedRESTURL:     "http://localhost:8081",
+		},
+		{
+			name: "credentials",
+			giveEnv: map[string]string{
+				coinbase.EnvAPIKey:        "TestKey",
+				coinbase.EnvAPISecret:     "VGVzdFNlY3JldA==",
+				coinbase.EnvAPIPassphrase: "TestPassphrase",
+			},
+			expectedRESTURL:     "https://api.exchange.coinbase.com",
+			expectedCredentials: &coinbase.Credentials{Key: "TestKey", Secret: "VGVzdFNlY3JldA==", Passphrase: "TestPassphrase"},
+		},
+		{
+			name:        "credentials_incomplete",
+			giveEnv:     map[string]string{coinbase.EnvAPIKey: "TestKey"},
+			expectedErr: "load credentials from environment: credentials: secret is required",
+		},
+		{
+			name:        "credentials_file_missing",
+			giveEnv:     map[string]string{envCredentialsFile: "/does/not/exist.json"},
+			expectedErr: "COINBASE_VWAP_CREDENTIALS_FILE: open credentials file: open /does/not/exist.json: no such file or directory",
+		},
+		{
+			name:                 "backpressure",
+			giveEnv:              map[string]string{envReadBuffer: "100", envOverflow: "drop_oldest"},
+			expectedRESTURL:      "https://api.exchange.coinbase.com",
+			expectedBackpressure: coinbase.Backpressure{BufferSize: 100, Overflow: coinbase.OverflowDropOldest},
+		},
+		{
+			name:        "read_buffer_invalid",
+			giveEnv:     map[string]string{envReadBuffer: "TestABC"},
+			expectedErr: `COINBASE_VWAP_READ_BUFFER: strconv.Atoi: parsing "TestABC": invalid syntax`,
+		},
+		{
+			name:        "unknown_environment",
+			giveEnv:     map[string]string{envEnvironment: "TestABC"},
+			expectedErr: `COINBASE_VWAP_ENVIRONMENT: unknown environment "TestABC"`,
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Do
+			actual, actualErr := newCoinbaseClient(func(key string) string { return tc.giveEnv[key] })
+
+			// Assert
+			if tc.expectedErr != "" {
+				assert.EqualError(t, actualErr, tc.expectedErr, "Actual err")
+
+				return
+			}
+
+			if !assert.NoError(t, actualErr, "Actual err") {
+				return
+			}
+
+			assert.Equal(t, tc.expectedEnvironment, actual.Environment, "Environment")
+			assert.Equal(t, tc.expectedFeedURL, actual.FeedURL, "Feed URL")
+			assert.Equal(t, tc.expectedRESTURL, actual.Backfill.BaseURL, "REST URL")
+			assert.Equal(t, tc.expectedCredentials, actual.Credentials, "Credentials")
+			assert.Equal(t, tc.expectedBackpressure, actual.Backpressure, "Backpressure")
+		})
+	}
+}
+
+func TestNewAppOptions(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name        string
+		giveEnv     map[string]string
+		expected    appOptions
+		expectedErr string
+	}{
+		{
+			name:    "default",
+			giveEnv: map[string]string{},
+		},
+		{
+			name:     "show_spread",
+			giveEnv:  map[string]string{envShowSpread: "true"},
+			expected: appOptions{showSpread: true},
+		},
+		{
+			name:        "show_spread_invalid",
+			giveEnv:     map[string]string{envShowSpread: "TestABC"},
+			expectedErr: `COINBASE_VWAP_SHOW_SPREAD: strconv.ParseBool: parsing "TestABC": invalid syntax`,
+		},
+		{
+			name:     "show_sides",
+			giveEnv:  map[string]string{envShowSides: "true"},
+			expected: appOptions{showSides: true},
+		},
+		{
+			name:        "show_sides_invalid",
+			giveEnv:     map[string]string{envShowSides: "TestABC"},
+			expectedErr: `COINBASE_VWAP_SHOW_SIDES: strconv.ParseBool: parsing "TestABC": invalid syntax`,
+		},
+		{
+			name:     "show_bands",
+			giveEnv:  map[string]string{envShowBands: "true"},
+			expected: appOptions{showBands: true},
+		},
+		{
+			name:        "show_bands_invalid",
+			giveEnv:     map[string]string{envShowBands: "TestABC"},
+			expectedErr: `COINBASE_VWAP_SHOW_BANDS: strconv.ParseBool: parsing "TestABC": invalid syntax`,
+		},
+		{
+			name:    "windows",
+			giveEnv: map[string]string{envWindows: "50, 200,5m"},
+			expected: appOptions{windows: []vwap.Window{
+				{Count: 50},
+				{Count: 200},
+				{Duration: time.Minute * 5},
+			}},
+		},
+		{
+			name:        "windows_invalid",
+			giveEnv:     map[string]string{envWindows: "50,-1m"},
+			expectedErr: `COINBASE_VWAP_WINDOWS: parse window "-1m": window must be positive`,
+		},
+		{
+			name:     "session_anchor",
+			giveEnv:  map[string]string{envSessionAnchor: "12:30"},
+			expected: appOptions{sessionAnchor: &vwap.DailyAnchor{Hour: 12, Minute: 30}},
+		},
+		{
+			name:        "session_anchor_invalid",
+			giveEnv:     map[string]string{envSessionAnchor: "TestABC"},
+			expectedErr: `COINBASE_VWAP_SESSION_ANCHOR: parse daily anchor "TestABC": expected a time of day (e.g. 09:30) and optional location (e.g. America/New_York)`,
+		},
+		{
+			name:     "output_json",
+			giveEnv:  map[string]string{envOutput: "json"},
+			expected: appOptions{output: outputJSON},
+		},
+		{
+			name:        "output_invalid",
+			giveEnv:     map[string]string{envOutput: "TestABC"},
+			expectedErr: `COINBASE_VWAP_OUTPUT: unknown output "TestABC", expected "text" or "json"`,
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := newAppOptions(func(key string) string { return tc.giveEnv[key] })
+
+			if tc.expectedErr != "" {
+				assert.EqualError(t, actualErr, tc.expectedErr, "Actual err")
+			} else {
+				assert.NoError(t, actualErr, "Actual err")
+			}
+
+			assert.Equal(t, tc.expected, actual, "Actual")
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+// defaultQuoteIncrement is used for products whose quote increment isn't known,
+// the finest precision Coinbase prices are quoted in.
+var defaultQuoteIncrement = decimal.MustParse("0.00000001")
+
+// quoteIncrements looks up the quote increment of each product, which its VWAPs
+// are rounded to. Increments are cached once looked up, and concurrent gets of the
+// same product share a lookup. It is safe for concurrent use.
+type quoteIncrements struct {
+	// If nil, defaultQuoteIncrement is used for every product.
+	rest *coinbase.RESTClient
+
+	// Lookups by product, either in flight or successful. Failed lookups are
+	// removed, so the next get retries. Guarded by mu, which isn't held while
+	// looking up.
+	mu      sync.Mutex
+	lookups map[coinbase.ProductID]*incrementLookup
+}
+
+// incrementLookup is the lookup of a product's quote increment.
+type incrementLookup struct {
+	// Closed once the lookup is done, after which increment and err are set.
+	done      chan struct{}
+	increment decimal.Decimal
+	err       error
+}
+
+// newQuoteIncrements creates a new quoteIncrements, looking them up with rest (if
+// not nil).
+func newQuoteIncrements(rest *coinbase.RESTClient) *quoteIncrements {
+	return &quoteIncrements{
+		rest:    rest,
+		lookups: make(map[coinbase.ProductID]*incrementLookup),
+	}
+}
+
+// get returns the quote increment of productID. If it can't be looked up,
+// defaultQuoteIncrement is returned with the error.
+func (q *quoteIncrements) get(ctx context.Context, productID coinbase.ProductID) (decimal.Decimal, error) {
+	if q.rest == nil {
+		return defaultQuoteIncrement, nil
+	}
+
+	q.mu.Lock()
+	lookup, ok := q.lookups[productID]
+	if !ok {
+		lookup = &incrementLookup{done: make(chan struct{})}
+		q.lookups[productID] = lookup
+	}
+	q.mu.Unlock()
+
+	if !ok {
+		lookup.increment, lookup.err = q.lookUp(ctx, productID)
+
+		if lookup.err != nil {
+			q.mu.Lock()
+			delete(q.lookups, productID)
+			q.mu.Unlock()
+		}
+
+		close(lookup.done)
+	}
+
+	select {
+	case <-lookup.done:
+	case <-ctx.Done():
+		return defaultQuoteIncrement, fmt.Errorf("look up quote increment: %w", ctx.Err())
+	}
+
+	if lookup.err != nil {
+		return defaultQuoteIncrement, lookup.err
+	}
+
+	return lookup.increment, nil
+}
+
+// lookUp looks up the quote increment of productID.
+func (q *quoteIncrements) lookUp(ctx context.Context, productID coinbase.ProductID) (decimal.Decimal, error) {
+	product, err := q.rest.GetProduct(ctx, productID)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("look up quote increment: %w", err)
+	}
+
+	increment, err := decimal.Parse(product.QuoteIncrement)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("parse quote increment: %w", err)
+	}
+
+	if increment.Sign() <= 0 {
+		return decimal.Decimal{}, fmt.Errorf("quote increment %v is not positive", increment)
+	}
+
+	return increment, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+)
+
+func TestQuoteIncrementsGet(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name             string
+		giveResponse     string
+		giveStatus       int
+		expected         string
+		expectedErr      string
+		expectedRequests int32
+	}{
+		{
+			name:             "looked_up_once",
+			giveResponse:     `{"id": "BTC-USD", "quote_increment": "0.01"}`,
+			giveStatus:       http.StatusOK,
+			expected:         "0.01",
+			expectedRequests: 1,
+		},
+		{
+			name:             "not_found",
+			giveResponse:     `{"message": "NotFound"}`,
+			giveStatus:       http.StatusNotFound,
+			expected:         "0.00000001",
+			expectedErr:      `look up quote increment: get product BTC-USD: request: unexpected status 404: "NotFound"`,
+			expectedRequests: 2,
+		},
+		{
+			name:             "invalid",
+			giveResponse:     `{"id": "BTC-USD", "quote_increment": "TestABC"}`,
+			giveStatus:       http.StatusOK,
+			expected:         "0.00000001",
+			expectedErr:      `parse quote increment: parse decimal "TestABC": invalid syntax`,
+			expectedRequests: 2,
+		},
+		{
+			name:             "zero",
+			giveResponse:     `{"id": "BTC-USD", "quote_increment": "0.00"}`,
+			giveStatus:       http.StatusOK,
+			expected:         "0.00000001",
+			expectedErr:      "quote increment 0.00 is not positive",
+			expectedRequests: 2,
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+
+			var requests int32
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+
+				w.WriteHeader(tc.giveStatus)
+				fmt.Fprint(w, tc.giveResponse)
+			}))
+			t.Cleanup(server.Close)
+
+			increments := newQuoteIncrements(&coinbase.RESTClient{BaseURL: server.URL})
+
+			// Do
+
+			_, _ = increments.get(context.Background(), coinbase.ProductIDBtcUsd)
+			actual, actualErr := increments.get(context.Background(), coinbase.ProductIDBtcUsd)
+
+			// Assert
+
+			if tc.expectedErr == "" {
+				assert.NoError(t, actualErr, "Actual err")
+			} else {
+				assert.EqualError(t, actualErr, tc.expectedErr, "Actual err")
+			}
+
+			assert.Equal(t, tc.expected, actual.String(), "Actual")
+			assert.Equal(t, tc.expectedRequests, atomic.LoadInt32(&requests), "Requests")
+		})
+	}
+}
+
+func TestQuoteIncrementsGetWithoutREST(t *testing.T) {
+	t.Parallel()
+
+	actual, actualErr := newQuoteIncrements(nil).get(context.Background(), coinbase.ProductIDBtcUsd)
+
+	assert.NoError(t, actualErr, "Actual err")
+	assert.Equal(t, "0.00000001", actual.String(), "Actual")
+}
+
+func TestQuoteIncrementsGetConcurrently(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	var btcUsdRequests int32
+
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, string(coinbase.ProductIDBtcUsd)) {
+			atomic.AddInt32(&btcUsdRequests, 1)
+			<-release
+		}
+
+		fmt.Fprint(w, `{"quote_increment": "0.01"}`)
+	}))
+	t.Cleanup(server.Close)
+
+	increments := newQuoteIncrements(&coinbase.RESTClient{BaseURL: server.URL})
+
+	// Do
+
+	btcUsd := make(chan string, 2)
+	for a := 0; a < 2; a++ {
+		go func() {
+			increment, _ := increments.get(context.Background(), coinbase.ProductIDBtcUsd)
+			btcUsd <- increment.String()
+		}()
+	}
+
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&btcUsdRequests) == 1 }, time.Second, time.Millisecond*10, "BTC-USD requested")
+
+	ctx, ctxCancel := context.WithTimeout(context.Background(), time.Millisecond*500)
+	t.Cleanup(ctxCancel)
+
+	// Not blocked by the BTC-USD lookup in flight.
+	actualEthUsd, actualEthUsdErr := increments.get(ctx, coinbase.ProductIDEthUsd)
+
+	close(release)
+
+	// Assert
+
+	assert.NoError(t, actualEthUsdErr, "ETH-USD err")
+	assert.Equal(t, "0.01", actualEthUsd.String(), "ETH-USD")
+
+	for a := 0; a < 2; a++ {
+		assert.Equal(t, "0.01", <-btcUsd, "BTC-USD")
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&btcUsdRequests), "BTC-USD requests")
+}
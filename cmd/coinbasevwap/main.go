@@ -16,94 +16,221 @@ import (
 )
 
 func main() {
-	err := runApp(&coinbase.Client{}, log.Writer(), make(chan os.Signal, 1))
+	coinbaseClient, err := newCoinbaseClient(os.Getenv)
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	options, err := newAppOptions(os.Getenv)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = runApp(coinbaseClient, options, os.Stdin, log.Writer(), make(chan os.Signal, 1))
+	if err != nil {
+		if coinbase.IsRetryable(err) {
+			log.Printf("[ERR] %v (retryable)", err)
+			os.Exit(exitCodeRetryable)
+		}
+
+		log.Fatal(err)
+	}
 }
 
+// exitCodeRetryable is exited with when the app fails with an error that may not
+// occur if it's run again (e.g. the connection to Coinbase dropped), as opposed to
+// 1 for an error that will (e.g. a request rejected by Coinbase). It is EX_TEMPFAIL
+// from sysexits.h.
+const exitCodeRetryable = 75
+
 // runApp runs the application, connecting to Coinbase with coinbaseClient and outputting
-// the VWAPs (or errors) on output. Signal interrupt to exit.
-func runApp(coinbaseClient *coinbase.Client, output io.Writer, interrupt chan os.Signal) error {
+// the VWAPs (or errors) on output. Products are added and removed as per commands
+// (see readCommands), if not nil. Signal interrupt to exit.
+func runApp(coinbaseClient *coinbase.Client, options appOptions, commands io.Reader, output io.Writer, interrupt chan os.Signal) error {
 	ctx := context.Background()
 
-	log.Print("[INF] Creating subscriptions...\n")
-	subscriptions, err := subscribeToAll(
-		ctx,
-		coinbaseClient,
-		[]coinbase.ProductID{
-			coinbase.ProductIDBtcUsd,
-			coinbase.ProductIDEthUsd,
-			coinbase.ProductIDEthBtc,
-		},
-	)
+	productIDs := []coinbase.ProductID{
+		coinbase.ProductIDBtcUsd,
+		coinbase.ProductIDEthUsd,
+		coinbase.ProductIDEthBtc,
+	}
+
+	log.Print("[INF] Creating subscription...\n")
+	subscription, err := coinbaseClient.SubscribeToMatchesForProducts(ctx, productIDs)
 	if err != nil {
-		return fmt.Errorf("subscribe to all: %w", err)
+		return fmt.Errorf("subscribe to matches: %w", err)
 	}
 
-	log.Print("[INF] Starting printing of VWAPS...\n")
 	wg := sync.WaitGroup{}
-	startPrintingVWAPs(subscriptions, &wg, output)
+
+	// If not showing spreads, these remain nil (and tickerDone never closes).
+	var (
+		tickerSubscription *coinbase.TickerSubscription
+		tickerDone         <-chan struct{}
+		productSpreads     *spreads
+	)
+
+	if options.showSpread {
+		log.Print("[INF] Creating ticker subscription...\n")
+		tickerSubscription, err = coinbaseClient.SubscribeToTickerForProducts(ctx, productIDs)
+		if err != nil {
+			closeSubscription(ctx, subscription)
+
+			return fmt.Errorf("subscribe to ticker: %w", err)
+		}
+
+		tickerDone = tickerSubscription.Done()
+		productSpreads = newSpreads()
+		startTrackingSpreads(tickerSubscription, productSpreads, &wg, output)
+	}
+
+	log.Print("[INF] Starting printing of VWAPS...\n")
+	printer := &vwapPrinter{
+		subscription:   subscription,
+		windows:        options.windows,
+		sessionAnchor:  options.sessionAnchor,
+		productAnchors: newAnchors(),
+
+		// The REST client used for backfill also serves product details.
+		increments: newQuoteIncrements(coinbaseClient.Backfill),
+
+		productSpreads: productSpreads,
+		sink:           newVWAPSink(options, output),
+		wg:             &wg,
+	}
+	printer.startAll()
+
+	updater := newProductUpdater(printer, tickerSubscription, &wg, output)
+	if commands != nil {
+		go readCommands(commands, updater, output)
+	}
 
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
 
-	<-interrupt
-	log.Print("[INF] Interrupted!\n")
+	select {
+	case <-interrupt:
+		log.Print("[INF] Interrupted!\n")
+	case <-subscription.Done():
+		log.Print("[ERR] Subscription stopped!\n")
+	case <-tickerDone:
+		log.Print("[ERR] Ticker subscription stopped!\n")
+	}
 
-	closeCtx, cancelCloseCtx := context.WithTimeout(ctx, time.Second*2)
-	defer cancelCloseCtx()
+	updater.stop()
 
-	for _, subscription := range subscriptions {
-		subscription := subscription
+	closeSubscription(ctx, subscription)
 
-		go func() {
-			if err := subscription.Close(closeCtx); err != nil {
-				log.Printf("[ERR] Failed to close subscription for %q: %v", subscription.ProductID(), err)
-			}
-		}()
+	if tickerSubscription != nil {
+		closeSubscription(ctx, tickerSubscription)
 	}
 
 	wg.Wait()
 
+	if err := subscription.Err(); err != nil {
+		return fmt.Errorf("subscription: %w", err)
+	}
+
+	if tickerSubscription != nil {
+		if err := tickerSubscription.Err(); err != nil {
+			return fmt.Errorf("ticker subscription: %w", err)
+		}
+	}
+
 	return nil
 }
 
-// subscribeToAll establishes subscriptions for each productID in productIDs using
-// coinbaseClient.
-func subscribeToAll(ctx context.Context, coinbaseClient *coinbase.Client, productIDs []coinbase.ProductID) ([]*coinbase.MatchesSubscription, error) {
-	var subscriptions []*coinbase.MatchesSubscription
+// closeSubscription closes subscription, allowing it a short time to do so gracefully.
+func closeSubscription(ctx context.Context, subscription interface{ Close(context.Context) error }) {
+	closeCtx, cancelCloseCtx := context.WithTimeout(ctx, time.Second*2)
+	defer cancelCloseCtx()
+
+	if err := subscription.Close(closeCtx); err != nil {
+		log.Printf("[ERR] Failed to close subscription: %v", err)
+	}
+}
+
+// vwapPrinter outputs the VWAPs of the products of a subscription to a sink.
+type vwapPrinter struct {
+	subscription *coinbase.MatchesSubscription
+
+	// The windows VWAPs are calculated over. If empty, the last 200 trades.
+	windows []vwap.Window
+
+	// If not nil, a session VWAP is output from each of these.
+	sessionAnchor *vwap.DailyAnchor
+
+	// Anchored VWAPs are output from each of these, as they're added.
+	productAnchors *anchors
+
+	// Looks up the quote increment of each product, which its VWAPs are rounded to.
+	increments *quoteIncrements
+
+	// If not nil, the latest spread (if any) is output alongside each VWAP.
+	productSpreads *spreads
+
+	sink vwapSink
+
+	// Used to signal when each VWAP output loop start/stops.
+	wg *sync.WaitGroup
+}
+
+// startAll will start outputting VWAPs for each product of the subscription.
+func (p *vwapPrinter) startAll() {
+	for _, productID := range p.subscription.ProductIDs() {
+		p.start(productID)
+	}
+}
+
+// start will start outputting VWAPs for productID of the subscription.
+func (p *vwapPrinter) start(productID coinbase.ProductID) {
+	read := p.subscription.ReadProduct(productID)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
 
-	for _, productID := range productIDs {
-		subscription, err := coinbaseClient.SubscribeToMatchesForProduct(ctx, productID)
+		ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second*5)
+
+		quoteIncrement, err := p.increments.get(ctx, productID)
 		if err != nil {
-			return nil, fmt.Errorf("subscribe to matches (%q): %w", productID, err)
+			p.sink.err(productID, fmt.Errorf("%w, rounding to %v", err, quoteIncrement))
 		}
 
-		subscriptions = append(subscriptions, subscription)
-	}
+		ctxCancel()
 
-	return subscriptions, nil
-}
+		windows := p.windows
+		if len(windows) == 0 {
+			windows = []vwap.Window{{Count: 200}}
+		}
+
+		calculators := &productCalculators{
+			productID:      productID,
+			increment:      quoteIncrement,
+			productAnchors: p.productAnchors,
+			productSpreads: p.productSpreads,
+		}
 
-// startPrintingVWAPs will start outputting VWAPs to w for each Subscription in subscriptions.
-// wg is used to signal when each VWAP output loop start/stops.
-func startPrintingVWAPs(subscriptions []*coinbase.MatchesSubscription, wg *sync.WaitGroup, w io.Writer) {
-	for _, subscription := range subscriptions {
-		read := subscription.Read()
-		productID := subscription.ProductID()
+		calculators.windows, err = vwap.NewMultiWindowVWAP(windows, quoteIncrement, nil)
+		if err != nil {
+			p.sink.err(productID, err)
+			return
+		}
 
-		wg.Add(1)
-		go func() {
-			printVWAP(read, productID, w)
+		if p.sessionAnchor != nil {
+			calculators.session = vwap.NewSessionVWAP(*p.sessionAnchor, quoteIncrement, nil)
+		}
 
-			wg.Done()
-		}()
-	}
+		printVWAP(read, calculators, func() uint64 { return p.subscription.Dropped(productID) }, p.sink)
+	}()
 }
 
-// printVWAP reads a MatchResponse from read and outputs it to w for productID productID.
-func printVWAP(read <-chan *coinbase.MatchResponse, productID coinbase.ProductID, w io.Writer) {
-	vwapCalculator := vwap.NewSlidingWindowVWAP(200)
+// printVWAP reads a MatchResponse from read and outputs the VWAPs calculated from
+// it by calculators to sink. dropped returns the number of responses dropped from
+// read so far (see coinbase.Backpressure), any increase in which is output.
+func printVWAP(read <-chan *coinbase.MatchResponse, calculators *productCalculators, dropped func() uint64, sink vwapSink) {
+	productID := calculators.productID
+
+	var reportedDropped uint64
 
 	for {
 		matchResponse, ok := <-read
@@ -111,14 +238,37 @@ func printVWAP(read <-chan *coinbase.MatchResponse, productID coinbase.ProductID
 			break
 		}
 
-		units, unitPrice, err := matchResponse.ToUnitsAndUnitPrice()
+		if d := dropped(); d > reportedDropped {
+			sink.notice(productID, fmt.Sprintf("%d messages dropped, output is falling behind", d-reportedDropped))
+			reportedDropped = d
+		}
+
+		if matchResponse.Notification != nil {
+			sink.notice(productID, matchResponse.Notification.String())
+			continue
+		}
+
+		units, unitPrice, err := matchResponse.ToDecimalUnitsAndUnitPrice()
 		if err != nil {
-			fmt.Fprintf(w, "%q ERROR: %v\n", productID, err)
+			sink.err(productID, err)
 			continue
 		}
 
-		v := vwapCalculator.Add(units, unitPrice)
+		update := calculators.add(matchResponse.Match.Time, aggressorSide(matchResponse.Match.Side), units, unitPrice)
+
+		sink.vwaps(productID, update)
+	}
+}
 
-		fmt.Fprintf(w, "%q: %v\n", productID, v)
+// aggressorSide returns the side of the aggressor of a match, given makerSide the
+// side of its maker order. The aggressor (taker) is on the other side.
+func aggressorSide(makerSide coinbase.Side) vwap.Side {
+	switch makerSide {
+	case coinbase.SideBuy:
+		return vwap.SideSell
+	case coinbase.SideSell:
+		return vwap.SideBuy
+	default:
+		return vwap.SideUnknown
 	}
 }
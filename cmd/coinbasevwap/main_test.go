@@ -4,18 +4,24 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+	"github.com/byatesrae/coinbase_vwap/internal/vwap"
 )
 
 func TestSubscribesReadsAndExits(t *testing.T) {
@@ -27,7 +33,7 @@ func TestSubscribesReadsAndExits(t *testing.T) {
 	t.Cleanup(ctxCancel)
 
 	wgForAllSubscriptionReads := sync.WaitGroup{}
-	wgForAllSubscriptionReads.Add(3) // Number of expected subscriptions
+	wgForAllSubscriptionReads.Add(1) // Number of expected connections
 
 	coinbaseClient := &coinbase.Client{
 		Dialer: newDialerFake(ctx, t, &wgForAllSubscriptionReads, []*matchesIn{
@@ -58,7 +64,7 @@ func TestSubscribesReadsAndExits(t *testing.T) {
 	// Do
 
 	go func() { // Run app
-		err := runApp(coinbaseClient, &sbWithMutex, interrupt)
+		err := runApp(coinbaseClient, appOptions{}, nil, &sbWithMutex, interrupt)
 
 		assert.NoError(t, err, "runApp error.")
 
@@ -76,22 +82,252 @@ func TestSubscribesReadsAndExits(t *testing.T) {
 	output := sbWithMutex.sb.String()
 	outputLines := strings.Split(output, "\n")
 
-	require.Len(t, outputLines, 13, "Number of lines outputted.")
-	require.Empty(t, outputLines[12], "Last line outputted.")
-
-	outputLines = outputLines[:12]
-	assert.Contains(t, outputLines, "\"ETH-USD\": 6")
-	assert.Contains(t, outputLines, "\"ETH-USD\": 5.2")
-	assert.Contains(t, outputLines, "\"ETH-USD\": 4.666666666666667")
-	assert.Contains(t, outputLines, "\"ETH-BTC\": 6")
-	assert.Contains(t, outputLines, "\"ETH-BTC\": 5.2")
-	assert.Contains(t, outputLines, "\"ETH-BTC\": 4.666666666666667")
-	assert.Contains(t, outputLines, "\"BTC-USD\": 6")
-	assert.Contains(t, outputLines, "\"BTC-USD\": 5.2")
-	assert.Contains(t, outputLines, "\"BTC-USD\": 4.666666666666667")
-	assert.Contains(t, outputLines, "\"ETH-USD\" ERROR: match response: read match: test, close called during ReadJSON")
-	assert.Contains(t, outputLines, "\"ETH-BTC\" ERROR: match response: read match: test, close called during ReadJSON")
-	assert.Contains(t, outputLines, "\"BTC-USD\" ERROR: match response: read match: test, close called during ReadJSON")
+	require.Len(t, outputLines, 10, "Number of lines outputted.")
+	require.Empty(t, outputLines[9], "Last line outputted.")
+
+	outputLines = outputLines[:9]
+	assert.Contains(t, outputLines, "\"ETH-USD\": 6.00000000")
+	assert.Contains(t, outputLines, "\"ETH-USD\": 5.20000000")
+	assert.Contains(t, outputLines, "\"ETH-USD\": 4.66666667")
+	assert.Contains(t, outputLines, "\"ETH-BTC\": 6.00000000")
+	assert.Contains(t, outputLines, "\"ETH-BTC\": 5.20000000")
+	assert.Contains(t, outputLines, "\"ETH-BTC\": 4.66666667")
+	assert.Contains(t, outputLines, "\"BTC-USD\": 6.00000000")
+	assert.Contains(t, outputLines, "\"BTC-USD\": 5.20000000")
+	assert.Contains(t, outputLines, "\"BTC-USD\": 4.66666667")
+}
+
+func TestPrintVWAPWithSpread(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	productSpreads := newSpreads()
+	tickerRead := make(chan *coinbase.TickerResponse, 2)
+	tickerRead <- &coinbase.TickerResponse{Ticker: coinbase.Ticker{BestBid: decimal.MustParse("19000.00"), BestAsk: decimal.MustParse("19000.01")}}
+	tickerRead <- &coinbase.TickerResponse{Err: fmt.Errorf("TestABC")}
+	close(tickerRead)
+
+	matchRead := make(chan *coinbase.MatchResponse, 1)
+	matchRead <- &coinbase.MatchResponse{Match: coinbase.Match{Size: "1", Price: "2"}}
+	close(matchRead)
+
+	sb := strings.Builder{}
+
+	// Do
+
+	trackSpread(tickerRead, coinbase.ProductIDBtcUsd, productSpreads, &sb)
+	printVWAP(matchRead, newProductCalculators(t, "0.01", nil, productSpreads, vwap.Window{Count: 200}), func() uint64 { return 0 }, &textSink{w: &sb})
+
+	// Assert
+
+	assert.Equal(t, "\"BTC-USD\" TICKER ERROR: TestABC\n\"BTC-USD\": 2.00 (spread: 0.01)\n", sb.String(), "Output")
+}
+
+func TestPrintVWAPDropped(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	matchRead := make(chan *coinbase.MatchResponse, 3)
+	matchRead <- &coinbase.MatchResponse{Match: coinbase.Match{Size: "1", Price: "2"}}
+	matchRead <- &coinbase.MatchResponse{Match: coinbase.Match{Size: "1", Price: "4"}}
+	matchRead <- &coinbase.MatchResponse{Match: coinbase.Match{Size: "1", Price: "6"}}
+	close(matchRead)
+
+	dropped := []uint64{0, 3, 3}
+
+	sb := strings.Builder{}
+
+	// Do
+
+	printVWAP(matchRead, newProductCalculators(t, "0.1", nil, nil, vwap.Window{Count: 200}), func() uint64 {
+		d := dropped[0]
+		dropped = dropped[1:]
+
+		return d
+	}, &textSink{w: &sb})
+
+	// Assert
+
+	assert.Equal(t, "\"BTC-USD\": 2.0\n\"BTC-USD\" NOTICE: 3 messages dropped, output is falling behind\n\"BTC-USD\": 3.0\n\"BTC-USD\": 4.0\n", sb.String(), "Output")
+}
+
+func TestPrintVWAPWindows(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return start }
+
+	for _, tc := range []struct {
+		name        string
+		giveSink    func(w io.Writer) vwapSink
+		giveSpread  bool
+		giveSession bool
+		giveAnchors []time.Duration // Since the start of the test.
+		expected    string
+	}{
+		{
+			name:     "text",
+			giveSink: func(w io.Writer) vwapSink { return &textSink{w: w} },
+			expected: "\"BTC-USD\": 2=2.0 1m=2.0\n" +
+				"\"BTC-USD\" NOTICE: reconnected after 2 attempt(s)\n" +
+				"\"BTC-USD\": 2=3.0 1m=3.0\n" +
+				"\"BTC-USD\": 2=5.0 1m=4.0\n",
+		},
+		{
+			name:       "text_with_spread",
+			giveSink:   func(w io.Writer) vwapSink { return &textSink{w: w} },
+			giveSpread: true,
+			expected: "\"BTC-USD\": 2=2.0 1m=2.0 (spread: 0.5)\n" +
+				"\"BTC-USD\" NOTICE: reconnected after 2 attempt(s)\n" +
+				"\"BTC-USD\": 2=3.0 1m=3.0 (spread: 0.5)\n" +
+				"\"BTC-USD\": 2=5.0 1m=4.0 (spread: 0.5)\n",
+		},
+		{
+			name:     "json",
+			giveSink: func(w io.Writer) vwapSink { return newJSONSink(w, false, false) },
+			expected: `{"product_id":"BTC-USD","time":"2022-10-01T12:00:00Z","vwaps":[{"window":"2","vwap":"2.0","trades":1},{"window":"1m","vwap":"2.0","trades":1}]}` + "\n" +
+				`{"product_id":"BTC-USD","notice":"reconnected after 2 attempt(s)"}` + "\n" +
+				`{"product_id":"BTC-USD","time":"2022-10-01T12:00:10Z","vwaps":[{"window":"2","vwap":"3.0","trades":2},{"window":"1m","vwap":"3.0","trades":2}]}` + "\n" +
+				`{"product_id":"BTC-USD","time":"2022-10-01T12:00:20Z","vwaps":[{"window":"2","vwap":"5.0","trades":2},{"window":"1m","vwap":"4.0","trades":3}]}` + "\n",
+		},
+		{
+			name:       "json_with_spread",
+			giveSink:   func(w io.Writer) vwapSink { return newJSONSink(w, false, false) },
+			giveSpread: true,
+			expected: `{"product_id":"BTC-USD","time":"2022-10-01T12:00:00Z","vwaps":[{"window":"2","vwap":"2.0","trades":1},{"window":"1m","vwap":"2.0","trades":1}],"spread":"0.5"}` + "\n" +
+				`{"product_id":"BTC-USD","notice":"reconnected after 2 attempt(s)"}` + "\n" +
+				`{"product_id":"BTC-USD","time":"2022-10-01T12:00:10Z","vwaps":[{"window":"2","vwap":"3.0","trades":2},{"window":"1m","vwap":"3.0","trades":2}],"spread":"0.5"}` + "\n" +
+				`{"product_id":"BTC-USD","time":"2022-10-01T12:00:20Z","vwaps":[{"window":"2","vwap":"5.0","trades":2},{"window":"1m","vwap":"4.0","trades":3}],"spread":"0.5"}` + "\n",
+		},
+		{
+			name:        "text_with_session_and_anchor",
+			giveSink:    func(w io.Writer) vwapSink { return &textSink{w: w} },
+			giveSession: true,
+			giveAnchors: []time.Duration{time.Second * 10},
+			expected: "\"BTC-USD\": 2=2.0 1m=2.0 session@2022-10-01T00:00:00Z=2.0 anchor@2022-10-01T12:00:10Z=0.0\n" +
+				"\"BTC-USD\" NOTICE: reconnected after 2 attempt(s)\n" +
+				"\"BTC-USD\": 2=3.0 1m=3.0 session@2022-10-01T00:00:00Z=3.0 anchor@2022-10-01T12:00:10Z=4.0\n" +
+				"\"BTC-USD\": 2=5.0 1m=4.0 session@2022-10-01T00:00:00Z=4.0 anchor@2022-10-01T12:00:10Z=5.0\n",
+		},
+		{
+			name:        "json_with_session_and_anchor",
+			giveSink:    func(w io.Writer) vwapSink { return newJSONSink(w, false, false) },
+			giveSession: true,
+			giveAnchors: []time.Duration{time.Second * 10},
+			expected: `{"product_id":"BTC-USD","time":"2022-10-01T12:00:00Z","vwaps":[{"window":"2","vwap":"2.0","trades":1},{"window":"1m","vwap":"2.0","trades":1}],` +
+				`"session":{"anchor":"2022-10-01T00:00:00Z","vwap":"2.0","trades":1},"anchored":[{"anchor":"2022-10-01T12:00:10Z","vwap":"0.0","trades":0}]}` + "\n" +
+				`{"product_id":"BTC-USD","notice":"reconnected after 2 attempt(s)"}` + "\n" +
+				`{"product_id":"BTC-USD","time":"2022-10-01T12:00:10Z","vwaps":[{"window":"2","vwap":"3.0","trades":2},{"window":"1m","vwap":"3.0","trades":2}],` +
+				`"session":{"anchor":"2022-10-01T00:00:00Z","vwap":"3.0","trades":2},"anchored":[{"anchor":"2022-10-01T12:00:10Z","vwap":"4.0","trades":1}]}` + "\n" +
+				`{"product_id":"BTC-USD","time":"2022-10-01T12:00:20Z","vwaps":[{"window":"2","vwap":"5.0","trades":2},{"window":"1m","vwap":"4.0","trades":3}],` +
+				`"session":{"anchor":"2022-10-01T00:00:00Z","vwap":"4.0","trades":3},"anchored":[{"anchor":"2022-10-01T12:00:10Z","vwap":"5.0","trades":2}]}` + "\n",
+		},
+		{
+			name:     "text_with_bands",
+			giveSink: func(w io.Writer) vwapSink { return &textSink{w: w, showBands: true} },
+			expected: "\"BTC-USD\": 2=2.0 (stddev: 0.0, bands: 2.0 2.0 2.0 2.0 2.0 2.0) 1m=2.0 (stddev: 0.0, bands: 2.0 2.0 2.0 2.0 2.0 2.0)\n" +
+				"\"BTC-USD\" NOTICE: reconnected after 2 attempt(s)\n" +
+				"\"BTC-USD\": 2=3.0 (stddev: 1.0, bands: 0.0 1.0 2.0 4.0 5.0 6.0) 1m=3.0 (stddev: 1.0, bands: 0.0 1.0 2.0 4.0 5.0 6.0)\n" +
+				"\"BTC-USD\": 2=5.0 (stddev: 1.0, bands: 2.0 3.0 4.0 6.0 7.0 8.0) 1m=4.0 (stddev: 1.6, bands: -0.9 0.7 2.4 5.6 7.3 8.9)\n",
+		},
+		{
+			name:     "json_with_bands",
+			giveSink: func(w io.Writer) vwapSink { return newJSONSink(w, false, true) },
+			expected: `{"product_id":"BTC-USD","time":"2022-10-01T12:00:00Z","vwaps":[` +
+				`{"window":"2","vwap":"2.0","trades":1,"bands":{"variance":"0.00","stddev":"0.0","upper":["2.0","2.0","2.0"],"lower":["2.0","2.0","2.0"]}},` +
+				`{"window":"1m","vwap":"2.0","trades":1,"bands":{"variance":"0.00","stddev":"0.0","upper":["2.0","2.0","2.0"],"lower":["2.0","2.0","2.0"]}}]}` + "\n" +
+				`{"product_id":"BTC-USD","notice":"reconnected after 2 attempt(s)"}` + "\n" +
+				`{"product_id":"BTC-USD","time":"2022-10-01T12:00:10Z","vwaps":[` +
+				`{"window":"2","vwap":"3.0","trades":2,"bands":{"variance":"1.00","stddev":"1.0","upper":["4.0","5.0","6.0"],"lower":["2.0","1.0","0.0"]}},` +
+				`{"window":"1m","vwap":"3.0","trades":2,"bands":{"variance":"1.00","stddev":"1.0","upper":["4.0","5.0","6.0"],"lower":["2.0","1.0","0.0"]}}]}` + "\n" +
+				`{"product_id":"BTC-USD","time":"2022-10-01T12:00:20Z","vwaps":[` +
+				`{"window":"2","vwap":"5.0","trades":2,"bands":{"variance":"1.00","stddev":"1.0","upper":["6.0","7.0","8.0"],"lower":["4.0","3.0","2.0"]}},` +
+				`{"window":"1m","vwap":"4.0","trades":3,"bands":{"variance":"2.67","stddev":"1.6","upper":["5.6","7.3","8.9"],"lower":["2.4","0.7","-0.9"]}}]}` + "\n",
+		},
+		{
+			name:     "text_with_sides",
+			giveSink: func(w io.Writer) vwapSink { return &textSink{w: w, showSides: true} },
+			expected: "\"BTC-USD\": 2=2.0 (buy: 2.0 on 1, sell: 0.0 on 0, imbalance: 1) 1m=2.0 (buy: 2.0 on 1, sell: 0.0 on 0, imbalance: 1)\n" +
+				"\"BTC-USD\" NOTICE: reconnected after 2 attempt(s)\n" +
+				"\"BTC-USD\": 2=3.0 (buy: 2.0 on 1, sell: 4.0 on 1, imbalance: 0) 1m=3.0 (buy: 2.0 on 1, sell: 4.0 on 1, imbalance: 0)\n" +
+				"\"BTC-USD\": 2=5.0 (buy: 6.0 on 1, sell: 4.0 on 1, imbalance: 0) 1m=4.0 (buy: 4.0 on 2, sell: 4.0 on 1, imbalance: 1)\n",
+		},
+		{
+			name:     "json_with_sides",
+			giveSink: func(w io.Writer) vwapSink { return newJSONSink(w, true, false) },
+			expected: `{"product_id":"BTC-USD","time":"2022-10-01T12:00:00Z","vwaps":[` +
+				`{"window":"2","vwap":"2.0","trades":1,"buy":{"vwap":"2.0","volume":"1"},"sell":{"vwap":"0.0","volume":"0"},"imbalance":"1"},` +
+				`{"window":"1m","vwap":"2.0","trades":1,"buy":{"vwap":"2.0","volume":"1"},"sell":{"vwap":"0.0","volume":"0"},"imbalance":"1"}]}` + "\n" +
+				`{"product_id":"BTC-USD","notice":"reconnected after 2 attempt(s)"}` + "\n" +
+				`{"product_id":"BTC-USD","time":"2022-10-01T12:00:10Z","vwaps":[` +
+				`{"window":"2","vwap":"3.0","trades":2,"buy":{"vwap":"2.0","volume":"1"},"sell":{"vwap":"4.0","volume":"1"},"imbalance":"0"},` +
+				`{"window":"1m","vwap":"3.0","trades":2,"buy":{"vwap":"2.0","volume":"1"},"sell":{"vwap":"4.0","volume":"1"},"imbalance":"0"}]}` + "\n" +
+				`{"product_id":"BTC-USD","time":"2022-10-01T12:00:20Z","vwaps":[` +
+				`{"window":"2","vwap":"5.0","trades":2,"buy":{"vwap":"6.0","volume":"1"},"sell":{"vwap":"4.0","volume":"1"},"imbalance":"0"},` +
+				`{"window":"1m","vwap":"4.0","trades":3,"buy":{"vwap":"4.0","volume":"2"},"sell":{"vwap":"4.0","volume":"1"},"imbalance":"1"}]}` + "\n",
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+
+			var productSpreads *spreads
+			if tc.giveSpread {
+				productSpreads = newSpreads()
+				productSpreads.set(coinbase.ProductIDBtcUsd, coinbase.Ticker{BestBid: decimal.MustParse("1.5"), BestAsk: decimal.MustParse("2")})
+			}
+
+			matchRead := make(chan *coinbase.MatchResponse, 4)
+			// The maker sides, so the aggressors are buy, sell, buy.
+			matchRead <- &coinbase.MatchResponse{Match: coinbase.Match{Size: "1", Price: "2", Side: coinbase.SideSell, Time: start}}
+			matchRead <- &coinbase.MatchResponse{Notification: &coinbase.ReconnectedNotification{Attempts: 2}}
+			matchRead <- &coinbase.MatchResponse{Match: coinbase.Match{Size: "1", Price: "4", Side: coinbase.SideBuy, Time: start.Add(time.Second * 10)}}
+			matchRead <- &coinbase.MatchResponse{Match: coinbase.Match{Size: "1", Price: "6", Side: coinbase.SideSell, Time: start.Add(time.Second * 20)}}
+			close(matchRead)
+
+			sb := strings.Builder{}
+
+			// Do
+
+			calculators := newProductCalculators(t, "0.1", clock, productSpreads, vwap.Window{Count: 2}, vwap.Window{Duration: time.Minute})
+
+			if tc.giveSession {
+				calculators.session = vwap.NewSessionVWAP(vwap.DailyAnchor{}, calculators.increment, clock)
+			}
+
+			calculators.productAnchors = newAnchors()
+			for _, anchor := range tc.giveAnchors {
+				calculators.productAnchors.add(coinbase.ProductIDBtcUsd, start.Add(anchor))
+			}
+
+			printVWAP(matchRead, calculators, func() uint64 { return 0 }, tc.giveSink(&sb))
+
+			// Assert
+
+			assert.Equal(t, tc.expected, sb.String(), "Output")
+		})
+	}
+}
+
+// newProductCalculators creates the productCalculators of BTC-USD over windows,
+// rounded to increment, with time told by now (if nil, time.Now is used).
+func newProductCalculators(t *testing.T, increment string, now func() time.Time, productSpreads *spreads, windows ...vwap.Window) *productCalculators {
+	t.Helper()
+
+	calculator, err := vwap.NewMultiWindowVWAP(windows, decimal.MustParse(increment), now)
+	require.NoError(t, err, "NewMultiWindowVWAP")
+
+	return &productCalculators{
+		productID:      coinbase.ProductIDBtcUsd,
+		increment:      decimal.MustParse(increment),
+		windows:        calculator,
+		productSpreads: productSpreads,
+	}
 }
 
 // stringBuilderMutex wraps a stringbuilder and implements io.writer with a mutex.
@@ -125,40 +361,42 @@ func (m *matchesIn) toMatch(productID coinbase.ProductID) *coinbase.Match {
 	}
 }
 
-// writeJSONRequestToProductID will return the productID of writeJSONRequest, assuming
-// it is of type coinbase.SubscribeRequest and is subscribing to the matches channel
-// for one productID.
-func writeJSONRequestToProductID(writeJSONRequest interface{}) coinbase.ProductID {
+// writeJSONRequestToProductIDs will return the productIDs of writeJSONRequest, assuming
+// it is of type coinbase.SubscribeRequest and is subscribing to the matches channel.
+func writeJSONRequestToProductIDs(writeJSONRequest interface{}) []coinbase.ProductID {
 	subscribeRequest, ok := writeJSONRequest.(coinbase.SubscribeRequest)
 
-	if !ok ||
-		len(subscribeRequest.ProductIDs) != 0 ||
-		len(subscribeRequest.Channels) != 1 ||
-		subscribeRequest.Channels[0].Name != coinbase.ChannelNameMatches ||
-		len(subscribeRequest.Channels[0].ProductIDs) != 1 {
-		return coinbase.ProductIDUnknown
+	if !ok || len(subscribeRequest.ProductIDs) != 0 {
+		return nil
+	}
+
+	for _, channel := range subscribeRequest.Channels {
+		if channel.Name == coinbase.ChannelNameMatches {
+			return channel.ProductIDs
+		}
 	}
 
-	return subscribeRequest.Channels[0].ProductIDs[0]
+	return nil
 }
 
 // newDialerFake creates a Dialer fake. It behaves under the assumption that connections
-// are created only for Coinbase Subscriptions to the Matches channel for one product.
-// This assumption is not validated. It will then fake matches being read from the
-// connection, one for each matchesIn, before waiting to be closed. After all reads
-// the wg is "Done"'d.
+// are created only for Coinbase Subscriptions to the Matches channel. This assumption
+// is not validated. It will then fake matches being read from the connection, one
+// for each matchesIn (last to first) for each subscribed product, before waiting to be closed. After
+// all reads for a connection the wg is "Done"'d.
 func newDialerFake(ctx context.Context, t *testing.T, wg *sync.WaitGroup, matchesIn []*matchesIn) *DialerMock {
 	return &DialerMock{
 		DialContextFunc: func(_ context.Context, _ string, _ http.Header) (coinbase.Conn, *http.Response, error) {
-			subscribedProductID := coinbase.ProductIDUnknown
-			subscribedProductIDMu := sync.Mutex{}
+			var subscribedProductIDs []coinbase.ProductID
+			subscribedProductIDsMu := sync.Mutex{}
 
-			readCountRemaining := int32(len(matchesIn))
+			readCount := int32(0)
 
 			closed := make(chan struct{})
+			closedOnce := sync.Once{}
 
 			return &ConnMock{
-				// Tries to set subscribedProductID.
+				// Tries to set subscribedProductIDs.
 				WriteJSONFunc: func(v interface{}) error {
 					select {
 					case <-ctx.Done():
@@ -168,19 +406,20 @@ func newDialerFake(ctx context.Context, t *testing.T, wg *sync.WaitGroup, matche
 					default:
 					}
 
-					productID := writeJSONRequestToProductID(v)
-					if productID == coinbase.ProductIDUnknown {
+					productIDs := writeJSONRequestToProductIDs(v)
+					if len(productIDs) == 0 {
 						return nil
 					}
 
-					subscribedProductIDMu.Lock()
-					subscribedProductID = productID
-					subscribedProductIDMu.Unlock()
+					subscribedProductIDsMu.Lock()
+					subscribedProductIDs = productIDs
+					subscribedProductIDsMu.Unlock()
 
 					return nil
 				},
 
-				// Tries to read a match from matchesIn.
+				// Tries to read a match from matchesIn, for each subscribed product
+				// in turn.
 				ReadJSONFunc: func(v interface{}) error {
 					select {
 					case <-ctx.Done():
@@ -190,25 +429,32 @@ func newDialerFake(ctx context.Context, t *testing.T, wg *sync.WaitGroup, matche
 					default:
 					}
 
-					count := atomic.LoadInt32(&readCountRemaining)
-					if count == 0 {
+					subscribedProductIDsMu.Lock()
+					productIDs := subscribedProductIDs
+					subscribedProductIDsMu.Unlock()
+
+					readTotal := int32(len(matchesIn) * len(productIDs))
+
+					count := atomic.LoadInt32(&readCount)
+					if count == readTotal {
 						<-closed
 						return fmt.Errorf("test, close called during ReadJSON")
 					}
 
-					matchIn, ok := v.(*coinbase.Match)
+					messageIn, ok := v.(*json.RawMessage)
 					if !ok {
 						return nil
 					}
 
-					subscribedProductIDMu.Lock()
-					productID := subscribedProductID
-					subscribedProductIDMu.Unlock()
+					productID := productIDs[int(count)%len(productIDs)]
+
+					b, err := json.Marshal(matchesIn[len(matchesIn)-1-int(count)/len(productIDs)].toMatch(productID))
+					require.NoError(t, err, "marshal match")
 
-					*matchIn = *matchesIn[count-1].toMatch(productID)
+					*messageIn = b
 
 					defer func() {
-						if atomic.AddInt32(&readCountRemaining, -1) == 0 {
+						if atomic.AddInt32(&readCount, 1) == readTotal {
 							wg.Done()
 						}
 					}()
@@ -216,6 +462,18 @@ func newDialerFake(ctx context.Context, t *testing.T, wg *sync.WaitGroup, matche
 					return nil
 				},
 
+				// A close message is treated as if the server has responded with
+				// its own, closing the connection.
+				WriteControlFunc: func(messageType int, data []byte, deadline time.Time) error {
+					if messageType == websocket.CloseMessage {
+						closedOnce.Do(func() { close(closed) })
+					}
+
+					return nil
+				},
+
+				SetReadDeadlineFunc: func(t time.Time) error { return nil },
+
 				CloseFunc: func() error {
 					select {
 					case <-ctx.Done():
@@ -223,7 +481,7 @@ func newDialerFake(ctx context.Context, t *testing.T, wg *sync.WaitGroup, matche
 					default:
 					}
 
-					close(closed)
+					closedOnce.Do(func() { close(closed) })
 
 					return nil
 				},
@@ -8,6 +8,7 @@ import (
 	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
 	"net/http"
 	"sync"
+	"time"
 )
 
 // Ensure, that ConnMock does implement coinbase.Conn.
@@ -26,6 +27,12 @@ var _ coinbase.Conn = &ConnMock{}
 //			ReadJSONFunc: func(v interface{}) error {
 //				panic("mock out the ReadJSON method")
 //			},
+//			SetReadDeadlineFunc: func(t time.Time) error {
+//				panic("mock out the SetReadDeadline method")
+//			},
+//			WriteControlFunc: func(messageType int, data []byte, deadline time.Time) error {
+//				panic("mock out the WriteControl method")
+//			},
 //			WriteJSONFunc: func(v interface{}) error {
 //				panic("mock out the WriteJSON method")
 //			},
@@ -42,6 +49,12 @@ type ConnMock struct {
 	// ReadJSONFunc mocks the ReadJSON method.
 	ReadJSONFunc func(v interface{}) error
 
+	// SetReadDeadlineFunc mocks the SetReadDeadline method.
+	SetReadDeadlineFunc func(t time.Time) error
+
+	// WriteControlFunc mocks the WriteControl method.
+	WriteControlFunc func(messageType int, data []byte, deadline time.Time) error
+
 	// WriteJSONFunc mocks the WriteJSON method.
 	WriteJSONFunc func(v interface{}) error
 
@@ -55,15 +68,31 @@ type ConnMock struct {
 			// V is the v argument value.
 			V interface{}
 		}
+		// SetReadDeadline holds details about calls to the SetReadDeadline method.
+		SetReadDeadline []struct {
+			// T is the t argument value.
+			T time.Time
+		}
+		// WriteControl holds details about calls to the WriteControl method.
+		WriteControl []struct {
+			// MessageType is the messageType argument value.
+			MessageType int
+			// Data is the data argument value.
+			Data []byte
+			// Deadline is the deadline argument value.
+			Deadline time.Time
+		}
 		// WriteJSON holds details about calls to the WriteJSON method.
 		WriteJSON []struct {
 			// V is the v argument value.
 			V interface{}
 		}
 	}
-	lockClose     sync.RWMutex
-	lockReadJSON  sync.RWMutex
-	lockWriteJSON sync.RWMutex
+	lockClose           sync.RWMutex
+	lockReadJSON        sync.RWMutex
+	lockSetReadDeadline sync.RWMutex
+	lockWriteControl    sync.RWMutex
+	lockWriteJSON       sync.RWMutex
 }
 
 // Close calls CloseFunc.
@@ -125,6 +154,78 @@ func (mock *ConnMock) ReadJSONCalls() []struct {
 	return calls
 }
 
+// SetReadDeadline calls SetReadDeadlineFunc.
+func (mock *ConnMock) SetReadDeadline(t time.Time) error {
+	if mock.SetReadDeadlineFunc == nil {
+		panic("ConnMock.SetReadDeadlineFunc: method is nil but Conn.SetReadDeadline was just called")
+	}
+	callInfo := struct {
+		T time.Time
+	}{
+		T: t,
+	}
+	mock.lockSetReadDeadline.Lock()
+	mock.calls.SetReadDeadline = append(mock.calls.SetReadDeadline, callInfo)
+	mock.lockSetReadDeadline.Unlock()
+	return mock.SetReadDeadlineFunc(t)
+}
+
+// SetReadDeadlineCalls gets all the calls that were made to SetReadDeadline.
+// Check the length with:
+//
+//	len(mockedConn.SetReadDeadlineCalls())
+func (mock *ConnMock) SetReadDeadlineCalls() []struct {
+	T time.Time
+} {
+	var calls []struct {
+		T time.Time
+	}
+	mock.lockSetReadDeadline.RLock()
+	calls = mock.calls.SetReadDeadline
+	mock.lockSetReadDeadline.RUnlock()
+	return calls
+}
+
+// WriteControl calls WriteControlFunc.
+func (mock *ConnMock) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if mock.WriteControlFunc == nil {
+		panic("ConnMock.WriteControlFunc: method is nil but Conn.WriteControl was just called")
+	}
+	callInfo := struct {
+		MessageType int
+		Data        []byte
+		Deadline    time.Time
+	}{
+		MessageType: messageType,
+		Data:        data,
+		Deadline:    deadline,
+	}
+	mock.lockWriteControl.Lock()
+	mock.calls.WriteControl = append(mock.calls.WriteControl, callInfo)
+	mock.lockWriteControl.Unlock()
+	return mock.WriteControlFunc(messageType, data, deadline)
+}
+
+// WriteControlCalls gets all the calls that were made to WriteControl.
+// Check the length with:
+//
+//	len(mockedConn.WriteControlCalls())
+func (mock *ConnMock) WriteControlCalls() []struct {
+	MessageType int
+	Data        []byte
+	Deadline    time.Time
+} {
+	var calls []struct {
+		MessageType int
+		Data        []byte
+		Deadline    time.Time
+	}
+	mock.lockWriteControl.RLock()
+	calls = mock.calls.WriteControl
+	mock.lockWriteControl.RUnlock()
+	return calls
+}
+
 // WriteJSON calls WriteJSONFunc.
 func (mock *ConnMock) WriteJSON(v interface{}) error {
 	if mock.WriteJSONFunc == nil {
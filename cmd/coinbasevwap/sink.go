@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+	"github.com/byatesrae/coinbase_vwap/internal/vwap"
+)
+
+// vwapSink is where the VWAPs of products are output. It must be safe for
+// concurrent use.
+type vwapSink interface {
+	// vwaps outputs the VWAPs of productID as of a trade.
+	vwaps(productID coinbase.ProductID, update vwapUpdate)
+
+	// notice outputs a notice about productID, e.g. that messages were dropped.
+	notice(productID coinbase.ProductID, notice string)
+
+	// err outputs an error of productID.
+	err(productID coinbase.ProductID, err error)
+}
+
+// newVWAPSink creates the vwapSink of options (see envOutput), writing to w.
+func newVWAPSink(options appOptions, w io.Writer) vwapSink {
+	if options.output == outputJSON {
+		return newJSONSink(w, options.showSides, options.showBands)
+	}
+
+	return &textSink{w: w, showSides: options.showSides, showBands: options.showBands}
+}
+
+// textSink outputs a line of text per VWAP update.
+type textSink struct {
+	mu sync.Mutex
+	w  io.Writer
+
+	// If true, the VWAP and volume of each side, and the imbalance, are output
+	// alongside each VWAP.
+	showSides bool
+
+	// If true, the standard deviation and bands are output alongside each VWAP.
+	showBands bool
+}
+
+func (s *textSink) vwaps(productID coinbase.ProductID, update vwapUpdate) {
+	var labels, formatted []string
+
+	for _, v := range update.windows {
+		f := v.VWAP.String()
+
+		if s.showSides {
+			f += fmt.Sprintf(" (buy: %v on %v, sell: %v on %v, imbalance: %v)", v.Buy.VWAP, v.Buy.Volume, v.Sell.VWAP, v.Sell.Volume, v.Imbalance())
+		}
+
+		// From -3σ to +3σ.
+		if s.showBands {
+			f += fmt.Sprintf(
+				" (stddev: %v, bands: %v %v %v %v %v %v)",
+				v.Bands.StdDev, v.Bands.Lower[2], v.Bands.Lower[1], v.Bands.Lower[0], v.Bands.Upper[0], v.Bands.Upper[1], v.Bands.Upper[2],
+			)
+		}
+
+		labels = append(labels, v.Window.String())
+		formatted = append(formatted, f)
+	}
+
+	if update.session != nil {
+		labels = append(labels, "session@"+update.session.Anchor.Format(time.RFC3339))
+		formatted = append(formatted, update.session.VWAP.String())
+	}
+
+	for _, v := range update.anchored {
+		labels = append(labels, "anchor@"+v.Anchor.Format(time.RFC3339))
+		formatted = append(formatted, v.VWAP.String())
+	}
+
+	// With a single VWAP, it alone, otherwise each labelled.
+	if len(formatted) > 1 {
+		for a := range formatted {
+			formatted[a] = labels[a] + "=" + formatted[a]
+		}
+	}
+
+	line := strings.Join(formatted, " ")
+
+	if update.spread != nil {
+		s.write("%q: %v (spread: %v)\n", productID, line, *update.spread)
+		return
+	}
+
+	s.write("%q: %v\n", productID, line)
+}
+
+func (s *textSink) notice(productID coinbase.ProductID, notice string) {
+	s.write("%q NOTICE: %v\n", productID, notice)
+}
+
+func (s *textSink) err(productID coinbase.ProductID, err error) {
+	s.write("%q ERROR: %v\n", productID, err)
+}
+
+// write formats a line, as per fmt.Fprintf, and writes it in one piece.
+func (s *textSink) write(format string, a ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Fprintf(s.w, format, a...)
+}
+
+// jsonSink outputs a JSON object per line, per VWAP update.
+type jsonSink struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+
+	// If true, the VWAP and volume of each side, and the imbalance, are output
+	// alongside each VWAP.
+	showSides bool
+
+	// If true, the standard deviation and bands are output alongside each VWAP.
+	showBands bool
+}
+
+func newJSONSink(w io.Writer, showSides, showBands bool) *jsonSink {
+	return &jsonSink{encoder: json.NewEncoder(w), showSides: showSides, showBands: showBands}
+}
+
+// jsonLine is a line output by jsonSink. Only the fields of its kind are set. The
+// spread is a string, as each VWAP is (see jsonVWAP).
+type jsonLine struct {
+	ProductID coinbase.ProductID `json:"product_id"`
+
+	Time     *time.Time       `json:"time,omitempty"`
+	VWAPs    []jsonVWAP       `json:"vwaps,omitempty"`
+	Session  *jsonAnchorVWAP  `json:"session,omitempty"`
+	Anchored []jsonAnchorVWAP `json:"anchored,omitempty"`
+	Spread   string           `json:"spread,omitempty"`
+	Notice   string           `json:"notice,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// jsonVWAP is the VWAP of a window output by jsonSink. The VWAP is a string, so
+// it isn't rounded by consumers that parse it as a float.
+type jsonVWAP struct {
+	Window string `json:"window"`
+	VWAP   string `json:"vwap"`
+	Trades int    `json:"trades"`
+
+	// Only set if showing sides.
+	Buy       *jsonSideVWAP `json:"buy,omitempty"`
+	Sell      *jsonSideVWAP `json:"sell,omitempty"`
+	Imbalance string        `json:"imbalance,omitempty"`
+
+	// Only set if showing bands.
+	Bands *jsonBands `json:"bands,omitempty"`
+}
+
+// jsonBands are the bands of a window output by jsonSink. Upper and Lower are
+// ±1σ, 2σ and 3σ, in that order.
+type jsonBands struct {
+	Variance string    `json:"variance"`
+	StdDev   string    `json:"stddev"`
+	Upper    [3]string `json:"upper"`
+	Lower    [3]string `json:"lower"`
+}
+
+// jsonSideVWAP is the VWAP of a side of a window output by jsonSink.
+type jsonSideVWAP struct {
+	VWAP   string `json:"vwap"`
+	Volume string `json:"volume"`
+}
+
+// jsonAnchorVWAP is an anchored VWAP output by jsonSink.
+type jsonAnchorVWAP struct {
+	Anchor time.Time `json:"anchor"`
+	VWAP   string    `json:"vwap"`
+	Trades int       `json:"trades"`
+}
+
+func newJSONAnchorVWAP(v vwap.AnchorVWAP) jsonAnchorVWAP {
+	return jsonAnchorVWAP{Anchor: v.Anchor, VWAP: v.VWAP.String(), Trades: v.Trades}
+}
+
+func (s *jsonSink) vwaps(productID coinbase.ProductID, update vwapUpdate) {
+	line := jsonLine{ProductID: productID, Time: &update.at, VWAPs: make([]jsonVWAP, len(update.windows))}
+	if update.spread != nil {
+		line.Spread = update.spread.String()
+	}
+
+	for a, v := range update.windows {
+		line.VWAPs[a] = jsonVWAP{Window: v.Window.String(), VWAP: v.VWAP.String(), Trades: v.Trades}
+
+		if s.showSides {
+			line.VWAPs[a].Buy = &jsonSideVWAP{VWAP: v.Buy.VWAP.String(), Volume: v.Buy.Volume.String()}
+			line.VWAPs[a].Sell = &jsonSideVWAP{VWAP: v.Sell.VWAP.String(), Volume: v.Sell.Volume.String()}
+			line.VWAPs[a].Imbalance = v.Imbalance().String()
+		}
+
+		if s.showBands {
+			bands := &jsonBands{Variance: v.Bands.Variance.String(), StdDev: v.Bands.StdDev.String()}
+			for b := range bands.Upper {
+				bands.Upper[b] = v.Bands.Upper[b].String()
+				bands.Lower[b] = v.Bands.Lower[b].String()
+			}
+
+			line.VWAPs[a].Bands = bands
+		}
+	}
+
+	if update.session != nil {
+		session := newJSONAnchorVWAP(*update.session)
+		line.Session = &session
+	}
+
+	for _, v := range update.anchored {
+		line.Anchored = append(line.Anchored, newJSONAnchorVWAP(v))
+	}
+
+	s.write(line)
+}
+
+func (s *jsonSink) notice(productID coinbase.ProductID, notice string) {
+	s.write(jsonLine{ProductID: productID, Notice: notice})
+}
+
+func (s *jsonSink) err(productID coinbase.ProductID, err error) {
+	s.write(jsonLine{ProductID: productID, Error: err.Error()})
+}
+
+// write encodes line to its own line.
+func (s *jsonSink) write(line jsonLine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Only fails if w does, and there's nowhere else to output that.
+	_ = s.encoder.Encode(line)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+// spreads holds the latest ticker, and so spread, per product. It is safe for
+// concurrent use, and a nil *spreads holds none.
+type spreads struct {
+	mu     sync.Mutex
+	latest map[coinbase.ProductID]coinbase.Ticker
+}
+
+func newSpreads() *spreads {
+	return &spreads{latest: make(map[coinbase.ProductID]coinbase.Ticker)}
+}
+
+// set records ticker as the latest for productID.
+func (s *spreads) set(productID coinbase.ProductID, ticker coinbase.Ticker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latest[productID] = ticker
+}
+
+// get returns the latest spread for productID, rounded (half to even) to a multiple
+// of increment, or false if there is none.
+func (s *spreads) get(productID coinbase.ProductID, increment decimal.Decimal) (decimal.Decimal, bool) {
+	if s == nil {
+		return decimal.Decimal{}, false
+	}
+
+	s.mu.Lock()
+	ticker, ok := s.latest[productID]
+	s.mu.Unlock()
+
+	if !ok {
+		return decimal.Decimal{}, false
+	}
+
+	return ticker.Spread(increment), true
+}
+
+// startTrackingSpreads will start recording the latest spread of each product of
+// subscription in productSpreads. Errors and notifications are output to w. wg
+// is used to signal when each loop start/stops.
+func startTrackingSpreads(subscription *coinbase.TickerSubscription, productSpreads *spreads, wg *sync.WaitGroup, w io.Writer) {
+	for _, productID := range subscription.ProductIDs() {
+		startTrackingSpread(subscription, productID, productSpreads, wg, w)
+	}
+}
+
+// startTrackingSpread will start recording the latest spread of productID of
+// subscription in productSpreads. See startTrackingSpreads.
+func startTrackingSpread(subscription *coinbase.TickerSubscription, productID coinbase.ProductID, productSpreads *spreads, wg *sync.WaitGroup, w io.Writer) {
+	read := subscription.ReadProduct(productID)
+
+	wg.Add(1)
+	go func() {
+		trackSpread(read, productID, productSpreads, w)
+
+		wg.Done()
+	}()
+}
+
+// trackSpread reads a TickerResponse from read and records its ticker in productSpreads
+// for productID productID.
+func trackSpread(read <-chan *coinbase.TickerResponse, productID coinbase.ProductID, productSpreads *spreads, w io.Writer) {
+	for tickerResponse := range read {
+		switch {
+		case tickerResponse.Notification != nil:
+			fmt.Fprintf(w, "%q TICKER NOTICE: %v\n", productID, tickerResponse.Notification)
+		case tickerResponse.Err != nil:
+			fmt.Fprintf(w, "%q TICKER ERROR: %v\n", productID, tickerResponse.Err)
+		default:
+			productSpreads.set(productID, tickerResponse.Ticker)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package candles
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+// BarType is what closes a Bar.
+type BarType string
+
+const (
+	// BarTypeVolume bars close once a number of units (base currency) is traded.
+	BarTypeVolume BarType = "volume"
+
+	// BarTypeDollar bars close once a notional (quote currency) is traded.
+	BarTypeDollar BarType = "dollar"
+
+	// BarTypeTick bars close after a number of trades.
+	BarTypeTick BarType = "tick"
+)
+
+// splitPrecision is the number of decimal places beyond those of a trade's units a
+// trade split across dollar bars is split to.
+const splitPrecision = 8
+
+// Bar is the open, high, low and close prices, volume and VWAP of the trades until
+// a threshold was reached.
+type Bar struct {
+	// When the first and last trades happened.
+	Start, End time.Time
+
+	// The prices of the first and last trades, and the highest and lowest prices.
+	Open, High, Low, Close decimal.Decimal
+
+	// The number of units traded.
+	Volume decimal.Decimal
+
+	// The sum of units * unitPrice traded.
+	Notional decimal.Decimal
+
+	// The VWAP of the bar's own trades.
+	VWAP decimal.Decimal
+
+	// The number of trades, where a trade split across bars is counted in each.
+	Trades int
+}
+
+// BarAggregator aggregates trades into bars closed by a threshold of volume, notional
+// or number of trades, in the order they're added. A trade that crosses a volume or
+// notional threshold is split, the units needed to reach it closing the bar and the
+// rest opening the next.
+//
+// Dollar bars can exceed their threshold by a fraction of a unit's price, as the
+// units split off to reach it are rounded up (see splitPrecision).
+//
+// The zero-value of this type has no threshold, and therefore no utility.
+type BarAggregator struct {
+	barType   BarType
+	threshold decimal.Decimal
+
+	// VWAPs are rounded to a multiple of this.
+	increment decimal.Decimal
+
+	// The bar still open, if any.
+	open *bar
+}
+
+// NewBarAggregator creates a new BarAggregator of bars of barType, closed once they
+// reach threshold. Each VWAP is rounded (half to even) to a multiple of increment.
+func NewBarAggregator(barType BarType, threshold, increment decimal.Decimal) (*BarAggregator, error) {
+	switch barType {
+	case BarTypeVolume, BarTypeDollar:
+	case BarTypeTick:
+		if threshold.Round(0).Cmp(threshold) != 0 {
+			return nil, fmt.Errorf("threshold %v of %s bars must be a whole number", threshold, barType)
+		}
+	default:
+		return nil, fmt.Errorf("unknown bar type %q", barType)
+	}
+
+	if threshold.Sign() <= 0 {
+		return nil, fmt.Errorf("threshold %v must be positive", threshold)
+	}
+
+	if increment.Sign() <= 0 {
+		return nil, fmt.Errorf("increment %v must be positive", increment)
+	}
+
+	return &BarAggregator{
+		barType:   barType,
+		threshold: threshold,
+		increment: increment,
+	}, nil
+}
+
+// Add records a new trade (the number of units traded and the price paid per unit)
+// that happened at. The return value is the bars closed by the trade, in order, if
+// any.
+func (a *BarAggregator) Add(at time.Time, units, unitPrice decimal.Decimal) []Bar {
+	if a.threshold.Sign() <= 0 {
+		return nil
+	}
+
+	var closed []Bar
+
+	// Splits are to the same decimal places however many bars the trade crosses.
+	splitScale := units.Scale() + splitPrecision
+
+	for {
+		if a.open == nil {
+			// The bar has no start, so its VWAP includes every trade.
+			a.open = newBar(time.Time{}, 0, a.increment)
+		}
+
+		part := units
+		if remaining, ok := a.remaining(unitPrice, splitScale); ok && remaining.Cmp(units) < 0 {
+			part = remaining
+		}
+
+		a.open.add(at, part, unitPrice)
+		units = units.Sub(part)
+
+		if a.reached() {
+			closed = append(closed, a.open.toBar())
+			a.open = nil
+		}
+
+		if units.Sign() <= 0 {
+			return closed
+		}
+	}
+}
+
+// AddMatch records a new trade from a match (see Add). Notifications are ignored.
+func (a *BarAggregator) AddMatch(m *coinbase.MatchResponse) ([]Bar, error) {
+	if m.Err == nil && m.Notification != nil {
+		return nil, nil
+	}
+
+	units, unitPrice, err := m.ToDecimalUnitsAndUnitPrice()
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Match.Time.IsZero() {
+		return nil, fmt.Errorf("parse time: time is missing")
+	}
+
+	return a.Add(m.Match.Time, units, unitPrice), nil
+}
+
+// Open returns the bar still open, or false if there's none.
+func (a *BarAggregator) Open() (Bar, bool) {
+	if a.open == nil {
+		return Bar{}, false
+	}
+
+	return a.open.toBar(), true
+}
+
+// remaining returns the units at unitPrice needed for the open bar to reach the
+// threshold, to at most scale decimal places, or false if a trade can't be split.
+func (a *BarAggregator) remaining(unitPrice decimal.Decimal, scale int32) (decimal.Decimal, bool) {
+	switch a.barType {
+	case BarTypeVolume:
+		return a.threshold.Sub(a.open.candle.Volume), true
+	case BarTypeDollar:
+		if unitPrice.Sign() <= 0 {
+			return decimal.Decimal{}, false
+		}
+
+		notional := a.threshold.Sub(a.open.notional)
+
+		// Rounded up, so the bar reaches the threshold.
+		needed := notional.DivRound(unitPrice, scale)
+		if needed.Mul(unitPrice).Cmp(notional) < 0 {
+			needed = needed.Add(decimal.New(1, scale))
+		}
+
+		return needed, true
+	default:
+		return decimal.Decimal{}, false
+	}
+}
+
+// reached returns true if the open bar has reached the threshold.
+func (a *BarAggregator) reached() bool {
+	switch a.barType {
+	case BarTypeVolume:
+		return a.open.candle.Volume.Cmp(a.threshold) >= 0
+	case BarTypeDollar:
+		return a.open.notional.Cmp(a.threshold) >= 0
+	default:
+		return decimal.New(int64(a.open.candle.Trades), 0).Cmp(a.threshold) >= 0
+	}
+}
+
+// toBar returns the bar as a Bar.
+func (b *bar) toBar() Bar {
+	return Bar{
+		Start:    b.openAt,
+		End:      b.closeAt,
+		Open:     b.candle.Open,
+		High:     b.candle.High,
+		Low:      b.candle.Low,
+		Close:    b.candle.Close,
+		Volume:   b.candle.Volume,
+		Notional: b.notional,
+		VWAP:     b.candle.VWAP,
+		Trades:   b.candle.Trades,
+	}
+}
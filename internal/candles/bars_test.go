@@ -0,0 +1,245 @@
+package candles
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+// barString returns b as a comparable string.
+func barString(b Bar) string {
+	return fmt.Sprintf(
+		"%s-%s o=%v h=%v l=%v c=%v v=%v n=%v vwap=%v trades=%d",
+		b.Start.Format("15:04:05"), b.End.Format("15:04:05"), b.Open, b.High, b.Low, b.Close, b.Volume, b.Notional, b.VWAP, b.Trades,
+	)
+}
+
+func TestNewBarAggregatorErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name          string
+		giveType      BarType
+		giveThreshold string
+		giveIncrement string
+		expectedErr   string
+	}{
+		{name: "unknown_type", giveType: "TestABC", giveThreshold: "1", giveIncrement: "0.01", expectedErr: `unknown bar type "TestABC"`},
+		{name: "zero_threshold", giveType: BarTypeVolume, giveThreshold: "0", giveIncrement: "0.01", expectedErr: "threshold 0 must be positive"},
+		{name: "fractional_ticks", giveType: BarTypeTick, giveThreshold: "1.5", giveIncrement: "0.01", expectedErr: "threshold 1.5 of tick bars must be a whole number"},
+		{name: "zero_increment", giveType: BarTypeDollar, giveThreshold: "100", giveIncrement: "0", expectedErr: "increment 0 must be positive"},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := NewBarAggregator(tc.giveType, decimal.MustParse(tc.giveThreshold), decimal.MustParse(tc.giveIncrement))
+
+			assert.Nil(t, actual, "Actual")
+			assert.EqualError(t, actualErr, tc.expectedErr, "Actual err")
+		})
+	}
+}
+
+func TestBarAggregatorAdd(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name          string
+		withType      BarType
+		withThreshold string
+		giveTrades    []step
+		expected      []string
+		expectedOpen  string
+	}{
+		{
+			name:          "volume",
+			withType:      BarTypeVolume,
+			withThreshold: "2",
+			giveTrades: []step{
+				{after: 0, units: "1", unitPrice: "10"},
+				{after: time.Second * 10, units: "1", unitPrice: "12"},
+				{after: time.Second * 20, units: "1", unitPrice: "11"},
+			},
+			expected: []string{
+				"12:00:00-12:00:10 o=10 h=12 l=10 c=12 v=2 n=22 vwap=11.00 trades=2",
+			},
+			expectedOpen: "12:00:20-12:00:20 o=11 h=11 l=11 c=11 v=1 n=11 vwap=11.00 trades=1",
+		},
+		{
+			name:          "volume_split",
+			withType:      BarTypeVolume,
+			withThreshold: "2",
+			giveTrades: []step{
+				{after: 0, units: "1.5", unitPrice: "10"},
+				{after: time.Second * 10, units: "3", unitPrice: "12"},
+			},
+			expected: []string{
+				"12:00:00-12:00:10 o=10 h=12 l=10 c=12 v=2.0 n=21.0 vwap=10.50 trades=2",
+				"12:00:10-12:00:10 o=12 h=12 l=12 c=12 v=2 n=24 vwap=12.00 trades=1",
+			},
+			expectedOpen: "12:00:10-12:00:10 o=12 h=12 l=12 c=12 v=0.5 n=6.0 vwap=12.00 trades=1",
+		},
+		{
+			name:          "volume_split_exactly",
+			withType:      BarTypeVolume,
+			withThreshold: "1",
+			giveTrades:    []step{{after: 0, units: "2", unitPrice: "10"}},
+			expected: []string{
+				"12:00:00-12:00:00 o=10 h=10 l=10 c=10 v=1 n=10 vwap=10.00 trades=1",
+				"12:00:00-12:00:00 o=10 h=10 l=10 c=10 v=1 n=10 vwap=10.00 trades=1",
+			},
+		},
+		{
+			name:          "dollar_split",
+			withType:      BarTypeDollar,
+			withThreshold: "100",
+			giveTrades: []step{
+				{after: 0, units: "3", unitPrice: "20"},
+				{after: time.Second * 10, units: "4", unitPrice: "25"},
+			},
+			expected: []string{
+				"12:00:00-12:00:10 o=20 h=25 l=20 c=25 v=4.60000000 n=100.00000000 vwap=21.74 trades=2", // 100 total price / 4.6 total units
+			},
+			expectedOpen: "12:00:10-12:00:10 o=25 h=25 l=25 c=25 v=2.40000000 n=60.00000000 vwap=25.00 trades=1",
+		},
+		{
+			name:          "dollar_split_rounded_up",
+			withType:      BarTypeDollar,
+			withThreshold: "10",
+			giveTrades:    []step{{after: 0, units: "4", unitPrice: "3"}},
+			expected: []string{
+				"12:00:00-12:00:00 o=3 h=3 l=3 c=3 v=3.33333334 n=10.00000002 vwap=3.00 trades=1",
+			},
+			expectedOpen: "12:00:00-12:00:00 o=3 h=3 l=3 c=3 v=0.66666666 n=1.99999998 vwap=3.00 trades=1",
+		},
+		{
+			name:          "tick",
+			withType:      BarTypeTick,
+			withThreshold: "2",
+			giveTrades: []step{
+				{after: 0, units: "5", unitPrice: "10"},
+				{after: time.Second * 10, units: "1", unitPrice: "16"},
+				{after: time.Second * 20, units: "1", unitPrice: "12"},
+			},
+			expected: []string{
+				"12:00:00-12:00:10 o=10 h=16 l=10 c=16 v=6 n=66 vwap=11.00 trades=2",
+			},
+			expectedOpen: "12:00:20-12:00:20 o=12 h=12 l=12 c=12 v=1 n=12 vwap=12.00 trades=1",
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+
+			a, err := NewBarAggregator(tc.withType, decimal.MustParse(tc.withThreshold), decimal.MustParse("0.01"))
+			require.NoError(t, err, "NewBarAggregator")
+
+			// Do
+
+			var actual []string
+			for _, trade := range tc.giveTrades {
+				for _, b := range a.Add(start.Add(trade.after), decimal.MustParse(trade.units), decimal.MustParse(trade.unitPrice)) {
+					actual = append(actual, barString(b))
+				}
+			}
+
+			// Assert
+
+			assert.Equal(t, tc.expected, actual, "Actual")
+
+			actualOpen, ok := a.Open()
+			if tc.expectedOpen == "" {
+				assert.False(t, ok, "Open")
+
+				return
+			}
+
+			require.True(t, ok, "Open")
+			assert.Equal(t, tc.expectedOpen, barString(actualOpen), "Actual open")
+		})
+	}
+}
+
+func TestBarAggregatorAddMatch(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name        string
+		give        *coinbase.MatchResponse
+		expected    []string
+		expectedErr string
+	}{
+		{
+			name:     "match",
+			give:     &coinbase.MatchResponse{Match: coinbase.Match{Time: start, Size: "1", Price: "10"}},
+			expected: []string{"12:00:00-12:00:00 o=10 h=10 l=10 c=10 v=1 n=10 vwap=10.00 trades=1"},
+		},
+		{
+			name: "notification",
+			give: &coinbase.MatchResponse{Notification: &coinbase.ReconnectingNotification{}},
+		},
+		{
+			name:        "missing_time",
+			give:        &coinbase.MatchResponse{Match: coinbase.Match{Size: "1", Price: "10"}},
+			expectedErr: "parse time: time is missing",
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+
+			a, err := NewBarAggregator(BarTypeTick, decimal.MustParse("1"), decimal.MustParse("0.01"))
+			require.NoError(t, err, "NewBarAggregator")
+
+			// Do
+
+			actual, actualErr := a.AddMatch(tc.give)
+
+			// Assert
+
+			if tc.expectedErr != "" {
+				assert.EqualError(t, actualErr, tc.expectedErr, "Actual err")
+
+				return
+			}
+
+			assert.NoError(t, actualErr, "Actual err")
+
+			var actualStrings []string
+			for _, b := range actual {
+				actualStrings = append(actualStrings, barString(b))
+			}
+
+			assert.Equal(t, tc.expected, actualStrings, "Actual")
+		})
+	}
+}
+
+func TestBarAggregatorZeroValue(t *testing.T) {
+	t.Parallel()
+
+	a := &BarAggregator{}
+
+	assert.Nil(t, a.Add(time.Now(), decimal.MustParse("1"), decimal.MustParse("1")), "Add")
+
+	_, ok := a.Open()
+	assert.False(t, ok, "Open")
+}
@@ -0,0 +1,306 @@
+// package candles provides ways to aggregate trades into OHLCV candles (bars) at a
+// fixed interval of time, using the exchange's timestamps, or of a fixed volume,
+// notional or number of trades.
+package candles
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+	"github.com/byatesrae/coinbase_vwap/internal/platform/deque"
+	"github.com/byatesrae/coinbase_vwap/internal/vwap"
+)
+
+// Candle is the open, high, low and close prices, volume and VWAP of the trades in
+// an interval of time.
+type Candle struct {
+	// The start of the interval, inclusive.
+	Start time.Time
+
+	// The length of the interval.
+	Interval time.Duration
+
+	// The prices of the first and last trades, and the highest and lowest prices.
+	// With no trades, they're all the close of the previous candle.
+	Open, High, Low, Close decimal.Decimal
+
+	// The number of units traded.
+	Volume decimal.Decimal
+
+	// The VWAP, or 0 if no units were traded.
+	VWAP decimal.Decimal
+
+	// The number of trades.
+	Trades int
+}
+
+// End returns the end of the interval, exclusive.
+func (c Candle) End() time.Time {
+	return c.Start.Add(c.Interval)
+}
+
+// LateTradeError is returned for a trade in a candle that's already closed.
+type LateTradeError struct {
+	// When the trade happened.
+	At time.Time
+
+	// Candles before this are closed.
+	ClosedUntil time.Time
+}
+
+func (e *LateTradeError) Error() string {
+	return fmt.Sprintf("trade at %v is late, candles are closed until %v", e.At, e.ClosedUntil)
+}
+
+// bar is a candle (or Bar) that's still open.
+type bar struct {
+	candle Candle
+
+	// When the open and close trades happened.
+	openAt, closeAt time.Time
+
+	// The sum of units * unitPrice traded.
+	notional decimal.Decimal
+
+	vwap *vwap.AnchoredVWAP
+}
+
+// newBar creates a new bar of a candle starting at start. Each VWAP is rounded (half
+// to even) to a multiple of increment.
+func newBar(start time.Time, interval time.Duration, increment decimal.Decimal) *bar {
+	return &bar{
+		candle: Candle{Start: start, Interval: interval},
+		vwap:   vwap.NewAnchoredVWAP(start, increment),
+	}
+}
+
+// add records a new trade in the bar.
+func (b *bar) add(at time.Time, units, unitPrice decimal.Decimal) {
+	if b.candle.Trades == 0 || at.Before(b.openAt) {
+		b.candle.Open = unitPrice
+		b.openAt = at
+	}
+
+	if b.candle.Trades == 0 || !at.Before(b.closeAt) {
+		b.candle.Close = unitPrice
+		b.closeAt = at
+	}
+
+	if b.candle.Trades == 0 || unitPrice.Cmp(b.candle.High) > 0 {
+		b.candle.High = unitPrice
+	}
+
+	if b.candle.Trades == 0 || unitPrice.Cmp(b.candle.Low) < 0 {
+		b.candle.Low = unitPrice
+	}
+
+	b.candle.Volume = b.candle.Volume.Add(units)
+	b.notional = b.notional.Add(units.Mul(unitPrice))
+
+	v := b.vwap.Add(at, units, unitPrice)
+	b.candle.VWAP = v.VWAP
+	b.candle.Trades = v.Trades
+}
+
+// Aggregator aggregates trades into candles of a fixed interval, aligned to
+// multiples of the interval since the zero time (e.g. on the minute, for 1m).
+//
+// Candles close once the latest time seen (of a trade, or see Advance) is at least
+// the grace period past their end, so trades arriving out of order within the grace
+// period are still included. Intervals without trades close as candles with no
+// volume, so there's no gap between candles after the first, unless there are more
+// of them in a row than the maximum to fill (e.g. after a long outage, or a trade
+// with a bogus timestamp). Only the last of those are filled, leaving a gap before
+// them.
+//
+// The zero-value of this type has no interval, and therefore no utility.
+type Aggregator struct {
+	interval time.Duration
+	grace    time.Duration
+
+	// VWAPs are rounded to a multiple of this.
+	increment decimal.Decimal
+
+	// The most candles without trades closed in a row.
+	maxFill int
+
+	// Candles still open, in order of start.
+	bars deque.Deque[*bar]
+
+	// The latest time seen.
+	latest time.Time
+
+	// Trades before this are late.
+	closedUntil time.Time
+
+	// The last candle closed, if any.
+	last *Candle
+}
+
+// NewAggregator creates a new Aggregator of candles of interval, closed grace
+// after their end. Each VWAP is rounded (half to even) to a multiple of increment.
+// At most maxFill candles without trades are closed in a row.
+func NewAggregator(interval, grace time.Duration, increment decimal.Decimal, maxFill int) (*Aggregator, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval %v must be positive", interval)
+	}
+
+	if grace < 0 {
+		return nil, fmt.Errorf("grace %v must not be negative", grace)
+	}
+
+	if increment.Sign() <= 0 {
+		return nil, fmt.Errorf("increment %v must be positive", increment)
+	}
+
+	if maxFill < 0 {
+		return nil, fmt.Errorf("max fill %d must not be negative", maxFill)
+	}
+
+	// So the span of the candles filled can't overflow.
+	if int64(maxFill) > math.MaxInt64/int64(interval) {
+		return nil, fmt.Errorf("max fill %d of %v candles is too long", maxFill, interval)
+	}
+
+	return &Aggregator{
+		interval:  interval,
+		grace:     grace,
+		increment: increment,
+		maxFill:   maxFill,
+	}, nil
+}
+
+// Add records a new trade (the number of units traded and the price paid per unit)
+// that happened at. The return value is the candles closed since the last call, in
+// order, if any.
+//
+// If the trade's candle is already closed, it's ignored and a *LateTradeError is
+// returned.
+func (a *Aggregator) Add(at time.Time, units, unitPrice decimal.Decimal) ([]Candle, error) {
+	if a.interval <= 0 {
+		return nil, nil
+	}
+
+	start := at.Truncate(a.interval)
+	if start.Before(a.closedUntil) {
+		return nil, &LateTradeError{At: at, ClosedUntil: a.closedUntil}
+	}
+
+	a.barAt(start).add(at, units, unitPrice)
+
+	return a.Advance(at), nil
+}
+
+// AddMatch records a new trade from a match (see Add). Notifications are ignored.
+func (a *Aggregator) AddMatch(m *coinbase.MatchResponse) ([]Candle, error) {
+	if m.Err == nil && m.Notification != nil {
+		return nil, nil
+	}
+
+	units, unitPrice, err := m.ToDecimalUnitsAndUnitPrice()
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Match.Time.IsZero() {
+		return nil, fmt.Errorf("parse time: time is missing")
+	}
+
+	return a.Add(m.Match.Time, units, unitPrice)
+}
+
+// Advance records that the time is now at least now (e.g. from the exchange's
+// heartbeat), without a trade. The return value is the candles closed since the
+// last call, in order, if any.
+func (a *Aggregator) Advance(now time.Time) []Candle {
+	if a.interval <= 0 {
+		return nil
+	}
+
+	if now.After(a.latest) {
+		a.latest = now
+	}
+
+	// Candles ending at or before this are closed.
+	cutoff := a.latest.Add(-a.grace).Truncate(a.interval)
+	if !cutoff.After(a.closedUntil) {
+		return nil
+	}
+
+	var closed []Candle
+
+	for a.bars.Len() > 0 && a.bars.Front().candle.Start.Before(cutoff) {
+		b := a.bars.PopFront()
+
+		closed = a.fill(closed, b.candle.Start)
+		closed = append(closed, b.candle)
+		a.setLast(b.candle)
+	}
+
+	closed = a.fill(closed, cutoff)
+	a.closedUntil = cutoff
+
+	return closed
+}
+
+// barAt returns the open bar starting at start, adding it if there's none.
+func (a *Aggregator) barAt(start time.Time) *bar {
+	i := a.bars.Len()
+	for ; i > 0; i-- {
+		b := a.bars.At(i - 1)
+		if b.candle.Start.Equal(start) {
+			return b
+		}
+
+		if b.candle.Start.Before(start) {
+			break
+		}
+	}
+
+	b := newBar(start, a.interval, a.increment)
+	a.bars.Insert(i, b)
+
+	return b
+}
+
+// fill appends to closed a candle without trades for each interval from the end of
+// the last candle closed until until, or only the last maxFill of them if there
+// are more. Nothing is filled before the first candle.
+func (a *Aggregator) fill(closed []Candle, until time.Time) []Candle {
+	if a.last == nil {
+		return closed
+	}
+
+	start := a.last.End()
+
+	// Sub saturates if they're centuries apart, which still exceeds maxSpan.
+	if maxSpan := time.Duration(a.maxFill) * a.interval; until.Sub(start) > maxSpan {
+		start = until.Add(-maxSpan)
+	}
+
+	for ; start.Before(until); start = start.Add(a.interval) {
+		empty := Candle{
+			Start:    start,
+			Interval: a.interval,
+			Open:     a.last.Close,
+			High:     a.last.Close,
+			Low:      a.last.Close,
+			Close:    a.last.Close,
+			VWAP:     decimal.Decimal{}.Quantize(a.increment),
+		}
+
+		closed = append(closed, empty)
+		a.setLast(empty)
+	}
+
+	return closed
+}
+
+// setLast records c as the last candle closed. It's a copy, as the candles closed
+// are returned to (and can be modified by) the caller.
+func (a *Aggregator) setLast(c Candle) {
+	a.last = &c
+}
@@ -0,0 +1,333 @@
+package candles
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+// step is either a trade, or (without units) an Advance.
+type step struct {
+	after     time.Duration
+	units     string
+	unitPrice string
+}
+
+// candleStrings returns candles as comparable strings.
+func candleStrings(candles []Candle) []string {
+	var s []string
+	for _, c := range candles {
+		s = append(s, fmt.Sprintf(
+			"%s o=%v h=%v l=%v c=%v v=%v vwap=%v trades=%d",
+			c.Start.Format("15:04:05"), c.Open, c.High, c.Low, c.Close, c.Volume, c.VWAP, c.Trades,
+		))
+	}
+
+	return s
+}
+
+func TestNewAggregatorErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name          string
+		giveInterval  time.Duration
+		giveGrace     time.Duration
+		giveIncrement string
+		giveMaxFill   int
+		expectedErr   string
+	}{
+		{name: "zero_interval", giveInterval: 0, giveIncrement: "0.01", expectedErr: "interval 0s must be positive"},
+		{name: "negative_grace", giveInterval: time.Minute, giveGrace: -time.Second, giveIncrement: "0.01", expectedErr: "grace -1s must not be negative"},
+		{name: "zero_increment", giveInterval: time.Minute, giveIncrement: "0", expectedErr: "increment 0 must be positive"},
+		{name: "negative_max_fill", giveInterval: time.Minute, giveIncrement: "0.01", giveMaxFill: -1, expectedErr: "max fill -1 must not be negative"},
+		{name: "max_fill_overflows", giveInterval: time.Hour, giveIncrement: "0.01", giveMaxFill: math.MaxInt64/int(time.Hour) + 1, expectedErr: "max fill 2562048 of 1h0m0s candles is too long"},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := NewAggregator(tc.giveInterval, tc.giveGrace, decimal.MustParse(tc.giveIncrement), tc.giveMaxFill)
+
+			assert.Nil(t, actual, "Actual")
+			assert.EqualError(t, actualErr, tc.expectedErr, "Actual err")
+		})
+	}
+}
+
+func TestAggregatorAdd(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name      string
+		giveSteps []step
+		expected  []string
+	}{
+		{
+			name:      "open",
+			giveSteps: []step{{after: 0, units: "1", unitPrice: "10"}},
+		},
+		{
+			name: "closes_after_grace",
+			giveSteps: []step{
+				{after: 0, units: "1", unitPrice: "10"},
+				{after: time.Second * 30, units: "2", unitPrice: "12"},
+				{after: time.Second * 45, units: "1", unitPrice: "9"},
+				{after: time.Second * 65, units: "1", unitPrice: "11"},
+				{after: time.Second * 70, units: "1", unitPrice: "11"},
+			},
+			expected: []string{
+				"12:00:00 o=10 h=12 l=9 c=9 v=4 vwap=10.75 trades=3", // 43 total price / 4 total units
+			},
+		},
+		{
+			name: "out_of_order_within_grace",
+			giveSteps: []step{
+				{after: time.Second * 30, units: "1", unitPrice: "10"},
+				{after: time.Second * 65, units: "1", unitPrice: "20"},
+				{after: time.Second * 55, units: "1", unitPrice: "12"},
+				{after: time.Second * 10, units: "2", unitPrice: "11"},
+				{after: time.Second * 70, units: "1", unitPrice: "20"},
+			},
+			expected: []string{
+				"12:00:00 o=11 h=12 l=10 c=12 v=4 vwap=11.00 trades=3",
+			},
+		},
+		{
+			name: "empty_intervals",
+			giveSteps: []step{
+				{after: 0, units: "1", unitPrice: "10"},
+				{after: time.Second * 195, units: "1", unitPrice: "11"},
+			},
+			expected: []string{
+				"12:00:00 o=10 h=10 l=10 c=10 v=1 vwap=10.00 trades=1",
+				"12:01:00 o=10 h=10 l=10 c=10 v=0 vwap=0.00 trades=0",
+				"12:02:00 o=10 h=10 l=10 c=10 v=0 vwap=0.00 trades=0",
+			},
+		},
+		{
+			name: "empty_intervals_beyond_max_fill",
+			giveSteps: []step{
+				{after: 0, units: "1", unitPrice: "10"},
+				{after: time.Hour * 24 * 365 * 100, units: "1", unitPrice: "11"},
+			},
+			expected: append(
+				[]string{"12:00:00 o=10 h=10 l=10 c=10 v=1 vwap=10.00 trades=1"},
+				// Only the last 10 are filled, the trade's candle is still open.
+				"11:49:00 o=10 h=10 l=10 c=10 v=0 vwap=0.00 trades=0",
+				"11:50:00 o=10 h=10 l=10 c=10 v=0 vwap=0.00 trades=0",
+				"11:51:00 o=10 h=10 l=10 c=10 v=0 vwap=0.00 trades=0",
+				"11:52:00 o=10 h=10 l=10 c=10 v=0 vwap=0.00 trades=0",
+				"11:53:00 o=10 h=10 l=10 c=10 v=0 vwap=0.00 trades=0",
+				"11:54:00 o=10 h=10 l=10 c=10 v=0 vwap=0.00 trades=0",
+				"11:55:00 o=10 h=10 l=10 c=10 v=0 vwap=0.00 trades=0",
+				"11:56:00 o=10 h=10 l=10 c=10 v=0 vwap=0.00 trades=0",
+				"11:57:00 o=10 h=10 l=10 c=10 v=0 vwap=0.00 trades=0",
+				"11:58:00 o=10 h=10 l=10 c=10 v=0 vwap=0.00 trades=0",
+			),
+		},
+		{
+			name: "advance",
+			giveSteps: []step{
+				{after: time.Second * 20, units: "1", unitPrice: "10"},
+				{after: time.Second * 150},
+			},
+			expected: []string{
+				"12:00:00 o=10 h=10 l=10 c=10 v=1 vwap=10.00 trades=1",
+				"12:01:00 o=10 h=10 l=10 c=10 v=0 vwap=0.00 trades=0",
+			},
+		},
+		{
+			name: "advance_backwards",
+			giveSteps: []step{
+				{after: time.Second * 20, units: "1", unitPrice: "10"},
+				{after: time.Second * 75},
+				{after: 0},
+			},
+			expected: []string{
+				"12:00:00 o=10 h=10 l=10 c=10 v=1 vwap=10.00 trades=1",
+			},
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+
+			a, err := NewAggregator(time.Minute, time.Second*10, decimal.MustParse("0.01"), 10)
+			require.NoError(t, err, "NewAggregator")
+
+			// Do
+
+			var actual []Candle
+			for _, s := range tc.giveSteps {
+				if s.units == "" {
+					actual = append(actual, a.Advance(start.Add(s.after))...)
+
+					continue
+				}
+
+				closed, err := a.Add(start.Add(s.after), decimal.MustParse(s.units), decimal.MustParse(s.unitPrice))
+				require.NoError(t, err, "Add")
+
+				actual = append(actual, closed...)
+			}
+
+			// Assert
+
+			assert.Equal(t, tc.expected, candleStrings(actual), "Actual")
+
+			for _, c := range actual {
+				assert.Equal(t, time.Minute, c.Interval, "Interval of %v", c.Start)
+			}
+		})
+	}
+}
+
+func TestAggregatorAddLate(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	a, err := NewAggregator(time.Minute, time.Second*10, decimal.MustParse("0.01"), 10)
+	require.NoError(t, err, "NewAggregator")
+
+	_, err = a.Add(start, decimal.MustParse("1"), decimal.MustParse("10"))
+	require.NoError(t, err, "Add")
+
+	closed, err := a.Add(start.Add(time.Second*70), decimal.MustParse("1"), decimal.MustParse("11"))
+	require.NoError(t, err, "Add")
+	require.Len(t, closed, 1, "Closed")
+
+	// Do
+
+	actual, actualErr := a.Add(start.Add(time.Second*59), decimal.MustParse("1"), decimal.MustParse("12"))
+
+	// Assert
+
+	assert.Nil(t, actual, "Actual")
+
+	var lateErr *LateTradeError
+	require.True(t, errors.As(actualErr, &lateErr), "Actual err %v is a *LateTradeError", actualErr)
+	assert.Equal(t, start.Add(time.Second*59), lateErr.At, "At")
+	assert.Equal(t, start.Add(time.Minute), lateErr.ClosedUntil, "ClosedUntil")
+}
+
+func TestAggregatorAddMatch(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name        string
+		give        *coinbase.MatchResponse
+		expected    []string
+		expectedErr string
+	}{
+		{
+			name: "match",
+			give: &coinbase.MatchResponse{Match: coinbase.Match{Time: start.Add(time.Minute * 2), Size: "1", Price: "11"}},
+			expected: []string{
+				"12:00:00 o=10 h=10 l=10 c=10 v=1 vwap=10.00 trades=1",
+				"12:01:00 o=10 h=10 l=10 c=10 v=0 vwap=0.00 trades=0",
+			},
+		},
+		{
+			name: "notification",
+			give: &coinbase.MatchResponse{Notification: &coinbase.ReconnectingNotification{}},
+		},
+		{
+			name:        "err",
+			give:        &coinbase.MatchResponse{Err: errors.New("TestABC")},
+			expectedErr: "match response: TestABC",
+		},
+		{
+			name:        "missing_time",
+			give:        &coinbase.MatchResponse{Match: coinbase.Match{Size: "1", Price: "11"}},
+			expectedErr: "parse time: time is missing",
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+
+			a, err := NewAggregator(time.Minute, 0, decimal.MustParse("0.01"), 10)
+			require.NoError(t, err, "NewAggregator")
+
+			_, err = a.Add(start, decimal.MustParse("1"), decimal.MustParse("10"))
+			require.NoError(t, err, "Add")
+
+			// Do
+
+			actual, actualErr := a.AddMatch(tc.give)
+
+			// Assert
+
+			if tc.expectedErr != "" {
+				assert.EqualError(t, actualErr, tc.expectedErr, "Actual err")
+
+				return
+			}
+
+			assert.NoError(t, actualErr, "Actual err")
+			assert.Equal(t, tc.expected, candleStrings(actual), "Actual")
+		})
+	}
+}
+
+func TestAggregatorZeroValue(t *testing.T) {
+	t.Parallel()
+
+	a := &Aggregator{}
+
+	actual, actualErr := a.Add(time.Now(), decimal.MustParse("1"), decimal.MustParse("1"))
+	assert.NoError(t, actualErr, "Add err")
+	assert.Nil(t, actual, "Add")
+	assert.Nil(t, a.Advance(time.Now()), "Advance")
+}
+
+func TestAggregatorClosedModified(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	a, err := NewAggregator(time.Minute, 0, decimal.MustParse("0.01"), 10)
+	require.NoError(t, err, "NewAggregator")
+
+	_, err = a.Add(start, decimal.MustParse("1"), decimal.MustParse("10"))
+	require.NoError(t, err, "Add")
+
+	closed := a.Advance(start.Add(time.Minute))
+	require.Len(t, closed, 1, "Closed")
+
+	closed[0].Close = decimal.MustParse("99")
+	closed[0].Start = time.Time{}
+
+	// Do
+
+	actual := a.Advance(start.Add(time.Minute * 2))
+
+	// Assert
+
+	assert.Equal(t, []string{"12:01:00 o=10 h=10 l=10 c=10 v=0 vwap=0.00 trades=0"}, candleStrings(actual), "Actual")
+}
@@ -0,0 +1,351 @@
+package coinbase
+
+import (
+	"fmt"
+	"sync"
+)
+
+// defaultReadBufferSize is the buffer size of each product's read channel if
+// Backpressure.BufferSize is 0.
+const defaultReadBufferSize = 10
+
+// OverflowPolicy is what a subscription does with a message for a product whose
+// read channel is full, i.e. when the reader has fallen behind.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock blocks until there is room in the read channel. The websocket
+	// isn't read in the meantime, so messages back up upstream. This is the default.
+	OverflowBlock OverflowPolicy = "block"
+
+	// OverflowDropOldest discards the oldest message in the read channel to make
+	// room for the new one.
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+
+	// OverflowDropNewest discards the new message.
+	OverflowDropNewest OverflowPolicy = "drop_newest"
+
+	// OverflowCoalesce discards every message in the read channel, so the next
+	// message read is the new (i.e. latest) one.
+	OverflowCoalesce OverflowPolicy = "coalesce"
+)
+
+// validate returns an error if the policy is unknown. An empty policy is treated
+// as OverflowBlock.
+func (p OverflowPolicy) validate() error {
+	switch p {
+	case "", OverflowBlock, OverflowDropOldest, OverflowDropNewest, OverflowCoalesce:
+		return nil
+	default:
+		return fmt.Errorf("unknown overflow policy %q", string(p))
+	}
+}
+
+// isLossy returns true if the policy discards messages.
+func (p OverflowPolicy) isLossy() bool {
+	return p == OverflowDropOldest || p == OverflowDropNewest || p == OverflowCoalesce
+}
+
+// Backpressure configures the read channel of each product of a subscription.
+// Messages discarded by the OverflowPolicy are counted, see e.g.
+// MatchesSubscription.Dropped. Errors and notifications are never discarded, if
+// the read channel is full of them, pushing blocks as per OverflowBlock.
+//
+// Level2 and Full subscriptions can't discard messages without their books
+// drifting, so they only support OverflowBlock.
+type Backpressure struct {
+	// BufferSize is the number of messages each read channel buffers. If 0, 10 is
+	// used.
+	BufferSize int
+
+	// Overflow is what to do when a read channel is full. If empty, OverflowBlock
+	// is used.
+	Overflow OverflowPolicy
+}
+
+// validate returns an error if b is invalid for a subscription of kind.
+func (b Backpressure) validate(kind feedKind) error {
+	if b.BufferSize < 0 {
+		return fmt.Errorf("backpressure buffer size must not be negative, got %d", b.BufferSize)
+	}
+
+	if err := b.Overflow.validate(); err != nil {
+		return fmt.Errorf("backpressure: %w", err)
+	}
+
+	if kind.lossless && b.Overflow.isLossy() {
+		return fmt.Errorf("backpressure: overflow policy %q would discard %s messages, only %q is supported", string(b.Overflow), kind.channelDescription, string(OverflowBlock))
+	}
+
+	return nil
+}
+
+// bufferSize returns the buffer size of a read channel.
+func (b Backpressure) bufferSize() int {
+	if b.BufferSize == 0 {
+		return defaultReadBufferSize
+	}
+
+	return b.BufferSize
+}
+
+// response is a value pushed to a read channel, e.g. a *MatchResponse.
+type response interface {
+	// isMessage returns true if the response is a message, rather than an error
+	// or a notification. Only messages are discarded by an overflow policy.
+	isMessage() bool
+}
+
+// readChannel is the read channel of a product, with what's needed to push to
+// it under a lossy overflow policy. Pushes to one read channel never wait on pushes
+// to another.
+//
+// Under a lossy overflow policy, responses are queued rather than buffered by ch,
+// and handed to the reader one at a time by forward. Messages are only ever
+// discarded from the queue, as taking them from ch would race the reader and could
+// reorder what's read.
+type readChannel[R response] struct {
+	ch chan R
+
+	// The number of responses queued before overflow applies.
+	size int
+
+	// Guards the fields below. It's never held while blocking on a send.
+	mu sync.Mutex
+
+	// The responses not yet read, oldest first, each numbered so forward can tell
+	// whether the one it handed to the reader was discarded meanwhile.
+	queue []queuedResponse[R]
+	next  uint64
+
+	// Signalled when queue or closed changes, so forward offers the new front.
+	changed chan struct{}
+
+	// Closed (and replaced) when forward takes from queue, waking pushes blocked
+	// on a full queue so they retry under mu.
+	taken chan struct{}
+
+	// Whether close has been invoked.
+	closed bool
+
+	// The number of messages discarded (or not pushed).
+	dropped uint64
+}
+
+// queuedResponse is a response queued by a readChannel.
+type queuedResponse[R response] struct {
+	n        uint64
+	response R
+}
+
+// newReadChannel creates a new readChannel buffering size responses. Under a lossy
+// overflow policy, this starts forwarding them to the reader until close is
+// invoked and every response has been read.
+func newReadChannel[R response](size int, overflow OverflowPolicy) *readChannel[R] {
+	if !overflow.isLossy() {
+		return &readChannel[R]{ch: make(chan R, size), size: size}
+	}
+
+	r := &readChannel[R]{
+		ch:      make(chan R),
+		size:    size,
+		changed: make(chan struct{}, 1),
+		taken:   make(chan struct{}),
+	}
+
+	go r.forward()
+
+	return r
+}
+
+// push pushes response to the read channel. If its buffer is full, overflow
+// applies. When blocking, this blocks until there's room or stop is closed.
+// Returns false if response wasn't pushed.
+func (r *readChannel[R]) push(response R, overflow OverflowPolicy, stop <-chan struct{}) bool {
+	if !overflow.isLossy() {
+		return pushBlocking(r.ch, response, stop)
+	}
+
+	for {
+		pushed, ok, taken := r.pushLossy(response, overflow)
+		if ok {
+			return pushed
+		}
+
+		// Retried under mu, as other pushes may take the room made meanwhile.
+		select {
+		case <-taken:
+		case <-stop:
+			return false
+		}
+	}
+}
+
+// pushLossy queues response without blocking, discarding messages (either response
+// or those queued) as per overflow if the queue is full. Errors and notifications
+// aren't discarded. Returns whether response was pushed, or false for ok if there
+// was no message to discard (and it must be retried once taken is closed).
+func (r *readChannel[R]) pushLossy(response R, overflow OverflowPolicy) (pushed, ok bool, taken <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Favour pushing, even if stopping, while there's room in the queue.
+	if len(r.queue) < r.size {
+		r.enqueue(response)
+
+		return true, true, nil
+	}
+
+	if overflow == OverflowDropNewest {
+		if !response.isMessage() {
+			return false, false, r.taken
+		}
+
+		r.dropped++
+
+		return false, true, nil
+	}
+
+	if r.discard(overflow == OverflowCoalesce) == 0 {
+		return false, false, r.taken
+	}
+
+	r.enqueue(response)
+
+	return true, true, nil
+}
+
+// enqueue appends response to the queue. mu must be held.
+func (r *readChannel[R]) enqueue(response R) {
+	r.queue = append(r.queue, queuedResponse[R]{n: r.next, response: response})
+	r.next++
+
+	r.signalChanged()
+}
+
+// discard discards the oldest queued message (or every queued message if all is
+// true), keeping the order of the rest. Returns the number discarded. mu must be
+// held.
+func (r *readChannel[R]) discard(all bool) uint64 {
+	var (
+		kept      = make([]queuedResponse[R], 0, len(r.queue))
+		discarded uint64
+	)
+
+	for _, queued := range r.queue {
+		if queued.response.isMessage() && (all || discarded == 0) {
+			discarded++
+
+			continue
+		}
+
+		kept = append(kept, queued)
+	}
+
+	if discarded > 0 {
+		r.queue = kept
+		r.dropped += discarded
+
+		r.signalChanged()
+	}
+
+	return discarded
+}
+
+// signalChanged signals forward that the queue (or closed) has changed, without
+// blocking. mu must be held.
+func (r *readChannel[R]) signalChanged() {
+	select {
+	case r.changed <- struct{}{}:
+	default:
+	}
+}
+
+// forward hands each queued response to the reader, in order, until close has
+// been invoked and the queue is empty. ch is then closed.
+func (r *readChannel[R]) forward() {
+	for {
+		r.mu.Lock()
+
+		if len(r.queue) == 0 && r.closed {
+			r.mu.Unlock()
+			close(r.ch)
+
+			return
+		}
+
+		// The front is offered while it's queued, rather than taken first, so it can
+		// still be discarded (and counts towards the queue's size) until it's read.
+		var (
+			offer chan R
+			front queuedResponse[R]
+		)
+
+		if len(r.queue) > 0 {
+			offer, front = r.ch, r.queue[0]
+		}
+
+		r.mu.Unlock()
+
+		select {
+		case offer <- front.response:
+			r.mu.Lock()
+
+			if len(r.queue) > 0 && r.queue[0].n == front.n {
+				r.queue = r.queue[1:]
+			} else {
+				// Discarded after it was read, so it wasn't dropped after all.
+				r.dropped--
+			}
+
+			close(r.taken)
+			r.taken = make(chan struct{})
+
+			r.mu.Unlock()
+		case <-r.changed:
+		}
+	}
+}
+
+// close closes the read channel once every response pushed has been read. It
+// mustn't be pushed to afterwards.
+func (r *readChannel[R]) close() {
+	if r.changed == nil {
+		close(r.ch)
+
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+
+	r.signalChanged()
+}
+
+// droppedCount returns the number of messages discarded by the overflow policy.
+func (r *readChannel[R]) droppedCount() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.dropped
+}
+
+// pushBlocking pushes response to read, blocking until there's room or stop is
+// closed. Returns false if response wasn't pushed.
+func pushBlocking[R any](read chan R, response R, stop <-chan struct{}) bool {
+	// Favour pushing, even if stopping, while there's room in the buffer.
+	select {
+	case read <- response:
+		return true
+	default:
+	}
+
+	select {
+	case read <- response:
+		return true
+	case <-stop:
+		return false
+	}
+}
@@ -3,12 +3,57 @@ package coinbase
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"time"
 )
 
 // Client can be used to integrate with the Coinbase API.
 type Client struct {
-	// The Dialer used to open a connection. If nil, a default is used.
+	// The Dialer used to open a connection. If nil, a default configured with
+	// DialerOptions is used.
 	Dialer Dialer
+
+	// DialerOptions tune the default Dialer. Ignored if Dialer is set.
+	DialerOptions DialerOptions
+
+	// Environment is the Coinbase environment to connect to. If empty,
+	// EnvironmentProduction is used.
+	Environment Environment
+
+	// FeedURL overrides the websocket feed URL of the Environment, e.g. to connect
+	// to a mock feed.
+	FeedURL string
+
+	// RequestHeader holds extra headers sent with the websocket handshake.
+	RequestHeader http.Header
+
+	// Credentials are used to sign every subscribe request. If nil, subscriptions
+	// are unauthenticated.
+	Credentials *Credentials
+
+	// Reconnect is the policy subscriptions use to redial and resubscribe when
+	// their connection fails. If nil, subscriptions don't reconnect.
+	Reconnect *ReconnectPolicy
+
+	// Backfill is used by matches subscriptions to fetch trades that are missing
+	// (indicated by a gap in trade IDs). If nil, missing trades aren't fetched.
+	Backfill *RESTClient
+
+	// LivenessTimeout is how long a subscription waits for a product to see a
+	// heartbeat or match before reporting it as stale (and, if reconnecting, forcing
+	// a reconnect). If 0, products are not checked for staleness.
+	LivenessTimeout time.Duration
+
+	// Decoders decode the messages read by subscriptions to any channels (see
+	// SubscribeToChannelsForProducts), e.g. to decode types of a new channel. If
+	// nil, NewDecoders is used (with the Full Channel's messages decoded as a
+	// *FullMessage, if subscribed to).
+	Decoders *Decoders
+
+	// Backpressure configures the read channel of each product of a subscription.
+	// If zero, each buffers 10 messages and blocks when full. Level2 and Full
+	// subscriptions can only block.
+	Backpressure Backpressure
 }
 
 func (c *Client) dialerOrDefault() Dialer {
@@ -17,7 +62,16 @@ func (c *Client) dialerOrDefault() Dialer {
 	}
 
 	// By default, use gorilla
-	return newGorillaWebsocketDialler(nil)
+	return newGorillaWebsocketDialler(c.DialerOptions)
+}
+
+// feedURL returns the websocket feed URL to dial.
+func (c *Client) feedURL() (string, error) {
+	if c.FeedURL != "" {
+		return c.FeedURL, nil
+	}
+
+	return c.Environment.FeedURL()
 }
 
 // SubscribeToMatchesForProduct will dial a new websocket connection and [Subscribe]
@@ -26,12 +80,137 @@ func (c *Client) dialerOrDefault() Dialer {
 // [Subscribe]: https://docs.cloud.coinbase.com/exchange/docs/websocket-overview#subscribe
 // [Matches Channel]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#matches-channel
 func (c *Client) SubscribeToMatchesForProduct(ctx context.Context, productID ProductID) (*MatchesSubscription, error) {
-	conn, _, err := c.dialerOrDefault().DialContext(ctx, "wss://ws-feed.exchange.coinbase.com", nil)
+	return c.SubscribeToMatchesForProducts(ctx, []ProductID{productID})
+}
+
+// SubscribeToMatchesForProducts will dial a single new websocket connection and
+// [Subscribe] to the [Matches Channel] for all products by ProductID. Matches
+// are routed to a read channel per product, see MatchesSubscription.ReadProduct.
+//
+// [Subscribe]: https://docs.cloud.coinbase.com/exchange/docs/websocket-overview#subscribe
+// [Matches Channel]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#matches-channel
+func (c *Client) SubscribeToMatchesForProducts(ctx context.Context, productIDs []ProductID) (*MatchesSubscription, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newMatchesSubscription(ctx, conn, c.subscriptionOptions(), productIDs...)
+}
+
+// SubscribeToTickerForProducts will dial a single new websocket connection and
+// [Subscribe] to the [Ticker Channel] for all products by ProductID. Tickers are
+// routed to a read channel per product, see TickerSubscription.ReadProduct.
+//
+// [Subscribe]: https://docs.cloud.coinbase.com/exchange/docs/websocket-overview#subscribe
+// [Ticker Channel]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#ticker-channel
+func (c *Client) SubscribeToTickerForProducts(ctx context.Context, productIDs []ProductID) (*TickerSubscription, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTickerSubscription(conn, c.subscriptionOptions(), productIDs...)
+}
+
+// SubscribeToLevel2ForProducts will dial a single new websocket connection and
+// [Subscribe] to the [Level2 Channel] for all products by ProductID. channel should
+// be ChannelNameLevel2 (which requires Credentials) or ChannelNameLevel2Batch.
+// Messages are routed to a read channel per product, see Level2Subscription.ReadProduct.
+//
+// [Subscribe]: https://docs.cloud.coinbase.com/exchange/docs/websocket-overview#subscribe
+// [Level2 Channel]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#level2-channel
+func (c *Client) SubscribeToLevel2ForProducts(ctx context.Context, channel ChannelName, productIDs []ProductID) (*Level2Subscription, error) {
+	if err := validateLevel2Channel(channel); err != nil {
+		return nil, err
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLevel2Subscription(conn, c.subscriptionOptions(), channel, productIDs...)
+}
+
+// SubscribeToFullForProducts will dial a single new websocket connection and
+// [Subscribe] to the [Full Channel] for all products by ProductID. Messages are
+// routed to a read channel per product, see FullSubscription.ReadProduct.
+//
+// [Subscribe]: https://docs.cloud.coinbase.com/exchange/docs/websocket-overview#subscribe
+// [Full Channel]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#full-channel
+func (c *Client) SubscribeToFullForProducts(ctx context.Context, productIDs []ProductID) (*FullSubscription, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFullSubscription(conn, c.subscriptionOptions(), productIDs...)
+}
+
+// SubscribeToChannelsForProducts will dial a single new websocket connection and
+// [Subscribe] to channels for all products by ProductID. Every message is decoded
+// with Client.Decoders and routed to a read channel per product, see
+// MessageSubscription.ReadProduct.
+//
+// [Subscribe]: https://docs.cloud.coinbase.com/exchange/docs/websocket-overview#subscribe
+func (c *Client) SubscribeToChannelsForProducts(ctx context.Context, channels []ChannelName, productIDs []ProductID) (*MessageSubscription, error) {
+	if err := validateChannels(channels); err != nil {
+		return nil, err
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newMessageSubscription(conn, c.subscriptionOptions(), c.decodersFor(channels), channels, productIDs...)
+}
+
+// decodersFor returns the Decoders for a subscription to channels.
+func (c *Client) decodersFor(channels []ChannelName) *Decoders {
+	if c.Decoders != nil {
+		return c.Decoders
+	}
+
+	for _, channel := range channels {
+		if channel == ChannelNameFull {
+			return fullDecoders
+		}
+	}
+
+	return defaultDecoders
+}
+
+// subscriptionOptions returns the options for subscriptions created by the Client.
+func (c *Client) subscriptionOptions() subscriptionOptions {
+	return subscriptionOptions{
+		reconnector:     newReconnector(c.Reconnect, c.dial),
+		backfill:        c.Backfill,
+		livenessTimeout: c.LivenessTimeout,
+		credentials:     c.Credentials,
+		backpressure:    c.Backpressure,
+	}
+}
+
+// dial opens a new websocket connection to the Coinbase feed.
+func (c *Client) dial(ctx context.Context) (Conn, error) {
+	feedURL, err := c.feedURL()
 	if err != nil {
-		// If errors.Is(websocket.ErrBadHandshake, err) we could inspect the response
-		// for further information that would aid in debugging.
 		return nil, fmt.Errorf("dialing coinbase: %w", err)
 	}
 
-	return newMatchesSubscription(ctx, conn, productID)
+	conn, response, err := c.dialerOrDefault().DialContext(ctx, feedURL, c.RequestHeader.Clone())
+	if err != nil {
+		transportErr := &TransportError{Op: "dialing coinbase", Err: err}
+
+		// Set if the handshake failed, which determines if the error is retryable.
+		if response != nil {
+			transportErr.StatusCode = response.StatusCode
+		}
+
+		return nil, transportErr
+	}
+
+	return conn, nil
 }
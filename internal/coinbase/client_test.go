@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -19,6 +21,10 @@ func TestSubscribeToMatchesForProduct(t *testing.T) {
 					return &ConnMock{
 						WriteJSONFunc: func(v interface{}) error { return nil },
 						CloseFunc:     func() error { return nil },
+						WriteControlFunc: func(messageType int, data []byte, deadline time.Time) error {
+							return nil
+						},
+						SetReadDeadlineFunc: func(t time.Time) error { return nil },
 						ReadJSONFunc: func(v interface{}) error {
 							return fmt.Errorf("TestABC")
 						},
@@ -90,3 +96,176 @@ func TestSubscribeToMatchesForProduct(t *testing.T) {
 		})
 	}
 }
+
+func TestSubscribeToMatchesForProducts(t *testing.T) {
+	t.Parallel()
+
+	var dialCount int32
+
+	conn := &ConnMock{
+		WriteJSONFunc: func(v interface{}) error { return nil },
+		CloseFunc:     func() error { return nil },
+		WriteControlFunc: func(messageType int, data []byte, deadline time.Time) error {
+			return nil
+		},
+		SetReadDeadlineFunc: func(t time.Time) error { return nil },
+		ReadJSONFunc: func(v interface{}) error {
+			return fmt.Errorf("TestABC")
+		},
+	}
+
+	c := Client{
+		Dialer: &DialerMock{
+			DialContextFunc: func(_ context.Context, _ string, _ http.Header) (Conn, *http.Response, error) {
+				atomic.AddInt32(&dialCount, 1)
+
+				return conn, nil, nil
+			},
+		},
+	}
+
+	ms, err := c.SubscribeToMatchesForProducts(context.Background(), []ProductID{ProductIDBtcUsd, ProductIDEthUsd, ProductIDEthBtc})
+	if !assert.NoError(t, err, "Subscribe err") {
+		return
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dialCount), "Dial count")
+	if assert.Len(t, conn.WriteJSONCalls(), 1, "WriteJSON calls") {
+		assert.Equal(
+			t,
+			SubscribeRequest{
+				Type: "subscribe",
+				Channels: []SubscribeChannelRequest{
+					{
+						Name:       ChannelNameMatches,
+						ProductIDs: []ProductID{ProductIDBtcUsd, ProductIDEthUsd, ProductIDEthBtc},
+					},
+					{
+						Name:       ChannelNameHeartbeat,
+						ProductIDs: []ProductID{ProductIDBtcUsd, ProductIDEthUsd, ProductIDEthBtc},
+					},
+				},
+			},
+			conn.WriteJSONCalls()[0].V,
+			"Subscribe request",
+		)
+	}
+
+	assert.NoError(t, ms.Close(context.Background()), "Close")
+}
+
+func TestClientDial(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name           string
+		with           *Client
+		expectedURL    string
+		expectedHeader http.Header
+		expectedErr    string
+	}{
+		{
+			name:        "default",
+			with:        &Client{},
+			expectedURL: "wss://ws-feed.exchange.coinbase.com",
+		},
+		{
+			name:        "sandbox",
+			with:        &Client{Environment: EnvironmentSandbox},
+			expectedURL: "wss://ws-feed-public.sandbox.exchange.coinbase.com",
+		},
+		{
+			name:        "direct",
+			with:        &Client{Environment: EnvironmentDirect},
+			expectedURL: "wss://ws-direct.exchange.coinbase.com",
+		},
+		{
+			name: "feed_url_and_header",
+			with: &Client{
+				Environment:   EnvironmentSandbox,
+				FeedURL:       "ws://localhost:8080",
+				RequestHeader: http.Header{"X-Test": []string{"ABC"}},
+			},
+			expectedURL:    "ws://localhost:8080",
+			expectedHeader: http.Header{"X-Test": []string{"ABC"}},
+		},
+		{
+			name:        "unknown_environment",
+			with:        &Client{Environment: "TestABC"},
+			expectedErr: `dialing coinbase: unknown environment "TestABC"`,
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+			dialer := &DialerMock{
+				DialContextFunc: func(_ context.Context, _ string, _ http.Header) (Conn, *http.Response, error) {
+					return &ConnMock{}, nil, nil
+				},
+			}
+			tc.with.Dialer = dialer
+
+			// Do
+			_, actualErr := tc.with.dial(context.Background())
+
+			// Assert
+			if tc.expectedErr != "" {
+				assert.EqualError(t, actualErr, tc.expectedErr, "Actual err")
+				assert.Empty(t, dialer.DialContextCalls(), "DialContext calls")
+
+				return
+			}
+
+			assert.NoError(t, actualErr, "Actual err")
+			if assert.Len(t, dialer.DialContextCalls(), 1, "DialContext calls") {
+				assert.Equal(t, tc.expectedURL, dialer.DialContextCalls()[0].UrlStr, "URL")
+				assert.Equal(t, tc.expectedHeader, dialer.DialContextCalls()[0].RequestHeader, "Request header")
+			}
+		})
+	}
+}
+
+func TestSubscribeToMatchesForProductsWithCredentials(t *testing.T) {
+	t.Parallel()
+
+	conn := &ConnMock{
+		WriteJSONFunc: func(v interface{}) error { return nil },
+		CloseFunc:     func() error { return nil },
+		WriteControlFunc: func(messageType int, data []byte, deadline time.Time) error {
+			return nil
+		},
+		SetReadDeadlineFunc: func(t time.Time) error { return nil },
+		ReadJSONFunc: func(v interface{}) error {
+			return fmt.Errorf("TestABC")
+		},
+	}
+
+	c := Client{
+		Dialer: &DialerMock{
+			DialContextFunc: func(_ context.Context, _ string, _ http.Header) (Conn, *http.Response, error) {
+				return conn, nil, nil
+			},
+		},
+		Credentials: &testCredentials,
+	}
+
+	ms, err := c.SubscribeToMatchesForProducts(context.Background(), []ProductID{ProductIDBtcUsd})
+	if !assert.NoError(t, err, "Subscribe err") {
+		return
+	}
+
+	if assert.Len(t, conn.WriteJSONCalls(), 1, "WriteJSON calls") {
+		actual, ok := conn.WriteJSONCalls()[0].V.(SubscribeRequest)
+		if assert.True(t, ok, "Subscribe request type") {
+			assert.Equal(t, testCredentials.Key, actual.Key, "Key")
+			assert.Equal(t, testCredentials.Passphrase, actual.Passphrase, "Passphrase")
+			assert.NotEmpty(t, actual.Signature, "Signature")
+			assert.NotEmpty(t, actual.Timestamp, "Timestamp")
+		}
+	}
+
+	assert.NoError(t, ms.Close(context.Background()), "Close")
+}
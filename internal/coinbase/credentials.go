@@ -0,0 +1,165 @@
+package coinbase
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Environment variables read by CredentialsFromEnv.
+const (
+	EnvAPIKey        = "COINBASE_API_KEY"
+	EnvAPISecret     = "COINBASE_API_SECRET"
+	EnvAPIPassphrase = "COINBASE_API_PASSPHRASE"
+)
+
+// The request signed to authenticate a websocket subscription.
+const (
+	subscribeSignMethod      = http.MethodGet
+	subscribeSignRequestPath = "/users/self/verify"
+)
+
+// Credentials are a Coinbase Exchange API key, used to sign requests. They are
+// redacted when formatted (e.g. logged).
+type Credentials struct {
+	Key string
+
+	// Secret is the base64 encoded API secret.
+	Secret string
+
+	Passphrase string
+}
+
+// CredentialsFromEnv reads Credentials from the environment variables EnvAPIKey,
+// EnvAPISecret and EnvAPIPassphrase using getenv. If none are set, nil is returned.
+func CredentialsFromEnv(getenv func(string) string) (*Credentials, error) {
+	c := &Credentials{
+		Key:        getenv(EnvAPIKey),
+		Secret:     getenv(EnvAPISecret),
+		Passphrase: getenv(EnvAPIPassphrase),
+	}
+
+	if *c == (Credentials{}) {
+		return nil, nil
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// LoadCredentialsFile reads Credentials from the JSON file at path, which should
+// contain a "key", "secret" and "passphrase".
+func LoadCredentialsFile(path string) (*Credentials, error) {
+	f, err := os.Open(path) //nolint:gosec // The path is provided by the operator.
+	if err != nil {
+		return nil, fmt.Errorf("open credentials file: %w", err)
+	}
+	defer f.Close()
+
+	return readCredentials(f)
+}
+
+// readCredentials reads JSON encoded Credentials from r.
+func readCredentials(r io.Reader) (*Credentials, error) {
+	var file struct {
+		Key        string `json:"key"`
+		Secret     string `json:"secret"`
+		Passphrase string `json:"passphrase"`
+	}
+
+	// Errors from the decoder don't include the values being decoded.
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("decode credentials: %w", err)
+	}
+
+	c := &Credentials{Key: file.Key, Secret: file.Secret, Passphrase: file.Passphrase}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Validate returns an error if any of the Credentials are missing or malformed.
+func (c Credentials) Validate() error {
+	switch {
+	case c.Key == "":
+		return fmt.Errorf("credentials: key is required")
+	case c.Secret == "":
+		return fmt.Errorf("credentials: secret is required")
+	case c.Passphrase == "":
+		return fmt.Errorf("credentials: passphrase is required")
+	}
+
+	if _, err := base64.StdEncoding.DecodeString(c.Secret); err != nil {
+		return fmt.Errorf("credentials: secret is not base64 encoded")
+	}
+
+	return nil
+}
+
+// Sign returns the base64 encoded HMAC-SHA256 [signature] of a request, keyed by
+// the secret.
+//
+// [signature]: https://docs.cloud.coinbase.com/exchange/docs/rest-auth#signing-a-message
+func (c Credentials) Sign(timestamp, method, requestPath, body string) (string, error) {
+	secret, err := base64.StdEncoding.DecodeString(c.Secret)
+	if err != nil {
+		return "", fmt.Errorf("decode secret: secret is not base64 encoded")
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(timestamp + method + requestPath + body))
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// SignSubscribeRequest returns a copy of request [authenticated] with the
+// Credentials as of now.
+//
+// [authenticated]: https://docs.cloud.coinbase.com/exchange/docs/websocket-auth
+func (c Credentials) SignSubscribeRequest(request SubscribeRequest, now time.Time) (SubscribeRequest, error) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	signature, err := c.Sign(timestamp, subscribeSignMethod, subscribeSignRequestPath, "")
+	if err != nil {
+		return SubscribeRequest{}, fmt.Errorf("sign subscribe request: %w", err)
+	}
+
+	request.Signature = signature
+	request.Key = c.Key
+	request.Passphrase = c.Passphrase
+	request.Timestamp = timestamp
+
+	return request, nil
+}
+
+// String redacts the Credentials.
+func (c Credentials) String() string {
+	return "coinbase.Credentials{REDACTED}"
+}
+
+// GoString redacts the Credentials.
+func (c Credentials) GoString() string {
+	return c.String()
+}
+
+// Format redacts the Credentials, regardless of verb.
+func (c Credentials) Format(f fmt.State, _ rune) {
+	_, _ = io.WriteString(f, c.String())
+}
+
+// MarshalJSON redacts the Credentials.
+func (c Credentials) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
@@ -0,0 +1,215 @@
+package coinbase
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testCredentials has a secret of base64("TestSecret").
+var testCredentials = Credentials{Key: "TestKey", Secret: "VGVzdFNlY3JldA==", Passphrase: "TestPassphrase"}
+
+func TestCredentialsSign(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name              string
+		with              Credentials
+		giveTimestamp     string
+		giveMethod        string
+		giveRequestPath   string
+		giveBody          string
+		expectedSignature string
+		expectedErr       string
+	}{
+		{
+			name:              "subscribe",
+			with:              testCredentials,
+			giveTimestamp:     "1700000000",
+			giveMethod:        "GET",
+			giveRequestPath:   "/users/self/verify",
+			expectedSignature: "kaOih33CZmins7V27KrODARuUQ6tfrflyYWbwIHS3/4=",
+		},
+		{
+			name:              "with_body",
+			with:              testCredentials,
+			giveTimestamp:     "1700000000",
+			giveMethod:        "POST",
+			giveRequestPath:   "/orders",
+			giveBody:          `{"size":"1"}`,
+			expectedSignature: "ERMvLGNkmhWaV8Or5TpDS01QPQdANHrPhL+PtS63Te4=",
+		},
+		{
+			name:        "secret_not_base64",
+			with:        Credentials{Key: "TestKey", Secret: "Test!Secret", Passphrase: "TestPassphrase"},
+			expectedErr: "decode secret: secret is not base64 encoded",
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := tc.with.Sign(tc.giveTimestamp, tc.giveMethod, tc.giveRequestPath, tc.giveBody)
+
+			if tc.expectedErr != "" {
+				assert.EqualError(t, actualErr, tc.expectedErr, "Actual err")
+				assert.NotContains(t, actualErr.Error(), tc.with.Secret, "Actual err")
+
+				return
+			}
+
+			assert.NoError(t, actualErr, "Actual err")
+			assert.Equal(t, tc.expectedSignature, actual, "Actual")
+		})
+	}
+}
+
+func TestCredentialsSignSubscribeRequest(t *testing.T) {
+	t.Parallel()
+
+	request := SubscribeRequest{
+		Type:     "subscribe",
+		Channels: []SubscribeChannelRequest{{Name: ChannelNameMatches, ProductIDs: []ProductID{ProductIDBtcUsd}}},
+	}
+
+	actual, err := testCredentials.SignSubscribeRequest(request, time.Unix(1700000000, 0))
+	if !assert.NoError(t, err, "Sign err") {
+		return
+	}
+
+	actualJSON, err := json.Marshal(actual)
+	if !assert.NoError(t, err, "Marshal err") {
+		return
+	}
+
+	assert.JSONEq(
+		t,
+		`{
+			"type": "subscribe",
+			"product_ids": null,
+			"channels": [{"name": "matches", "product_ids": ["BTC-USD"]}],
+			"signature": "kaOih33CZmins7V27KrODARuUQ6tfrflyYWbwIHS3/4=",
+			"key": "TestKey",
+			"passphrase": "TestPassphrase",
+			"timestamp": "1700000000"
+		}`,
+		string(actualJSON),
+		"Actual",
+	)
+	assert.Empty(t, request.Signature, "Original request is unchanged")
+}
+
+func TestCredentialsFromEnv(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name        string
+		giveEnv     map[string]string
+		expected    *Credentials
+		expectedErr string
+	}{
+		{
+			name:    "unset",
+			giveEnv: map[string]string{},
+		},
+		{
+			name: "set",
+			giveEnv: map[string]string{
+				EnvAPIKey:        testCredentials.Key,
+				EnvAPISecret:     testCredentials.Secret,
+				EnvAPIPassphrase: testCredentials.Passphrase,
+			},
+			expected: &testCredentials,
+		},
+		{
+			name: "missing_passphrase",
+			giveEnv: map[string]string{
+				EnvAPIKey:    testCredentials.Key,
+				EnvAPISecret: testCredentials.Secret,
+			},
+			expectedErr: "credentials: passphrase is required",
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := CredentialsFromEnv(func(key string) string { return tc.giveEnv[key] })
+
+			if tc.expectedErr != "" {
+				assert.EqualError(t, actualErr, tc.expectedErr, "Actual err")
+			} else {
+				assert.NoError(t, actualErr, "Actual err")
+			}
+
+			assert.Equal(t, tc.expected, actual, "Actual")
+		})
+	}
+}
+
+func TestLoadCredentialsFile(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		// Setup
+		path := filepath.Join(t.TempDir(), "credentials.json")
+		content := `{"key":"TestKey","secret":"VGVzdFNlY3JldA==","passphrase":"TestPassphrase"}`
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write credentials file: %v", err)
+		}
+
+		// Do
+		actual, actualErr := LoadCredentialsFile(path)
+
+		// Assert
+		assert.NoError(t, actualErr, "Actual err")
+		assert.Equal(t, &testCredentials, actual, "Actual")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		actual, actualErr := readCredentials(strings.NewReader(`{"key":"TestKey","secret":"Test!Secret","passphrase":"TestPassphrase"}`))
+
+		assert.Nil(t, actual, "Actual")
+		assert.EqualError(t, actualErr, "credentials: secret is not base64 encoded", "Actual err")
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		t.Parallel()
+
+		_, actualErr := LoadCredentialsFile(filepath.Join(t.TempDir(), "missing.json"))
+
+		assert.ErrorIs(t, actualErr, os.ErrNotExist, "Actual err")
+	})
+}
+
+func TestCredentialsRedacted(t *testing.T) {
+	t.Parallel()
+
+	marshalled, err := json.Marshal(struct{ Credentials *Credentials }{&testCredentials})
+	assert.NoError(t, err, "Marshal err")
+
+	for _, actual := range []string{
+		fmt.Sprint(testCredentials),
+		fmt.Sprintf("%v %+v %#v %s %q %x %d", testCredentials, testCredentials, testCredentials, testCredentials, testCredentials, testCredentials, testCredentials),
+		fmt.Sprintf("%+v %#v", &testCredentials, &testCredentials),
+		fmt.Sprintf("%+v", Client{Credentials: &testCredentials}),
+		string(marshalled),
+	} {
+		assert.NotContains(t, actual, testCredentials.Key, "Key")
+		assert.NotContains(t, actual, testCredentials.Secret, "Secret")
+		assert.NotContains(t, actual, testCredentials.Passphrase, "Passphrase")
+	}
+}
@@ -0,0 +1,156 @@
+package coinbase
+
+import "encoding/json"
+
+// Message is a message decoded from the websocket feed (see Decoders). It is one
+// of *Match, *Ticker, *Heartbeat, *Status, *Level2Snapshot, *Level2Update,
+// *FullMessage, *ErrorMessage, *SubscriptionsMessage or *RawMessage, unless other
+// types are registered.
+type Message interface {
+	// MessageType returns the type of the message.
+	MessageType() MessageType
+}
+
+// MessageType returns the type of the message.
+func (m *Match) MessageType() MessageType { return m.Type }
+
+// MessageType returns the type of the message.
+func (h *Heartbeat) MessageType() MessageType { return h.Type }
+
+// MessageType returns the type of the message.
+func (t *Ticker) MessageType() MessageType { return t.Type }
+
+// MessageType returns the type of the message.
+func (l *Level2Snapshot) MessageType() MessageType { return l.Type }
+
+// MessageType returns the type of the message.
+func (l *Level2Update) MessageType() MessageType { return l.Type }
+
+// MessageType returns the type of the message.
+func (f *FullMessage) MessageType() MessageType { return f.Type }
+
+// MessageType returns the type of the message.
+func (s *Status) MessageType() MessageType { return s.Type }
+
+// MessageType returns the type of the message.
+func (e *ErrorMessage) MessageType() MessageType { return e.Type }
+
+// MessageType returns the type of the message.
+func (s *SubscriptionsMessage) MessageType() MessageType { return s.Type }
+
+// RawMessage is a message of a type with no registered decoder, left undecoded.
+type RawMessage struct {
+	Type MessageType
+	JSON json.RawMessage
+}
+
+// MessageType returns the type of the message.
+func (r *RawMessage) MessageType() MessageType { return r.Type }
+
+// DecodeFunc decodes a message of a single type.
+type DecodeFunc func(message json.RawMessage) (Message, error)
+
+// DecoderFor returns a DecodeFunc that unmarshals a message into the value returned
+// by newMessage, which should be a pointer.
+func DecoderFor(newMessage func() Message) DecodeFunc {
+	return func(message json.RawMessage) (Message, error) {
+		decoded := newMessage()
+		if err := json.Unmarshal(message, decoded); err != nil {
+			return nil, err
+		}
+
+		return decoded, nil
+	}
+}
+
+// Decoders decodes messages read from the websocket feed, by first reading their
+// type and then dispatching to the DecodeFunc registered for it. Messages of a type
+// without one are decoded as a *RawMessage. Registering is not safe for concurrent
+// use with decoding.
+//
+// The zero-value of this type is not usable, see NewDecoders.
+type Decoders struct {
+	decoders map[MessageType]DecodeFunc
+}
+
+// NewDecoders creates new Decoders, with a DecodeFunc registered for each of the
+// message types "match", "last_match", "ticker", "heartbeat", "status", "snapshot",
+// "l2update", "error" and "subscriptions".
+func NewDecoders() *Decoders {
+	d := &Decoders{decoders: make(map[MessageType]DecodeFunc)}
+
+	d.Register(MessageTypeMatch, DecoderFor(func() Message { return &Match{} }))
+	d.Register(MessageTypeLastMatch, DecoderFor(func() Message { return &Match{} }))
+	d.Register(MessageTypeTicker, DecoderFor(func() Message { return &Ticker{} }))
+	d.Register(MessageTypeHeartbeat, DecoderFor(func() Message { return &Heartbeat{} }))
+	d.Register(MessageTypeStatus, DecoderFor(func() Message { return &Status{} }))
+	d.Register(MessageTypeSnapshot, DecoderFor(func() Message { return &Level2Snapshot{} }))
+	d.Register(MessageTypeL2Update, DecoderFor(func() Message { return &Level2Update{} }))
+	d.Register(MessageTypeError, DecoderFor(func() Message { return &ErrorMessage{} }))
+	d.Register(MessageTypeSubscriptions, DecoderFor(func() Message { return &SubscriptionsMessage{} }))
+
+	return d
+}
+
+// Register registers decode for messageType, replacing any already registered.
+func (d *Decoders) Register(messageType MessageType, decode DecodeFunc) {
+	d.decoders[messageType] = decode
+}
+
+// Decode decodes message.
+func (d *Decoders) Decode(message json.RawMessage) (Message, error) {
+	header, err := decodeHeader(message)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.decode(header, message)
+}
+
+// decode decodes message, whose header has already been decoded.
+func (d *Decoders) decode(header messageHeader, message json.RawMessage) (Message, error) {
+	decode, ok := d.decoders[header.Type]
+	if !ok {
+		return &RawMessage{Type: header.Type, JSON: message}, nil
+	}
+
+	decoded, err := decode(message)
+	if err != nil {
+		return nil, &DecodeError{MessageType: header.Type, Err: err}
+	}
+
+	return decoded, nil
+}
+
+// decodeHeader decodes the header of message.
+func decodeHeader(message json.RawMessage) (messageHeader, error) {
+	header := messageHeader{}
+	if err := json.Unmarshal(message, &header); err != nil {
+		return messageHeader{}, &DecodeError{Err: err}
+	}
+
+	return header, nil
+}
+
+// defaultDecoders are used by subscriptions that don't need their own. They must
+// not be registered with.
+var defaultDecoders = NewDecoders()
+
+// fullDecoders decode the messages of the Full Channel (including "match") as a
+// *FullMessage. They must not be registered with.
+var fullDecoders = func() *Decoders {
+	d := NewDecoders()
+
+	for _, messageType := range []MessageType{
+		MessageTypeReceived,
+		MessageTypeOpen,
+		MessageTypeDone,
+		MessageTypeMatch,
+		MessageTypeChange,
+		MessageTypeActivate,
+	} {
+		d.Register(messageType, DecoderFor(func() Message { return &FullMessage{} }))
+	}
+
+	return d
+}()
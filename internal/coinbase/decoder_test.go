@@ -0,0 +1,131 @@
+package coinbase
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+func TestDecodersDecode(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name        string
+		with        func() *Decoders
+		giveMessage string
+		expected    Message
+		expectedErr error
+	}{
+		{
+			name:        "match",
+			giveMessage: `{"type": "match", "trade_id": 1, "product_id": "BTC-USD", "size": "1.5", "price": "2"}`,
+			expected:    &Match{Type: MessageTypeMatch, TradeID: 1, ProductID: ProductIDBtcUsd, Size: "1.5", Price: "2"},
+		},
+		{
+			name:        "last_match",
+			giveMessage: `{"type": "last_match", "trade_id": 1}`,
+			expected:    &Match{Type: MessageTypeLastMatch, TradeID: 1},
+		},
+		{
+			name:        "ticker",
+			giveMessage: `{"type": "ticker", "product_id": "BTC-USD", "price": "10"}`,
+			expected:    &Ticker{Type: MessageTypeTicker, ProductID: ProductIDBtcUsd, Price: decimal.MustParse("10")},
+		},
+		{
+			name:        "heartbeat",
+			giveMessage: `{"type": "heartbeat", "product_id": "BTC-USD", "sequence": 3, "time": "2022-10-20T01:00:00Z"}`,
+			expected:    &Heartbeat{Type: MessageTypeHeartbeat, ProductID: ProductIDBtcUsd, Sequence: 3, Time: time.Date(2022, 10, 20, 1, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:        "status",
+			giveMessage: `{"type": "status", "products": [{"id": "BTC-USD", "quote_increment": "0.01", "status": "online"}], "currencies": [{"id": "USD", "min_size": "0.01"}]}`,
+			expected: &Status{
+				Type:       MessageTypeStatus,
+				Products:   []StatusProduct{{ID: ProductIDBtcUsd, QuoteIncrement: "0.01", Status: "online"}},
+				Currencies: []StatusCurrency{{ID: "USD", MinSize: "0.01"}},
+			},
+		},
+		{
+			name:        "l2update",
+			giveMessage: `{"type": "l2update", "product_id": "BTC-USD", "changes": [["buy", "10", "0.5"]]}`,
+			expected: &Level2Update{
+				Type:      MessageTypeL2Update,
+				ProductID: ProductIDBtcUsd,
+				Changes:   []Level2Change{{Side: SideBuy, PriceLevel: PriceLevel{Price: decimal.MustParse("10"), Size: decimal.MustParse("0.5")}}},
+			},
+		},
+		{
+			name:        "error",
+			giveMessage: `{"type": "error", "message": "TestABC", "reason": "TestDEF"}`,
+			expected:    &ErrorMessage{Type: MessageTypeError, Message: "TestABC", Reason: "TestDEF"},
+		},
+		{
+			name:        "subscriptions",
+			giveMessage: `{"type": "subscriptions", "channels": [{"name": "matches", "product_ids": ["BTC-USD"]}]}`,
+			expected: &SubscriptionsMessage{
+				Type:     MessageTypeSubscriptions,
+				Channels: []SubscribeChannelRequest{{Name: ChannelNameMatches, ProductIDs: []ProductID{ProductIDBtcUsd}}},
+			},
+		},
+		{
+			name:        "unrecognised",
+			giveMessage: `{"type": "TestABC", "a": 1}`,
+			expected:    &RawMessage{Type: "TestABC", JSON: json.RawMessage(`{"type": "TestABC", "a": 1}`)},
+		},
+		{
+			name: "registered",
+			with: func() *Decoders {
+				d := NewDecoders()
+				d.Register("TestABC", func(message json.RawMessage) (Message, error) {
+					return &RawMessage{Type: "TestDEF"}, nil
+				})
+
+				return d
+			},
+			giveMessage: `{"type": "TestABC"}`,
+			expected:    &RawMessage{Type: "TestDEF"},
+		},
+		{
+			name:        "invalid_header",
+			giveMessage: `[]`,
+			expectedErr: fmt.Errorf("decode message: json: cannot unmarshal array into Go value of type coinbase.messageHeader"),
+		},
+		{
+			name:        "invalid_message",
+			giveMessage: `{"type": "ticker", "price": "abc"}`,
+			expectedErr: fmt.Errorf("decode ticker: parse decimal \"abc\": invalid syntax"),
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+
+			decoders := NewDecoders()
+			if tc.with != nil {
+				decoders = tc.with()
+			}
+
+			// Do
+
+			actual, err := decoders.Decode(json.RawMessage(tc.giveMessage))
+
+			// Assert
+
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error(), "Err")
+				return
+			}
+
+			assert.NoError(t, err, "Err")
+			assert.Equal(t, tc.expected, actual, "Actual")
+		})
+	}
+}
@@ -2,7 +2,10 @@ package coinbase
 
 import (
 	"context"
+	"crypto/tls"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -17,6 +20,16 @@ type Conn interface {
 	// WriteJSON should write the JSON encoding of v as a message.
 	WriteJSON(v interface{}) error
 
+	// SetReadDeadline should set the read deadline on the underlying network connection.
+	// After a read has timed out, the connection is considered failed. A zero value
+	// for t means reads will not time out. It should be safe to call concurrently
+	// with ReadJSON.
+	SetReadDeadline(t time.Time) error
+
+	// WriteControl should write a control message (e.g. a close message, see
+	// websocket.CloseMessage) with the given deadline.
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+
 	// Close should close the underlying network connection without sending or waiting
 	// for a close message.
 	Close() error
@@ -28,6 +41,27 @@ type Dialer interface {
 	DialContext(ctx context.Context, urlStr string, requestHeader http.Header) (Conn, *http.Response, error)
 }
 
+// DialerOptions tune the default (gorilla) Dialer.
+type DialerOptions struct {
+	// TLSClientConfig is the TLS configuration to use. If nil, the default
+	// configuration is used.
+	TLSClientConfig *tls.Config
+
+	// Proxy returns the proxy to use for a request. If nil, http.ProxyFromEnvironment
+	// is used.
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// HandshakeTimeout is the duration allowed for the handshake to complete. If 0,
+	// 45 seconds is used.
+	HandshakeTimeout time.Duration
+
+	// EnableCompression negotiates permessage-deflate compression with the server.
+	EnableCompression bool
+}
+
+// defaultHandshakeTimeout matches websocket.DefaultDialer.
+const defaultHandshakeTimeout = 45 * time.Second
+
 type gorillaWebsocketDialler struct {
 	d *websocket.Dialer
 }
@@ -38,9 +72,20 @@ func (g *gorillaWebsocketDialler) DialContext(ctx context.Context, urlStr string
 	return g.d.DialContext(ctx, urlStr, requestHeader)
 }
 
-func newGorillaWebsocketDialler(d *websocket.Dialer) Dialer {
-	if d == nil {
-		d = websocket.DefaultDialer
+func newGorillaWebsocketDialler(options DialerOptions) Dialer {
+	d := &websocket.Dialer{
+		TLSClientConfig:   options.TLSClientConfig,
+		Proxy:             options.Proxy,
+		HandshakeTimeout:  options.HandshakeTimeout,
+		EnableCompression: options.EnableCompression,
+	}
+
+	if d.Proxy == nil {
+		d.Proxy = http.ProxyFromEnvironment
+	}
+
+	if d.HandshakeTimeout == 0 {
+		d.HandshakeTimeout = defaultHandshakeTimeout
 	}
 
 	return &gorillaWebsocketDialler{d: d}
@@ -0,0 +1,47 @@
+package coinbase
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGorillaWebsocketDialler(t *testing.T) {
+	t.Parallel()
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+
+		actual := newGorillaWebsocketDialler(DialerOptions{}).(*gorillaWebsocketDialler)
+
+		assert.NotNil(t, actual.d.Proxy, "Proxy")
+		assert.Equal(t, defaultHandshakeTimeout, actual.d.HandshakeTimeout, "Handshake timeout")
+		assert.Nil(t, actual.d.TLSClientConfig, "TLS client config")
+		assert.False(t, actual.d.EnableCompression, "Enable compression")
+	})
+
+	t.Run("options", func(t *testing.T) {
+		t.Parallel()
+
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS13}
+		proxyURL := &url.URL{Scheme: "http", Host: "proxy:3128"}
+
+		actual := newGorillaWebsocketDialler(DialerOptions{
+			TLSClientConfig:   tlsConfig,
+			Proxy:             http.ProxyURL(proxyURL),
+			HandshakeTimeout:  time.Second,
+			EnableCompression: true,
+		}).(*gorillaWebsocketDialler)
+
+		actualProxyURL, err := actual.d.Proxy(&http.Request{})
+		assert.NoError(t, err, "Proxy err")
+		assert.Equal(t, proxyURL, actualProxyURL, "Proxy URL")
+		assert.Same(t, tlsConfig, actual.d.TLSClientConfig, "TLS client config")
+		assert.Equal(t, time.Second, actual.d.HandshakeTimeout, "Handshake timeout")
+		assert.True(t, actual.d.EnableCompression, "Enable compression")
+	})
+}
@@ -0,0 +1,46 @@
+package coinbase
+
+import "fmt"
+
+// Environment is a named Coinbase Exchange environment.
+type Environment string
+
+const (
+	// EnvironmentProduction is the public production environment.
+	EnvironmentProduction Environment = "production"
+
+	// EnvironmentSandbox is the public sandbox environment, for testing.
+	EnvironmentSandbox Environment = "sandbox"
+
+	// EnvironmentDirect is the lower latency production direct feed. It requires
+	// authenticated subscriptions.
+	EnvironmentDirect Environment = "direct"
+)
+
+// FeedURL returns the websocket feed URL of the Environment. An empty Environment
+// is treated as EnvironmentProduction.
+func (e Environment) FeedURL() (string, error) {
+	switch e {
+	case "", EnvironmentProduction:
+		return "wss://ws-feed.exchange.coinbase.com", nil
+	case EnvironmentSandbox:
+		return "wss://ws-feed-public.sandbox.exchange.coinbase.com", nil
+	case EnvironmentDirect:
+		return "wss://ws-direct.exchange.coinbase.com", nil
+	default:
+		return "", fmt.Errorf("unknown environment %q", string(e))
+	}
+}
+
+// RESTURL returns the REST API base URL of the Environment (see RESTClient.BaseURL).
+// An empty Environment is treated as EnvironmentProduction.
+func (e Environment) RESTURL() (string, error) {
+	switch e {
+	case "", EnvironmentProduction, EnvironmentDirect:
+		return "https://api.exchange.coinbase.com", nil
+	case EnvironmentSandbox:
+		return "https://api-public.sandbox.exchange.coinbase.com", nil
+	default:
+		return "", fmt.Errorf("unknown environment %q", string(e))
+	}
+}
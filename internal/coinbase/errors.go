@@ -0,0 +1,156 @@
+package coinbase
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// The errors of this package are each classified as retryable (i.e. the operation
+// may succeed if retried, typically over a new connection) or fatal. Use IsRetryable
+// to classify an error, or errors.As to inspect one.
+var (
+	_ error = (*RemoteError)(nil)
+	_ error = (*ProtocolError)(nil)
+	_ error = (*DecodeError)(nil)
+	_ error = (*TransportError)(nil)
+	_ error = (*SubscriptionRejectedError)(nil)
+)
+
+// IsRetryable returns true if err (or the first error it wraps that is classified)
+// is retryable. Errors that aren't classified (e.g. invalid arguments) are fatal.
+func IsRetryable(err error) bool {
+	var classified interface{ Retryable() bool }
+	if !errors.As(err, &classified) {
+		return false
+	}
+
+	return classified.Retryable()
+}
+
+// RemoteError is an "error" message received from Coinbase.
+type RemoteError struct {
+	Message string
+	Reason  string
+}
+
+func (e *RemoteError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("error message received: %q", e.Message)
+	}
+
+	return fmt.Sprintf("error message received: %q (reason: %q)", e.Message, e.Reason)
+}
+
+// Retryable returns false, Coinbase sends error messages for requests it won't
+// accept.
+func (e *RemoteError) Retryable() bool {
+	return false
+}
+
+// ProtocolError is a message received that the subscription didn't expect, either
+// of a type it doesn't read or for a product it isn't subscribed to.
+type ProtocolError struct {
+	MessageType MessageType
+
+	// If set, the message was for this product which isn't subscribed to.
+	UnsubscribedProductID ProductID
+}
+
+func (e *ProtocolError) Error() string {
+	if e.UnsubscribedProductID != ProductIDUnknown {
+		return fmt.Sprintf("received %s for unsubscribed product %q", e.MessageType, e.UnsubscribedProductID)
+	}
+
+	return fmt.Sprintf("received unexpected message with type %q", e.MessageType)
+}
+
+// Retryable returns false, the same messages would be received again.
+func (e *ProtocolError) Retryable() bool {
+	return false
+}
+
+// DecodeError is a message received that couldn't be decoded.
+type DecodeError struct {
+	// The type of the message, or MessageTypeUnknown if not even that could be
+	// decoded.
+	MessageType MessageType
+
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	if e.MessageType == MessageTypeUnknown {
+		return fmt.Sprintf("decode message: %v", e.Err)
+	}
+
+	return fmt.Sprintf("decode %s: %v", e.MessageType, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable returns false, the same messages would be received again.
+func (e *DecodeError) Retryable() bool {
+	return false
+}
+
+// TransportError is a failure to open, read from or write to a connection, or of a
+// REST request.
+type TransportError struct {
+	// The operation that failed, e.g. "dialing coinbase" or "read match".
+	Op string
+
+	// The HTTP status code the server responded to the websocket handshake or REST
+	// request with, if it failed. 0 otherwise.
+	StatusCode int
+
+	// If the REST API responded 429 (Too Many Requests), how long to wait before
+	// retrying.
+	RetryAfter time.Duration
+
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Op, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable returns true, unless the server rejected the websocket handshake or REST
+// request with a client error status (e.g. 401 or 404) other than 429 (Too Many
+// Requests).
+func (e *TransportError) Retryable() bool {
+	return e.StatusCode < http.StatusBadRequest ||
+		e.StatusCode >= http.StatusInternalServerError ||
+		e.StatusCode == http.StatusTooManyRequests
+}
+
+// SubscriptionRejectedError is returned when Coinbase responds to a subscribe or
+// unsubscribe request with an "error" message.
+type SubscriptionRejectedError struct {
+	// The type of the request rejected, i.e. "subscribe" or "unsubscribe".
+	RequestType string
+
+	ProductIDs []ProductID
+
+	Err *RemoteError
+}
+
+func (e *SubscriptionRejectedError) Error() string {
+	return fmt.Sprintf("%s request for product %s rejected: %v", e.RequestType, joinProductIDs(e.ProductIDs), e.Err)
+}
+
+func (e *SubscriptionRejectedError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable returns false, the same request would be rejected again.
+func (e *SubscriptionRejectedError) Retryable() bool {
+	return false
+}
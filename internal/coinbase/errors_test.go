@@ -0,0 +1,99 @@
+package coinbase
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryable(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		giveErr  error
+		expected bool
+	}{
+		{
+			name:    "unclassified",
+			giveErr: fmt.Errorf("TestABC"),
+		},
+		{
+			name:    "remote",
+			giveErr: &RemoteError{Message: "TestABC"},
+		},
+		{
+			name:    "protocol",
+			giveErr: &ProtocolError{MessageType: MessageTypeMatch},
+		},
+		{
+			name:    "decode",
+			giveErr: &DecodeError{MessageType: MessageTypeMatch, Err: fmt.Errorf("TestABC")},
+		},
+		{
+			name:     "transport",
+			giveErr:  &TransportError{Op: "read match", Err: fmt.Errorf("TestABC")},
+			expected: true,
+		},
+		{
+			name:     "transport_server_error",
+			giveErr:  &TransportError{Op: "dialing coinbase", StatusCode: http.StatusBadGateway, Err: fmt.Errorf("TestABC")},
+			expected: true,
+		},
+		{
+			name:     "transport_too_many_requests",
+			giveErr:  &TransportError{Op: "dialing coinbase", StatusCode: http.StatusTooManyRequests, Err: fmt.Errorf("TestABC")},
+			expected: true,
+		},
+		{
+			name:    "transport_client_error",
+			giveErr: &TransportError{Op: "dialing coinbase", StatusCode: http.StatusUnauthorized, Err: fmt.Errorf("TestABC")},
+		},
+		{
+			name:    "subscription_rejected",
+			giveErr: &SubscriptionRejectedError{RequestType: "subscribe", ProductIDs: []ProductID{ProductIDBtcUsd}, Err: &RemoteError{Message: "TestABC"}},
+		},
+		{
+			name:     "wrapped",
+			giveErr:  fmt.Errorf("TestABC: %w", &TransportError{Op: "read match", Err: fmt.Errorf("TestDEF")}),
+			expected: true,
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Do
+
+			actual := IsRetryable(tc.giveErr)
+
+			// Assert
+
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestErrorsAs(t *testing.T) {
+	t.Parallel()
+
+	err := fmt.Errorf("TestABC: %w", &SubscriptionRejectedError{
+		RequestType: "subscribe",
+		ProductIDs:  []ProductID{ProductIDBtcUsd, ProductIDEthUsd},
+		Err:         &RemoteError{Message: "Failed to subscribe", Reason: "TestDEF"},
+	})
+
+	assert.EqualError(t, err, `TestABC: subscribe request for product BTC-USD,ETH-USD rejected: error message received: "Failed to subscribe" (reason: "TestDEF")`, "Err")
+
+	var remoteErr *RemoteError
+	if assert.True(t, errors.As(err, &remoteErr), "As RemoteError") {
+		assert.Equal(t, "TestDEF", remoteErr.Reason, "Reason")
+	}
+
+	var transportErr *TransportError
+	assert.False(t, errors.As(err, &transportErr), "As TransportError")
+}
@@ -0,0 +1,930 @@
+package coinbase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// feed manages a connection subscribed to Coinbase channels (plus the Heartbeat
+// Channel) for a set of products. It runs the read loop, reconnects, watches liveness
+// and closes. Subscriptions built on a feed decode the messages read (see handle)
+// and push the results, of type R, to per-product read channels.
+type feed[R response] struct {
+	// Describes what the feed reads, for errors.
+	kind feedKind
+
+	// The products subscribed to, in the order requested, and their read channels
+	// (pushed to by the connection read loop). Products can be added and removed
+	// (see subscribe and unsubscribe), so both are guarded by productsMu. A read
+	// channel is kept once created (even when its product is unsubscribed) until
+	// the read loop exits.
+	productIDs []ProductID
+	reads      map[ProductID]*readChannel[R]
+	productsMu sync.RWMutex
+
+	// Serialises writes of subscribe and unsubscribe requests, including when
+	// resubscribing after reconnecting, with changes to the products.
+	writeMu sync.Mutex
+
+	// Serialises subscribe and unsubscribe, which each wait for confirmation.
+	updateMu sync.Mutex
+
+	// Tracks the channels the server has confirmed are subscribed to.
+	subscribed *subscribedTracker
+
+	// The current connection, guarded by connMu as it is replaced on reconnect.
+	conn   Conn
+	connMu sync.Mutex
+
+	// Optional behaviours of the feed.
+	options subscriptionOptions
+
+	// Handles each message decoded, other than those of type "heartbeat",
+	// "subscriptions" and "error". Returns false if the message isn't handled.
+	handle func(header messageHeader, message Message) bool
+
+	// Create a read value from an error or a notification.
+	errResponse          func(err error) R
+	notificationResponse func(notification Notification) R
+
+	// Tracks when each product was last seen. If nil, there is no liveness watchdog.
+	liveness *livenessTracker
+
+	// A channel to signal background goroutines (the liveness watchdog and any
+	// started with goBackground) to stop, closed when the read loop exits.
+	stopBackground chan struct{}
+
+	// Goroutines started with goBackground, which the read loop waits for before
+	// closing the read channels.
+	background sync.WaitGroup
+
+	// Closed when Close is first invoked, signalling the read loop to stop.
+	closing   chan struct{}
+	closeOnce sync.Once
+
+	// Closed when the first Close invocation has returned, after which closeErr
+	// is set.
+	closed   chan struct{}
+	closeErr error
+
+	// Closed when the read loop has exited, after which err is set.
+	done chan struct{}
+	err  error
+}
+
+// feedKind describes what a feed reads.
+type feedKind struct {
+	// The channels subscribed to (the Heartbeat Channel is added to these).
+	channels []ChannelName
+
+	// Used in subscribe errors, e.g. "Matches".
+	channelDescription string
+
+	// Used in read errors, e.g. "match".
+	messageDescription string
+
+	// Decode the messages read.
+	decoders *Decoders
+
+	// If true, every message must be read (e.g. to maintain a book), so lossy
+	// overflow policies are rejected.
+	lossless bool
+}
+
+// subscriptionOptions are optional behaviours of a subscription, typically derived
+// from the Client that created it.
+type subscriptionOptions struct {
+	// Used to reconnect when the connection fails. If nil, there is no reconnect.
+	reconnector *reconnector
+
+	// Used to fetch trades missing from the subscription. If nil, there is no
+	// backfill.
+	backfill *RESTClient
+
+	// If a product sees neither a heartbeat nor a message within this duration, it
+	// is reported as stale. If 0, there is no liveness watchdog.
+	livenessTimeout time.Duration
+
+	// Used to sign subscribe requests. If nil, requests aren't signed.
+	credentials *Credentials
+
+	// The size of each read channel and what to do when one is full.
+	backpressure Backpressure
+}
+
+// sign returns request signed with the credentials (if any).
+func (o subscriptionOptions) sign(request SubscribeRequest) (SubscribeRequest, error) {
+	if o.credentials == nil {
+		return request, nil
+	}
+
+	return o.credentials.SignSubscribeRequest(request, time.Now())
+}
+
+// newFeed creates a new feed. It will first subscribe to the kind's channels and
+// the Heartbeat Channel for all productIDs over conn, in a single request. If this
+// is successful, the feed should be started once its handle function is set.
+func newFeed[R response](conn Conn, kind feedKind, options subscriptionOptions, productIDs []ProductID, errResponse func(error) R, notificationResponse func(Notification) R) (*feed[R], error) {
+	if len(productIDs) == 0 {
+		return nil, fmt.Errorf("productID is required")
+	}
+
+	if err := options.backpressure.validate(kind); err != nil {
+		return nil, err
+	}
+
+	reads := make(map[ProductID]*readChannel[R], len(productIDs))
+	for _, productID := range productIDs {
+		if productID == ProductIDUnknown {
+			return nil, fmt.Errorf("productID is required")
+		}
+
+		if _, ok := reads[productID]; ok {
+			return nil, fmt.Errorf("productID %s is duplicated", productID)
+		}
+
+		reads[productID] = newReadChannel[R](options.backpressure.bufferSize(), options.backpressure.Overflow)
+	}
+
+	signed, err := options.sign(kind.request("subscribe", productIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.WriteJSON(signed); err != nil {
+		return nil, &TransportError{
+			Op:  fmt.Sprintf("subscribing to %s channel for product %s", kind.channelDescription, joinProductIDs(productIDs)),
+			Err: err,
+		}
+	}
+
+	f := &feed[R]{
+		kind:                 kind,
+		productIDs:           append([]ProductID(nil), productIDs...),
+		reads:                reads,
+		subscribed:           newSubscribedTracker(),
+		conn:                 conn,
+		options:              options,
+		errResponse:          errResponse,
+		notificationResponse: notificationResponse,
+		stopBackground:       make(chan struct{}),
+		closing:              make(chan struct{}),
+		closed:               make(chan struct{}),
+		done:                 make(chan struct{}),
+	}
+
+	if options.livenessTimeout > 0 {
+		f.liveness = newLivenessTracker(options.livenessTimeout, time.Now(), productIDs)
+	}
+
+	return f, nil
+}
+
+// request returns a request of type requestType (i.e. "subscribe" or "unsubscribe")
+// for the kind's channels and the Heartbeat Channel, for productIDs. Channels that
+// aren't per product (e.g. "status") are subscribed to without productIDs, and are
+// left out of unsubscribe requests as the remaining products still need them.
+func (k feedKind) request(requestType string, productIDs []ProductID) SubscribeRequest {
+	request := SubscribeRequest{Type: requestType}
+	for _, channel := range k.channels {
+		switch {
+		case channel.isPerProduct():
+			request.Channels = append(request.Channels, SubscribeChannelRequest{Name: channel, ProductIDs: productIDs})
+		case requestType == "subscribe":
+			request.Channels = append(request.Channels, SubscribeChannelRequest{Name: channel})
+		}
+	}
+
+	request.Channels = append(request.Channels, SubscribeChannelRequest{Name: ChannelNameHeartbeat, ProductIDs: productIDs})
+
+	return request
+}
+
+// productID returns the first product subscribed to.
+func (f *feed[R]) productID() ProductID {
+	f.productsMu.RLock()
+	defer f.productsMu.RUnlock()
+
+	return f.productIDs[0]
+}
+
+// copyProductIDs returns a copy of the products subscribed to.
+func (f *feed[R]) copyProductIDs() []ProductID {
+	f.productsMu.RLock()
+	defer f.productsMu.RUnlock()
+
+	productIDs := make([]ProductID, len(f.productIDs))
+	copy(productIDs, f.productIDs)
+
+	return productIDs
+}
+
+// subscribeRequest returns the request that subscribes to all products currently
+// subscribed to (e.g. to resubscribe after reconnecting).
+func (f *feed[R]) subscribeRequest() SubscribeRequest {
+	return f.kind.request("subscribe", f.copyProductIDs())
+}
+
+// readProduct returns the read channel for productID, or nil if the feed is not
+// (and never was) for productID.
+func (f *feed[R]) readProduct(productID ProductID) <-chan R {
+	read := f.read(productID)
+	if read == nil {
+		return nil
+	}
+
+	return read.ch
+}
+
+// droppedCount returns the number of responses for productID dropped by the
+// overflow policy.
+func (f *feed[R]) droppedCount(productID ProductID) uint64 {
+	read := f.read(productID)
+	if read == nil {
+		return 0
+	}
+
+	return read.droppedCount()
+}
+
+// read returns the read channel for productID, or nil if there is none.
+func (f *feed[R]) read(productID ProductID) *readChannel[R] {
+	f.productsMu.RLock()
+	defer f.productsMu.RUnlock()
+
+	return f.reads[productID]
+}
+
+// isSubscribed returns true if productID is currently subscribed to. f.productsMu
+// must be held.
+func (f *feed[R]) isSubscribed(productID ProductID) bool {
+	for _, subscribedProductID := range f.productIDs {
+		if subscribedProductID == productID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// subscribedReads returns the read channels of the products currently subscribed
+// to.
+func (f *feed[R]) subscribedReads() []*readChannel[R] {
+	f.productsMu.RLock()
+	defer f.productsMu.RUnlock()
+
+	reads := make([]*readChannel[R], len(f.productIDs))
+	for a, productID := range f.productIDs {
+		reads[a] = f.reads[productID]
+	}
+
+	return reads
+}
+
+// subscribedChannels returns the channels the server has confirmed are subscribed
+// to.
+func (f *feed[R]) subscribedChannels() []SubscribeChannelRequest {
+	return f.subscribed.subscribed()
+}
+
+// subscribe subscribes to the kind's channels (and the Heartbeat Channel) for
+// productIDs, in addition to those already subscribed to, over the current
+// connection. It then waits until the server confirms it, an error message is
+// received (in which case productIDs are removed again) or ctx is done. Each
+// product's read channel is available (see readProduct) before the request is
+// sent.
+func (f *feed[R]) subscribe(ctx context.Context, productIDs []ProductID) error {
+	f.updateMu.Lock()
+	defer f.updateMu.Unlock()
+
+	if err := f.validateUpdate(productIDs, true); err != nil {
+		return err
+	}
+
+	signed, err := f.options.sign(f.kind.request("subscribe", productIDs))
+	if err != nil {
+		return err
+	}
+
+	errs := f.subscribed.errs()
+
+	if err := f.writeUpdate(signed, productIDs, f.addProducts); err != nil {
+		f.removeProducts(productIDs)
+
+		return &TransportError{
+			Op:  fmt.Sprintf("subscribing to %s channel for product %s", f.kind.channelDescription, joinProductIDs(productIDs)),
+			Err: err,
+		}
+	}
+
+	rejected, err := f.subscribed.wait(ctx, f.done, errs, func(subscribed []SubscribeChannelRequest) bool {
+		return channelsInclude(subscribed, f.kind.channels, productIDs)
+	})
+	if rejected != nil {
+		f.removeProducts(productIDs)
+
+		return &SubscriptionRejectedError{RequestType: "subscribe", ProductIDs: productIDs, Err: rejected}
+	}
+
+	return err
+}
+
+// unsubscribe unsubscribes from the kind's channels (and the Heartbeat Channel)
+// for productIDs over the current connection. It then waits until the server
+// confirms it, an error message is received or ctx is done. Nothing more is read
+// for productIDs once this is invoked, but their read channels are only closed
+// once the read loop exits.
+func (f *feed[R]) unsubscribe(ctx context.Context, productIDs []ProductID) error {
+	f.updateMu.Lock()
+	defer f.updateMu.Unlock()
+
+	if err := f.validateUpdate(productIDs, false); err != nil {
+		return err
+	}
+
+	signed, err := f.options.sign(f.kind.request("unsubscribe", productIDs))
+	if err != nil {
+		return err
+	}
+
+	errs := f.subscribed.errs()
+
+	// Not restored on error, if the connection has failed the products won't be
+	// resubscribed to.
+	if err := f.writeUpdate(signed, productIDs, f.removeProducts); err != nil {
+		return &TransportError{
+			Op:  fmt.Sprintf("unsubscribing from %s channel for product %s", f.kind.channelDescription, joinProductIDs(productIDs)),
+			Err: err,
+		}
+	}
+
+	rejected, err := f.subscribed.wait(ctx, f.done, errs, func(subscribed []SubscribeChannelRequest) bool {
+		return channelsExclude(subscribed, f.kind.channels, productIDs)
+	})
+	if rejected != nil {
+		return &SubscriptionRejectedError{RequestType: "unsubscribe", ProductIDs: productIDs, Err: rejected}
+	}
+
+	return err
+}
+
+// validateUpdate validates productIDs, to be subscribed to if subscribe is true
+// or otherwise unsubscribed from.
+func (f *feed[R]) validateUpdate(productIDs []ProductID, subscribe bool) error {
+	if len(productIDs) == 0 {
+		return fmt.Errorf("productID is required")
+	}
+
+	f.productsMu.RLock()
+	defer f.productsMu.RUnlock()
+
+	seen := make(map[ProductID]bool, len(productIDs))
+	for _, productID := range productIDs {
+		switch {
+		case productID == ProductIDUnknown:
+			return fmt.Errorf("productID is required")
+		case seen[productID]:
+			return fmt.Errorf("productID %s is duplicated", productID)
+		case subscribe && f.isSubscribed(productID):
+			return fmt.Errorf("productID %s is already subscribed", productID)
+		case !subscribe && !f.isSubscribed(productID):
+			return fmt.Errorf("productID %s is not subscribed", productID)
+		}
+
+		seen[productID] = true
+	}
+
+	if !subscribe && len(productIDs) == len(f.productIDs) {
+		return fmt.Errorf("unsubscribing from every product is not supported, use Close")
+	}
+
+	return nil
+}
+
+// writeUpdate applies the change to productIDs (with update) and writes request
+// (for them) over the current connection. If the feed is between reconnect attempts,
+// nothing is written as resubscribing will include the change.
+func (f *feed[R]) writeUpdate(request SubscribeRequest, productIDs []ProductID, update func([]ProductID)) error {
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+
+	update(productIDs)
+
+	conn := f.currentConn()
+	if conn == nil {
+		return nil
+	}
+
+	return conn.WriteJSON(request)
+}
+
+// addProducts adds productIDs to those subscribed to, creating their read channels
+// if they don't already have them.
+func (f *feed[R]) addProducts(productIDs []ProductID) {
+	f.productsMu.Lock()
+	defer f.productsMu.Unlock()
+
+	for _, productID := range productIDs {
+		if _, ok := f.reads[productID]; !ok {
+			f.reads[productID] = newReadChannel[R](f.options.backpressure.bufferSize(), f.options.backpressure.Overflow)
+		}
+	}
+
+	f.productIDs = append(f.productIDs, productIDs...)
+
+	if f.liveness != nil {
+		f.liveness.add(productIDs, time.Now())
+	}
+}
+
+// removeProducts removes productIDs from those subscribed to. Their read channels
+// are kept.
+func (f *feed[R]) removeProducts(productIDs []ProductID) {
+	f.productsMu.Lock()
+	defer f.productsMu.Unlock()
+
+	remaining := make([]ProductID, 0, len(f.productIDs))
+
+	for _, productID := range f.productIDs {
+		removed := false
+		for _, removedProductID := range productIDs {
+			if productID == removedProductID {
+				removed = true
+				break
+			}
+		}
+
+		if !removed {
+			remaining = append(remaining, productID)
+		}
+	}
+
+	f.productIDs = remaining
+
+	if f.liveness != nil {
+		f.liveness.remove(productIDs)
+	}
+}
+
+// doneErr returns the error that stopped the read loop, or nil if it hasn't
+// stopped or was stopped by Close.
+func (f *feed[R]) doneErr() error {
+	select {
+	case <-f.done:
+		return f.err
+	default:
+		return nil
+	}
+}
+
+// closeHandshakeTimeout bounds the close handshake when Close is invoked with a
+// ctx that has no deadline.
+const closeHandshakeTimeout = time.Second * 5
+
+// Close closes the feed. See subscription.Close.
+func (f *feed[R]) Close(ctx context.Context) error {
+	first := false
+	f.closeOnce.Do(func() { first = true })
+
+	if !first {
+		select {
+		case <-f.closed:
+			return f.closeErr
+		case <-ctx.Done():
+			return fmt.Errorf("wait for close: %w", ctx.Err())
+		}
+	}
+
+	close(f.closing)
+
+	f.closeErr = f.close(ctx)
+
+	close(f.closed)
+
+	return f.closeErr
+}
+
+// close performs the close handshake and then closes the connection. The read loop
+// may be reading throughout, so only WriteControl, SetReadDeadline and Close are
+// invoked on the connection here (they're safe to invoke concurrently with a read).
+func (f *feed[R]) close(ctx context.Context) error {
+	deadline := time.Now().Add(closeHandshakeTimeout)
+
+	// ctx bounds the handshake if it has an earlier deadline, otherwise the timeout
+	// does.
+	var handshakeTimeout <-chan time.Time
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	} else {
+		handshakeTimer := time.NewTimer(time.Until(deadline))
+		defer handshakeTimer.Stop()
+
+		handshakeTimeout = handshakeTimer.C
+	}
+
+	// The connection is nil if the read loop is between reconnect attempts.
+	if conn := f.currentConn(); conn != nil {
+		// Both are best effort. If the server responds with its own close message
+		// the read loop will exit, otherwise the read deadline will see it exit.
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), deadline)
+		_ = conn.SetReadDeadline(deadline)
+	}
+
+	select {
+	case <-f.done:
+		return f.closeConn()
+	case <-ctx.Done():
+		readLoopErr := fmt.Errorf("wait for read loop to exit: %w", ctx.Err())
+
+		// If the read loop is still blocked, this will unblock it.
+		if err := f.closeConn(); err != nil {
+			return err
+		}
+
+		return readLoopErr
+	case <-handshakeTimeout:
+	}
+
+	// The read should have timed out, but if it hasn't (e.g. the deadline couldn't be
+	// set) this interrupts it.
+	if err := f.closeConn(); err != nil {
+		return err
+	}
+
+	select {
+	case <-f.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("wait for read loop to exit: %w", ctx.Err())
+	}
+}
+
+// closeConn closes the current connection, if any.
+func (f *feed[R]) closeConn() error {
+	if conn := f.currentConn(); conn != nil {
+		if err := conn.Close(); err != nil {
+			return fmt.Errorf("close connection: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// start starts the read loop (and liveness watchdog).
+func (f *feed[R]) start() {
+	watchdogDone := f.startWatchdog()
+
+	go func() {
+		defer func() {
+			close(f.stopBackground)
+			<-watchdogDone
+			f.background.Wait()
+
+			f.productsMu.RLock()
+			for _, read := range f.reads {
+				read.close()
+			}
+			f.productsMu.RUnlock()
+
+			close(f.done)
+		}()
+
+		for {
+			if f.isClosing() {
+				return
+			}
+
+			var message json.RawMessage
+			if err := f.currentConn().ReadJSON(&message); err != nil {
+				err = &TransportError{Op: "read " + f.kind.messageDescription, Err: err}
+
+				if f.isClosing() {
+					// Expected, as the read has been interrupted by Close.
+					return
+				}
+
+				if f.options.reconnector != nil {
+					if f.reconnect(err) {
+						if f.liveness != nil {
+							f.liveness.reset(time.Now())
+						}
+
+						continue
+					}
+
+					if f.isClosing() {
+						return
+					}
+				}
+
+				f.pushErrToAll(err)
+
+				// There's no recovery here, if we keep invoking conn.ReadJSON it
+				// will eventually panic.
+				f.err = err
+
+				return
+			}
+
+			f.handleMessage(message)
+		}
+	}()
+}
+
+// handleMessage decodes message and handles it (or passes it on to the handle
+// function).
+func (f *feed[R]) handleMessage(message json.RawMessage) {
+	header, err := decodeHeader(message)
+	if err != nil {
+		f.pushErrToAll(err)
+		return
+	}
+
+	decoded, err := f.kind.decoders.decode(header, message)
+	if err != nil {
+		f.pushErrToAll(err)
+		return
+	}
+
+	switch decoded := decoded.(type) {
+	case *Heartbeat:
+		f.seen(decoded.ProductID)
+	case *ErrorMessage:
+		err := &RemoteError{Message: decoded.Message, Reason: decoded.Reason}
+
+		f.subscribed.fail(err)
+		f.pushErrToAll(err)
+	case *SubscriptionsMessage:
+		f.subscribed.confirm(decoded.Channels)
+	default:
+		if !f.handle(header, decoded) {
+			f.pushErrToAll(&ProtocolError{MessageType: header.Type})
+		}
+	}
+}
+
+// readFor returns the read channel for productID, for a message of messageType.
+// If the feed has never been for productID, an error is pushed to all products
+// and false is returned. If productID has since been unsubscribed from, false is
+// returned (messages may be received until the server has processed the unsubscribe
+// request).
+func (f *feed[R]) readFor(messageType MessageType, productID ProductID) (*readChannel[R], bool) {
+	f.productsMu.RLock()
+	read, ok := f.reads[productID]
+	subscribed := f.isSubscribed(productID)
+	f.productsMu.RUnlock()
+
+	if !ok {
+		f.pushErrToAll(&ProtocolError{MessageType: messageType, UnsubscribedProductID: productID})
+		return nil, false
+	}
+
+	return read, subscribed
+}
+
+// seen records productID as seen now, for the liveness watchdog (if any).
+func (f *feed[R]) seen(productID ProductID) {
+	if f.liveness != nil {
+		f.liveness.seen(productID, time.Now())
+	}
+}
+
+// startWatchdog starts the liveness watchdog (if the feed has one), which periodically
+// checks for stale products until signalled to stop. The returned channel is closed
+// when the watchdog has stopped.
+func (f *feed[R]) startWatchdog() <-chan struct{} {
+	done := make(chan struct{})
+
+	if f.liveness == nil {
+		close(done)
+
+		return done
+	}
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(f.liveness.checkInterval())
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-f.stopBackground:
+				return
+			case now := <-ticker.C:
+				notifications := f.liveness.checkStale(now)
+
+				for _, notification := range notifications {
+					f.pushUntil(f.read(notification.ProductID), f.notificationResponse(notification), f.stopBackground)
+
+					select {
+					case <-f.stopBackground:
+						return
+					default:
+					}
+				}
+
+				if len(notifications) > 0 && f.options.reconnector != nil {
+					// Unblocks the read loop, which will then reconnect.
+					if conn := f.currentConn(); conn != nil {
+						_ = conn.Close()
+					}
+				}
+			}
+		}
+	}()
+
+	return done
+}
+
+// reconnect will attempt to redial and resubscribe, as per the reconnect policy,
+// after the connection failed with cause. Returns true if successful, or false
+// if attempts are exhausted, an attempt fails with an error that isn't retryable
+// (see IsRetryable) or the feed is closing.
+func (f *feed[R]) reconnect(cause error) bool {
+	ctx, ctxCancel := f.cancelOnClosing(context.Background())
+	defer ctxCancel()
+
+	// The connection has failed (or been closed by the liveness watchdog) so an
+	// error closing it isn't of interest.
+	f.connMu.Lock()
+	_ = f.conn.Close()
+	f.conn = nil
+	f.connMu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		backoff := f.options.reconnector.policy.backoff(attempt, f.options.reconnector.random())
+		f.pushNotificationToAll(&ReconnectingNotification{Attempt: attempt, Backoff: backoff, Err: cause})
+
+		backoffTimer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			backoffTimer.Stop()
+
+			return false
+		case <-backoffTimer.C:
+		}
+
+		err := f.redial(ctx)
+		if err == nil {
+			f.pushNotificationToAll(&ReconnectedNotification{Attempts: attempt})
+
+			return true
+		}
+
+		cause = err
+
+		if f.isClosing() {
+			return false
+		}
+
+		if f.options.reconnector.policy.exhausted(attempt) || !IsRetryable(err) {
+			f.pushNotificationToAll(&ReconnectGaveUpNotification{Attempts: attempt, Err: cause})
+
+			return false
+		}
+	}
+}
+
+// redial dials a new connection and resubscribes. If successful, the new connection
+// replaces the current one.
+func (f *feed[R]) redial(ctx context.Context) error {
+	conn, err := f.options.reconnector.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Held until the new connection replaces the current one, so products aren't
+	// changed without it being subscribed to them.
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+
+	request := f.subscribeRequest()
+
+	// Sign each time, the signature is only valid for a short time.
+	signed, err := f.options.sign(request)
+	if err != nil {
+		_ = conn.Close()
+
+		return err
+	}
+
+	if err := conn.WriteJSON(signed); err != nil {
+		_ = conn.Close()
+
+		return &TransportError{
+			Op:  fmt.Sprintf("resubscribing to %s channel for product %s", f.kind.channelDescription, joinProductIDs(f.copyProductIDs())),
+			Err: err,
+		}
+	}
+
+	f.connMu.Lock()
+	defer f.connMu.Unlock()
+
+	if f.isClosing() {
+		_ = conn.Close()
+
+		return fmt.Errorf("subscription closed while reconnecting")
+	}
+
+	f.conn = conn
+
+	return nil
+}
+
+// currentConn returns the current connection, which is nil between reconnect
+// attempts.
+func (f *feed[R]) currentConn() Conn {
+	f.connMu.Lock()
+	defer f.connMu.Unlock()
+
+	return f.conn
+}
+
+// cancelOnClosing returns a copy of ctx that is also cancelled when Close is
+// invoked.
+func (f *feed[R]) cancelOnClosing(ctx context.Context) (context.Context, context.CancelFunc) {
+	return cancelOn(ctx, f.closing)
+}
+
+// cancelOn returns a copy of ctx that is also cancelled when stop is closed.
+func cancelOn(ctx context.Context, stop <-chan struct{}) (context.Context, context.CancelFunc) {
+	ctx, ctxCancel := context.WithCancel(ctx)
+
+	go func() {
+		select {
+		case <-stop:
+			ctxCancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, ctxCancel
+}
+
+// goBackground runs fn in a goroutine that the read loop waits for before closing
+// the read channels. fn must return promptly once stopBackground is closed (e.g.
+// pushing with pushUntil). It must only be invoked from the read loop.
+func (f *feed[R]) goBackground(fn func()) {
+	f.background.Add(1)
+
+	go func() {
+		defer f.background.Done()
+
+		fn()
+	}()
+}
+
+// isClosing returns true if Close has been invoked.
+func (f *feed[R]) isClosing() bool {
+	select {
+	case <-f.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// push pushes response to read. If read's buffer is full, the overflow policy
+// applies. When blocking, this blocks until there's room or Close is invoked.
+// Returns false if response wasn't pushed.
+func (f *feed[R]) push(read *readChannel[R], response R) bool {
+	return f.pushUntil(read, response, f.closing)
+}
+
+// pushUntil pushes response to read as push does, but blocks until stop is closed
+// rather than until Close is invoked.
+func (f *feed[R]) pushUntil(read *readChannel[R], response R, stop <-chan struct{}) bool {
+	return read.push(response, f.options.backpressure.Overflow, stop)
+}
+
+// pushErrToAll pushes err to the read channel of every product subscribed to.
+func (f *feed[R]) pushErrToAll(err error) {
+	for _, read := range f.subscribedReads() {
+		f.push(read, f.errResponse(err))
+	}
+}
+
+// pushNotificationToAll pushes notification to the read channel of every product
+// subscribed to.
+func (f *feed[R]) pushNotificationToAll(notification Notification) {
+	for _, read := range f.subscribedReads() {
+		f.push(read, f.notificationResponse(notification))
+	}
+}
+
+// joinProductIDs formats productIDs as a comma separated list.
+func joinProductIDs(productIDs []ProductID) string {
+	s := make([]string, len(productIDs))
+	for a, productID := range productIDs {
+		s[a] = string(productID)
+	}
+
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,322 @@
+package coinbase
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRawMessageConn creates a Conn mock that reads each message pushed to the
+// returned channel. Writing a close message (i.e. the close handshake) unblocks
+// ReadJSON with an error.
+func newRawMessageConn(t *testing.T) (*ConnMock, chan<- string) {
+	t.Helper()
+
+	readIn := make(chan string)
+
+	closeWritten := make(chan struct{})
+	closeWrittenOnce := sync.Once{}
+
+	return &ConnMock{
+		WriteJSONFunc: func(v interface{}) error { return nil },
+		ReadJSONFunc: func(v interface{}) error {
+			select {
+			case <-closeWritten:
+				return fmt.Errorf("close message written")
+			case message := <-readIn:
+				*v.(*json.RawMessage) = json.RawMessage(message)
+
+				return nil
+			}
+		},
+		WriteControlFunc: func(messageType int, data []byte, deadline time.Time) error {
+			if messageType == websocket.CloseMessage {
+				closeWrittenOnce.Do(func() { close(closeWritten) })
+			}
+
+			return nil
+		},
+		SetReadDeadlineFunc: func(t time.Time) error { return nil },
+		CloseFunc:           func() error { return nil },
+	}, readIn
+}
+
+func TestFeedPushOverflow(t *testing.T) {
+	t.Parallel()
+
+	match := func(tradeID int64) *MatchResponse { return &MatchResponse{Match: Match{TradeID: tradeID}} }
+	notification := &MatchResponse{Notification: &ReconnectingNotification{}}
+	errResponse := &MatchResponse{Err: fmt.Errorf("TestABC")}
+
+	for _, tc := range []struct {
+		name            string
+		withOverflow    OverflowPolicy
+		give            []*MatchResponse
+		expected        []*MatchResponse
+		expectedDropped uint64
+	}{
+		{
+			name:            "drop_oldest_keeps_notifications",
+			withOverflow:    OverflowDropOldest,
+			give:            []*MatchResponse{match(1), notification, match(2), match(3), match(4)},
+			expected:        []*MatchResponse{notification, match(3), match(4)},
+			expectedDropped: 2,
+		},
+		{
+			name:            "drop_oldest_full_of_notifications",
+			withOverflow:    OverflowDropOldest,
+			give:            []*MatchResponse{notification, errResponse, notification, match(1)},
+			expected:        []*MatchResponse{notification, errResponse, notification},
+			expectedDropped: 0,
+		},
+		{
+			name:            "drop_newest",
+			withOverflow:    OverflowDropNewest,
+			give:            []*MatchResponse{match(1), notification, match(2), match(3)},
+			expected:        []*MatchResponse{match(1), notification, match(2)},
+			expectedDropped: 1,
+		},
+		{
+			name:            "drop_newest_keeps_errors",
+			withOverflow:    OverflowDropNewest,
+			give:            []*MatchResponse{match(1), match(2), match(3), errResponse},
+			expected:        []*MatchResponse{match(1), match(2), match(3)},
+			expectedDropped: 0,
+		},
+		{
+			name:            "coalesce_keeps_notifications_and_errors",
+			withOverflow:    OverflowCoalesce,
+			give:            []*MatchResponse{match(1), notification, match(2), match(3), errResponse, match(4)},
+			expected:        []*MatchResponse{notification, errResponse, match(4)},
+			expectedDropped: 3,
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+
+			f, err := newFeed(
+				&ConnMock{WriteJSONFunc: func(v interface{}) error { return nil }},
+				feedKind{channels: []ChannelName{ChannelNameMatches}, channelDescription: "Matches"},
+				subscriptionOptions{backpressure: Backpressure{BufferSize: 3, Overflow: tc.withOverflow}},
+				[]ProductID{ProductIDBtcUsd},
+				func(err error) *MatchResponse { return &MatchResponse{Err: err} },
+				func(notification Notification) *MatchResponse { return &MatchResponse{Notification: notification} },
+			)
+			require.NoError(t, err, "newFeed")
+
+			read := f.read(ProductIDBtcUsd)
+
+			// Stopped, so a push that would block (rather than discard) returns.
+			stop := make(chan struct{})
+			close(stop)
+
+			// Do
+
+			for _, response := range tc.give {
+				f.pushUntil(read, response, stop)
+			}
+
+			// Assert
+
+			read.mu.Lock()
+			actual := make([]*MatchResponse, len(read.queue))
+			read.mu.Unlock()
+
+			for a := range actual {
+				actual[a] = <-read.ch
+			}
+
+			assert.Equal(t, tc.expected, actual, "Read")
+			assert.Equal(t, tc.expectedDropped, f.droppedCount(ProductIDBtcUsd), "Dropped")
+		})
+	}
+}
+
+func TestFeedPushOverflowPerProduct(t *testing.T) {
+	t.Parallel()
+
+	for _, overflow := range []OverflowPolicy{OverflowDropOldest, OverflowDropNewest, OverflowCoalesce} {
+		overflow := overflow
+
+		t.Run(string(overflow), func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+
+			f, err := newFeed(
+				&ConnMock{WriteJSONFunc: func(v interface{}) error { return nil }},
+				feedKind{channels: []ChannelName{ChannelNameMatches}, channelDescription: "Matches"},
+				subscriptionOptions{backpressure: Backpressure{BufferSize: 2, Overflow: overflow}},
+				[]ProductID{ProductIDBtcUsd, ProductIDEthUsd},
+				func(err error) *MatchResponse { return &MatchResponse{Err: err} },
+				func(notification Notification) *MatchResponse { return &MatchResponse{Notification: notification} },
+			)
+			require.NoError(t, err, "newFeed")
+
+			stop := make(chan struct{})
+			t.Cleanup(func() { close(stop) })
+
+			// BTC-USD is never read, and is full of notifications that can't be
+			// discarded, so pushes of both messages and notifications to it block.
+			stalled := f.read(ProductIDBtcUsd)
+			for a := 0; a < 2; a++ {
+				f.pushUntil(stalled, &MatchResponse{Notification: &ReconnectingNotification{Attempt: a}}, stop)
+			}
+
+			for a := 0; a < 4; a++ {
+				a := a
+
+				go func() {
+					for b := 0; ; b++ {
+						select {
+						case <-stop:
+							return
+						default:
+						}
+
+						var response *MatchResponse
+						if a%2 == 0 {
+							response = &MatchResponse{Match: Match{TradeID: int64(b)}}
+						} else {
+							response = &MatchResponse{Notification: &ReconnectingNotification{Attempt: b}}
+						}
+
+						f.pushUntil(stalled, response, stop)
+					}
+				}()
+			}
+
+			// Do
+
+			read := f.read(ProductIDEthUsd)
+
+			var actual []int64
+
+			for a := int64(1); a <= 100; a++ {
+				f.pushUntil(read, &MatchResponse{Match: Match{TradeID: a}}, stop)
+
+				select {
+				case response := <-read.ch:
+					actual = append(actual, response.Match.TradeID)
+				case <-time.NewTimer(time.Millisecond * 300).C:
+					t.Fatalf("Timed out reading")
+				}
+			}
+
+			// Assert
+
+			assert.Len(t, actual, 100, "Read")
+			assert.Equal(t, uint64(0), f.droppedCount(ProductIDEthUsd), "Dropped")
+		})
+	}
+}
+
+func TestReadChannelPushConcurrentDrain(t *testing.T) {
+	t.Parallel()
+
+	const (
+		pushers = 8
+		pushes  = 2000
+	)
+
+	for _, overflow := range []OverflowPolicy{OverflowDropOldest, OverflowDropNewest, OverflowCoalesce} {
+		overflow := overflow
+
+		t.Run(string(overflow), func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+
+			read := newReadChannel[*MatchResponse](2, overflow)
+
+			stop := make(chan struct{})
+			t.Cleanup(func() { close(stop) })
+
+			// Each pusher pushes messages and notifications, numbered in order, so
+			// both drains (of messages) and blocked pushes (of notifications) run
+			// against each other.
+			pushing := sync.WaitGroup{}
+
+			for a := 0; a < pushers; a++ {
+				a := a
+
+				pushing.Add(1)
+
+				go func() {
+					defer pushing.Done()
+
+					for b := 0; b < pushes; b++ {
+						n := a*pushes + b
+
+						response := &MatchResponse{Match: Match{TradeID: int64(n)}}
+						if b%3 == 0 {
+							response = &MatchResponse{Notification: &ReconnectingNotification{Attempt: n}}
+						}
+
+						read.push(response, overflow, stop)
+					}
+				}()
+			}
+
+			// The rest are read before the channel closes.
+			go func() {
+				pushing.Wait()
+				read.close()
+			}()
+
+			// Do
+
+			var actual []*MatchResponse
+
+			for done := false; !done; {
+				select {
+				case response, ok := <-read.ch:
+					if !ok {
+						done = true
+
+						break
+					}
+
+					actual = append(actual, response)
+				case <-time.NewTimer(time.Second * 5).C:
+					t.Fatalf("Timed out reading")
+				}
+			}
+
+			// Assert
+
+			last := make([]int, pushers)
+			for a := range last {
+				last[a] = -1
+			}
+
+			notifications := 0
+
+			for _, response := range actual {
+				n := int(response.Match.TradeID)
+				if response.Notification != nil {
+					n = response.Notification.(*ReconnectingNotification).Attempt
+					notifications++
+				}
+
+				pusher := n / pushes
+				require.Greater(t, n, last[pusher], "Pusher %d pushed %d out of order", pusher, n)
+
+				last[pusher] = n
+			}
+
+			assert.Equal(t, pushers*((pushes+2)/3), notifications, "Notifications")
+			assert.Equal(t, uint64(pushers*pushes-len(actual)), read.droppedCount(), "Dropped")
+		})
+	}
+}
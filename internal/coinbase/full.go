@@ -0,0 +1,67 @@
+package coinbase
+
+// FullSubscription is created by a Client to manage a subscription to the [Full Channel].
+// Like a MatchesSubscription, it can multiplex many products over one connection,
+// with each message routed to a per-product read channel.
+//
+// [Full Channel]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#full-channel
+type FullSubscription struct {
+	subscription[*FullResponse]
+}
+
+// newFullSubscription creates a new FullSubscription. It will first subscribe to
+// the Full and Heartbeat Channels for all productIDs over conn, in a single request.
+// If this is successful, the read loop is started.
+func newFullSubscription(conn Conn, options subscriptionOptions, productIDs ...ProductID) (*FullSubscription, error) {
+	f, err := newFeed(
+		conn,
+		feedKind{
+			channels:           []ChannelName{ChannelNameFull},
+			channelDescription: "Full",
+			messageDescription: "full message",
+			decoders:           fullDecoders,
+			lossless:           true,
+		},
+		options,
+		productIDs,
+		func(err error) *FullResponse { return &FullResponse{Err: err} },
+		func(notification Notification) *FullResponse { return &FullResponse{Notification: notification} },
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FullSubscription{subscription: subscription[*FullResponse]{feed: f}}
+
+	f.handle = s.handleMessage
+	f.start()
+
+	return s, nil
+}
+
+// ReadProduct can be used to read from this subscription for productID. Messages
+// of type "received", "open", "done", "match", "change", "activate" or "error" are
+// read. Sequence values aren't checked, a book built from the messages should do
+// so (and resync from a snapshot on a gap). Errors, reconnect events and staleness
+// are read as they are for MatchesSubscription.ReadProduct. The channel is closed
+// when the subscription is done.
+//
+// Returns nil if the subscription is not for productID.
+func (s *FullSubscription) ReadProduct(productID ProductID) <-chan *FullResponse {
+	return s.feed.readProduct(productID)
+}
+
+// handleMessage pushes a full channel message to the relevant read channel.
+func (s *FullSubscription) handleMessage(_ messageHeader, message Message) bool {
+	fullMessage, ok := message.(*FullMessage)
+	if !ok {
+		return false
+	}
+
+	if read, ok := s.feed.readFor(fullMessage.Type, fullMessage.ProductID); ok {
+		s.feed.seen(fullMessage.ProductID)
+		s.feed.push(read, &FullResponse{Message: *fullMessage})
+	}
+
+	return true
+}
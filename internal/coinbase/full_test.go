@@ -0,0 +1,99 @@
+package coinbase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFullSubscriptionRead(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	conn, readIn := newRawMessageConn(t)
+
+	fs, err := newFullSubscription(conn, subscriptionOptions{}, ProductIDBtcUsd)
+	require.NoError(t, err, "newFullSubscription")
+
+	t.Cleanup(func() {
+		if err := fs.Close(context.Background()); err != nil {
+			t.Errorf("Failed to close test FullSubscription: %v", err)
+		}
+	})
+
+	// Do
+
+	readIn <- `{"type": "open", "product_id": "BTC-USD", "sequence": 10, "order_id": "TestABC", "price": "200.2", "remaining_size": "1.0", "side": "sell"}`
+	readIn <- `{"type": "match", "product_id": "BTC-USD", "sequence": 11, "trade_id": 5, "maker_order_id": "TestABC", "taker_order_id": "TestDEF", "size": "0.5", "price": "200.2", "side": "sell"}`
+	readIn <- `{"type": "l2update", "product_id": "BTC-USD"}`
+
+	// Assert
+
+	for _, expected := range []*FullResponse{
+		{Message: FullMessage{Type: MessageTypeOpen, ProductID: ProductIDBtcUsd, Sequence: 10, OrderID: "TestABC", Price: "200.2", RemainingSize: "1.0", Side: SideSell}},
+		{Message: FullMessage{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd, Sequence: 11, TradeID: 5, MakerOrderID: "TestABC", TakerOrderID: "TestDEF", Size: "0.5", Price: "200.2", Side: SideSell}},
+	} {
+		select {
+		case actual := <-fs.Read():
+			assert.Equal(t, expected, actual, "Actual")
+		case <-time.NewTimer(time.Millisecond * 300).C:
+			t.Fatalf("Timed out reading")
+		}
+	}
+
+	select {
+	case actual := <-fs.Read():
+		assert.EqualError(t, actual.Err, "received unexpected message with type \"l2update\"", "Actual err")
+	case <-time.NewTimer(time.Millisecond * 300).C:
+		t.Fatalf("Timed out reading")
+	}
+}
+
+func TestFullMessageToMatch(t *testing.T) {
+	t.Parallel()
+
+	matchTime := time.Date(2022, 10, 20, 1, 0, 0, 0, time.UTC)
+
+	actual := (&FullMessage{
+		Type:         MessageTypeMatch,
+		Time:         matchTime,
+		ProductID:    ProductIDBtcUsd,
+		Sequence:     11,
+		TradeID:      5,
+		MakerOrderID: "TestABC",
+		TakerOrderID: "TestDEF",
+		Size:         "0.5",
+		Price:        "200.2",
+		Side:         SideSell,
+	}).ToMatch()
+
+	assert.Equal(
+		t,
+		Match{
+			Type:         MessageTypeMatch,
+			TradeID:      5,
+			Sequence:     11,
+			MakerOrderID: "TestABC",
+			TakerOrderID: "TestDEF",
+			Time:         matchTime,
+			ProductID:    ProductIDBtcUsd,
+			Size:         "0.5",
+			Price:        "200.2",
+			Side:         SideSell,
+		},
+		actual,
+	)
+}
+
+func TestNewFullSubscriptionLossyOverflow(t *testing.T) {
+	t.Parallel()
+
+	actual, err := newFullSubscription(&ConnMock{}, subscriptionOptions{backpressure: Backpressure{Overflow: OverflowDropOldest}}, ProductIDBtcUsd)
+
+	assert.Nil(t, actual, "Actual")
+	assert.EqualError(t, err, `backpressure: overflow policy "drop_oldest" would discard Full messages, only "block" is supported`, "Err")
+}
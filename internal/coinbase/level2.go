@@ -0,0 +1,91 @@
+package coinbase
+
+import "fmt"
+
+// Level2Subscription is created by a Client to manage a subscription to the [Level2 Channel]
+// (or its batched equivalent). Like a MatchesSubscription, it can multiplex many
+// products over one connection, with each message routed to a per-product read channel.
+//
+// [Level2 Channel]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#level2-channel
+type Level2Subscription struct {
+	subscription[*Level2Response]
+}
+
+// newLevel2Subscription creates a new Level2Subscription. It will first subscribe
+// to channel (ChannelNameLevel2 or ChannelNameLevel2Batch) and the Heartbeat Channel
+// for all productIDs over conn, in a single request. If this is successful, the
+// read loop is started.
+func newLevel2Subscription(conn Conn, options subscriptionOptions, channel ChannelName, productIDs ...ProductID) (*Level2Subscription, error) {
+	if err := validateLevel2Channel(channel); err != nil {
+		return nil, err
+	}
+
+	f, err := newFeed(
+		conn,
+		feedKind{
+			channels:           []ChannelName{channel},
+			channelDescription: "Level2",
+			messageDescription: "level2",
+			decoders:           defaultDecoders,
+			lossless:           true,
+		},
+		options,
+		productIDs,
+		func(err error) *Level2Response { return &Level2Response{Err: err} },
+		func(notification Notification) *Level2Response { return &Level2Response{Notification: notification} },
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Level2Subscription{subscription: subscription[*Level2Response]{feed: f}}
+
+	f.handle = s.handleMessage
+	f.start()
+
+	return s, nil
+}
+
+// ReadProduct can be used to read from this subscription for productID. Only
+// messages of type "snapshot", "l2update" or "error" are read. A snapshot is read
+// first, and again after every reconnect, so a book built from updates should be
+// reset by each. Errors, reconnect events and staleness are read as they are for
+// MatchesSubscription.ReadProduct. The channel is closed when the subscription
+// is done.
+//
+// Returns nil if the subscription is not for productID.
+func (s *Level2Subscription) ReadProduct(productID ProductID) <-chan *Level2Response {
+	return s.feed.readProduct(productID)
+}
+
+// handleMessage pushes a message of type "snapshot" or "l2update" to the relevant
+// read channel.
+func (s *Level2Subscription) handleMessage(_ messageHeader, message Message) bool {
+	switch message := message.(type) {
+	case *Level2Snapshot:
+		if read, ok := s.feed.readFor(message.Type, message.ProductID); ok {
+			s.feed.seen(message.ProductID)
+			s.feed.push(read, &Level2Response{Snapshot: message})
+		}
+
+		return true
+	case *Level2Update:
+		if read, ok := s.feed.readFor(message.Type, message.ProductID); ok {
+			s.feed.seen(message.ProductID)
+			s.feed.push(read, &Level2Response{Update: message})
+		}
+
+		return true
+	default:
+		return false
+	}
+}
+
+// validateLevel2Channel returns an error if channel is not a level2 channel.
+func validateLevel2Channel(channel ChannelName) error {
+	if channel != ChannelNameLevel2 && channel != ChannelNameLevel2Batch {
+		return fmt.Errorf("channel %q is not a level2 channel", channel)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package coinbase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+func TestLevel2SubscriptionRead(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	conn, readIn := newRawMessageConn(t)
+
+	ls, err := newLevel2Subscription(conn, subscriptionOptions{}, ChannelNameLevel2Batch, ProductIDBtcUsd)
+	require.NoError(t, err, "newLevel2Subscription")
+
+	t.Cleanup(func() {
+		if err := ls.Close(context.Background()); err != nil {
+			t.Errorf("Failed to close test Level2Subscription: %v", err)
+		}
+	})
+
+	// Do
+
+	readIn <- `{"type": "snapshot", "product_id": "BTC-USD", "bids": [["1", "2"]], "asks": []}`
+	readIn <- `{"type": "l2update", "product_id": "BTC-USD", "changes": [["sell", "3", "4"]]}`
+	readIn <- `{"type": "l2update", "product_id": "BTC-USD", "changes": [["sell", "abc", "4"]]}`
+	readIn <- `{"type": "error", "message": "TestABC"}`
+
+	// Assert
+
+	if assert.Len(t, conn.WriteJSONCalls(), 1, "WriteJSON calls") {
+		assert.Equal(
+			t,
+			[]SubscribeChannelRequest{
+				{Name: ChannelNameLevel2Batch, ProductIDs: []ProductID{ProductIDBtcUsd}},
+				{Name: ChannelNameHeartbeat, ProductIDs: []ProductID{ProductIDBtcUsd}},
+			},
+			conn.WriteJSONCalls()[0].V.(SubscribeRequest).Channels,
+			"Subscribe request channels",
+		)
+	}
+
+	for _, expected := range []*Level2Response{
+		{Snapshot: &Level2Snapshot{
+			Type:      MessageTypeSnapshot,
+			ProductID: ProductIDBtcUsd,
+			Bids:      []PriceLevel{{Price: decimal.New(1, 0), Size: decimal.New(2, 0)}},
+			Asks:      []PriceLevel{},
+		}},
+		{Update: &Level2Update{
+			Type:      MessageTypeL2Update,
+			ProductID: ProductIDBtcUsd,
+			Changes:   []Level2Change{{Side: SideSell, PriceLevel: PriceLevel{Price: decimal.New(3, 0), Size: decimal.New(4, 0)}}},
+		}},
+		{Err: fmt.Errorf("decode l2update: %w", fmt.Errorf("level2 change: parse price: parse decimal \"abc\": invalid syntax"))},
+		{Err: &RemoteError{Message: "TestABC"}},
+	} {
+		select {
+		case actual := <-ls.Read():
+			if expected.Err != nil {
+				assert.EqualError(t, actual.Err, expected.Err.Error(), "Actual err")
+				continue
+			}
+
+			assert.Equal(t, expected, actual, "Actual")
+		case <-time.NewTimer(time.Millisecond * 300).C:
+			t.Fatalf("Timed out reading")
+		}
+	}
+}
+
+func TestNewLevel2SubscriptionInvalidChannel(t *testing.T) {
+	t.Parallel()
+
+	actual, err := newLevel2Subscription(&ConnMock{}, subscriptionOptions{}, ChannelNameMatches, ProductIDBtcUsd)
+
+	assert.Nil(t, actual, "Actual")
+	assert.EqualError(t, err, "channel \"matches\" is not a level2 channel", "Err")
+}
+
+func TestNewLevel2SubscriptionLossyOverflow(t *testing.T) {
+	t.Parallel()
+
+	actual, err := newLevel2Subscription(&ConnMock{}, subscriptionOptions{backpressure: Backpressure{Overflow: OverflowCoalesce}}, ChannelNameLevel2Batch, ProductIDBtcUsd)
+
+	assert.Nil(t, actual, "Actual")
+	assert.EqualError(t, err, `backpressure: overflow policy "coalesce" would discard Level2 messages, only "block" is supported`, "Err")
+}
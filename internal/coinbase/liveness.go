@@ -0,0 +1,120 @@
+package coinbase
+
+import (
+	"sync"
+	"time"
+)
+
+// minLivenessCheckInterval is the shortest interval the liveness watchdog checks
+// for stale products at, however short the timeout.
+const minLivenessCheckInterval = time.Millisecond
+
+// livenessTracker tracks when a message (e.g. a heartbeat or match) was last seen
+// per product, to determine if a product has gone stale. It is safe for concurrent
+// use.
+type livenessTracker struct {
+	timeout time.Duration
+
+	mu       sync.Mutex
+	lastSeen map[ProductID]time.Time
+
+	// Products reported as stale, that haven't been seen since.
+	stale map[ProductID]bool
+}
+
+// newLivenessTracker creates a new livenessTracker for productIDs, all considered
+// last seen at now. A product is stale if it isn't seen within timeout.
+func newLivenessTracker(timeout time.Duration, now time.Time, productIDs []ProductID) *livenessTracker {
+	l := &livenessTracker{
+		timeout:  timeout,
+		lastSeen: make(map[ProductID]time.Time, len(productIDs)),
+		stale:    make(map[ProductID]bool, len(productIDs)),
+	}
+
+	for _, productID := range productIDs {
+		l.lastSeen[productID] = now
+	}
+
+	return l
+}
+
+// checkInterval returns how often products should be checked for staleness, a
+// quarter of the timeout but no less than minLivenessCheckInterval.
+func (l *livenessTracker) checkInterval() time.Duration {
+	if interval := l.timeout / 4; interval > minLivenessCheckInterval {
+		return interval
+	}
+
+	return minLivenessCheckInterval
+}
+
+// seen records productID as seen at now.
+func (l *livenessTracker) seen(productID ProductID, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.lastSeen[productID]; !ok {
+		return
+	}
+
+	l.lastSeen[productID] = now
+	delete(l.stale, productID)
+}
+
+// add starts tracking productIDs, all considered last seen at now.
+func (l *livenessTracker) add(productIDs []ProductID, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, productID := range productIDs {
+		l.lastSeen[productID] = now
+		delete(l.stale, productID)
+	}
+}
+
+// remove stops tracking productIDs.
+func (l *livenessTracker) remove(productIDs []ProductID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, productID := range productIDs {
+		delete(l.lastSeen, productID)
+		delete(l.stale, productID)
+	}
+}
+
+// reset records all products as seen at now.
+func (l *livenessTracker) reset(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for productID := range l.lastSeen {
+		l.lastSeen[productID] = now
+		delete(l.stale, productID)
+	}
+}
+
+// checkStale returns a notification for each product that has become stale as
+// of now. A product is only reported once until it is seen again.
+func (l *livenessTracker) checkStale(now time.Time) []*StaleNotification {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var notifications []*StaleNotification
+
+	for productID, lastSeen := range l.lastSeen {
+		if l.stale[productID] || now.Sub(lastSeen) <= l.timeout {
+			continue
+		}
+
+		l.stale[productID] = true
+
+		notifications = append(notifications, &StaleNotification{
+			ProductID: productID,
+			LastSeen:  lastSeen,
+			Timeout:   l.timeout,
+		})
+	}
+
+	return notifications
+}
@@ -0,0 +1,124 @@
+package coinbase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLivenessTrackerCheckStale(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2022, 11, 7, 8, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name     string
+		with     func() *livenessTracker
+		giveNow  time.Time
+		expected []*StaleNotification
+	}{
+		{
+			name: "not_stale",
+			with: func() *livenessTracker {
+				return newLivenessTracker(time.Second, start, []ProductID{ProductIDBtcUsd})
+			},
+			giveNow: start.Add(time.Second),
+		},
+		{
+			name: "stale",
+			with: func() *livenessTracker {
+				return newLivenessTracker(time.Second, start, []ProductID{ProductIDBtcUsd})
+			},
+			giveNow:  start.Add(time.Second * 2),
+			expected: []*StaleNotification{{ProductID: ProductIDBtcUsd, LastSeen: start, Timeout: time.Second}},
+		},
+		{
+			name: "seen",
+			with: func() *livenessTracker {
+				l := newLivenessTracker(time.Second, start, []ProductID{ProductIDBtcUsd})
+				l.seen(ProductIDBtcUsd, start.Add(time.Second))
+
+				return l
+			},
+			giveNow: start.Add(time.Second * 2),
+		},
+		{
+			name: "seen_unknown_product",
+			with: func() *livenessTracker {
+				l := newLivenessTracker(time.Second, start, []ProductID{ProductIDBtcUsd})
+				l.seen(ProductIDEthUsd, start.Add(time.Second))
+
+				return l
+			},
+			giveNow:  start.Add(time.Second * 2),
+			expected: []*StaleNotification{{ProductID: ProductIDBtcUsd, LastSeen: start, Timeout: time.Second}},
+		},
+		{
+			name: "already_reported",
+			with: func() *livenessTracker {
+				l := newLivenessTracker(time.Second, start, []ProductID{ProductIDBtcUsd})
+				l.checkStale(start.Add(time.Second * 2))
+
+				return l
+			},
+			giveNow: start.Add(time.Second * 3),
+		},
+		{
+			name: "stale_again_after_seen",
+			with: func() *livenessTracker {
+				l := newLivenessTracker(time.Second, start, []ProductID{ProductIDBtcUsd})
+				l.checkStale(start.Add(time.Second * 2))
+				l.seen(ProductIDBtcUsd, start.Add(time.Second*3))
+
+				return l
+			},
+			giveNow:  start.Add(time.Second * 5),
+			expected: []*StaleNotification{{ProductID: ProductIDBtcUsd, LastSeen: start.Add(time.Second * 3), Timeout: time.Second}},
+		},
+		{
+			name: "reset",
+			with: func() *livenessTracker {
+				l := newLivenessTracker(time.Second, start, []ProductID{ProductIDBtcUsd})
+				l.checkStale(start.Add(time.Second * 2))
+				l.reset(start.Add(time.Second * 2))
+
+				return l
+			},
+			giveNow: start.Add(time.Second * 2),
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := tc.with().checkStale(tc.giveNow)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestLivenessTrackerCheckInterval(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name        string
+		giveTimeout time.Duration
+		expected    time.Duration
+	}{
+		{name: "quarter", giveTimeout: time.Second, expected: time.Millisecond * 250},
+		{name: "minimum", giveTimeout: time.Millisecond * 2, expected: minLivenessCheckInterval},
+		{name: "below_4ns", giveTimeout: time.Nanosecond * 3, expected: minLivenessCheckInterval},
+		{name: "1ns", giveTimeout: time.Nanosecond, expected: minLivenessCheckInterval},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := newLivenessTracker(tc.giveTimeout, time.Now(), nil).checkInterval()
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package coinbase
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MessageSubscription is created by a Client to manage a subscription to any
+// channels, reading every message decoded from them (see Decoders). Like a
+// MatchesSubscription, it can multiplex many products over one connection, with
+// each message routed to a per-product read channel.
+type MessageSubscription struct {
+	subscription[*MessageResponse]
+}
+
+// newMessageSubscription creates a new MessageSubscription. It will first subscribe
+// to channels and the Heartbeat Channel for all productIDs over conn, in a single
+// request, decoding messages with decoders. If this is successful, the read loop
+// is started.
+func newMessageSubscription(conn Conn, options subscriptionOptions, decoders *Decoders, channels []ChannelName, productIDs ...ProductID) (*MessageSubscription, error) {
+	channelDescriptions := make([]string, len(channels))
+	for a, channel := range channels {
+		channelDescriptions[a] = string(channel)
+	}
+
+	f, err := newFeed(
+		conn,
+		feedKind{
+			channels:           channels,
+			channelDescription: strings.Join(channelDescriptions, ","),
+			messageDescription: "message",
+			decoders:           decoders,
+		},
+		options,
+		productIDs,
+		func(err error) *MessageResponse { return &MessageResponse{Err: err} },
+		func(notification Notification) *MessageResponse { return &MessageResponse{Notification: notification} },
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &MessageSubscription{subscription: subscription[*MessageResponse]{feed: f}}
+
+	f.handle = s.handleMessage
+	f.start()
+
+	return s, nil
+}
+
+// ReadProduct can be used to read from this subscription for productID. Every
+// message is read, other than those of type "heartbeat" and "subscriptions" (see
+// Subscribed), as a MessageResponse.Message. Messages of a type without a decoder
+// are read as a *RawMessage, and messages that aren't for a product (e.g. "status")
+// are read for every product. Errors (including "error" messages), reconnect events
+// and staleness are read as they are for MatchesSubscription.ReadProduct. The
+// channel is closed when the subscription is done.
+//
+// Returns nil if the subscription is not for productID.
+func (s *MessageSubscription) ReadProduct(productID ProductID) <-chan *MessageResponse {
+	return s.feed.readProduct(productID)
+}
+
+// handleMessage pushes message to the read channel of its product or, if it isn't
+// for a product, to all of them.
+func (s *MessageSubscription) handleMessage(header messageHeader, message Message) bool {
+	if header.ProductID == ProductIDUnknown {
+		for _, read := range s.feed.subscribedReads() {
+			s.feed.push(read, &MessageResponse{Message: message})
+		}
+
+		return true
+	}
+
+	if read, ok := s.feed.readFor(header.Type, header.ProductID); ok {
+		s.feed.seen(header.ProductID)
+		s.feed.push(read, &MessageResponse{Message: message})
+	}
+
+	return true
+}
+
+// validateChannels returns an error if channels can't be subscribed to by a
+// MessageSubscription.
+func validateChannels(channels []ChannelName) error {
+	if len(channels) == 0 {
+		return fmt.Errorf("channel is required")
+	}
+
+	for _, channel := range channels {
+		if channel == ChannelNameHeartbeat {
+			return fmt.Errorf("channel %q is always subscribed to", channel)
+		}
+	}
+
+	return nil
+}
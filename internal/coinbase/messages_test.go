@@ -0,0 +1,161 @@
+package coinbase
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageSubscriptionReadProduct(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	conn, readIn := newRawMessageConn(t)
+
+	ms, err := newMessageSubscription(conn, subscriptionOptions{}, NewDecoders(), []ChannelName{ChannelNameMatches, ChannelNameStatus}, ProductIDBtcUsd, ProductIDEthUsd)
+	require.NoError(t, err, "newMessageSubscription")
+
+	t.Cleanup(func() {
+		if err := ms.Close(context.Background()); err != nil {
+			t.Errorf("Failed to close test MessageSubscription: %v", err)
+		}
+	})
+
+	// Do
+
+	readIn <- `{"type": "subscriptions", "channels": []}`
+	readIn <- `{"type": "heartbeat", "product_id": "BTC-USD"}`
+	readIn <- `{"type": "match", "product_id": "ETH-USD", "trade_id": 1}`
+	readIn <- `{"type": "status", "products": []}`
+	readIn <- `{"type": "TestABC", "product_id": "BTC-USD"}`
+	readIn <- `{"type": "match", "product_id": "ETH-BTC"}`
+
+	// Assert
+
+	if assert.Len(t, conn.WriteJSONCalls(), 1, "WriteJSON calls") {
+		assert.Equal(
+			t,
+			SubscribeRequest{
+				Type: "subscribe",
+				Channels: []SubscribeChannelRequest{
+					{Name: ChannelNameMatches, ProductIDs: []ProductID{ProductIDBtcUsd, ProductIDEthUsd}},
+					{Name: ChannelNameStatus},
+					{Name: ChannelNameHeartbeat, ProductIDs: []ProductID{ProductIDBtcUsd, ProductIDEthUsd}},
+				},
+			},
+			conn.WriteJSONCalls()[0].V,
+			"Subscribe request",
+		)
+	}
+
+	for _, tc := range []struct {
+		productID ProductID
+		expected  []*MessageResponse
+	}{
+		{
+			productID: ProductIDBtcUsd,
+			expected: []*MessageResponse{
+				{Message: &Status{Type: MessageTypeStatus, Products: []StatusProduct{}}},
+				{Message: &RawMessage{Type: "TestABC", JSON: json.RawMessage(`{"type": "TestABC", "product_id": "BTC-USD"}`)}},
+				{Err: &ProtocolError{MessageType: MessageTypeMatch, UnsubscribedProductID: ProductIDEthBtc}},
+			},
+		},
+		{
+			productID: ProductIDEthUsd,
+			expected: []*MessageResponse{
+				{Message: &Match{Type: MessageTypeMatch, ProductID: ProductIDEthUsd, TradeID: 1}},
+				{Message: &Status{Type: MessageTypeStatus, Products: []StatusProduct{}}},
+				{Err: &ProtocolError{MessageType: MessageTypeMatch, UnsubscribedProductID: ProductIDEthBtc}},
+			},
+		},
+	} {
+		read := ms.ReadProduct(tc.productID)
+
+		for _, expected := range tc.expected {
+			select {
+			case actual := <-read:
+				assert.Equal(t, expected, actual, "Actual for %s", tc.productID)
+			case <-time.NewTimer(time.Millisecond * 300).C:
+				t.Fatalf("Timed out reading for %s", tc.productID)
+			}
+		}
+	}
+}
+
+func TestMessageSubscriptionSubscribeStatus(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	conn, readIn := newRawMessageConn(t)
+
+	ms, err := newMessageSubscription(conn, subscriptionOptions{}, NewDecoders(), []ChannelName{ChannelNameMatches, ChannelNameStatus}, ProductIDBtcUsd)
+	require.NoError(t, err, "newMessageSubscription")
+
+	t.Cleanup(func() {
+		if err := ms.Close(context.Background()); err != nil {
+			t.Errorf("Failed to close test MessageSubscription: %v", err)
+		}
+	})
+
+	readIn <- `{"type": "subscriptions", "channels": [{"name": "matches", "product_ids": ["BTC-USD"]}, {"name": "status"}]}`
+
+	ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(ctxCancel)
+
+	// Do
+
+	subscribeErr := make(chan error, 1)
+	go func() { subscribeErr <- ms.Subscribe(ctx, ProductIDEthUsd) }()
+
+	require.Eventually(t, func() bool { return len(conn.WriteJSONCalls()) == 2 }, time.Second, time.Millisecond*10, "Subscribe request written")
+
+	readIn <- `{"type": "subscriptions", "channels": [{"name": "matches", "product_ids": ["BTC-USD", "ETH-USD"]}, {"name": "status"}]}`
+
+	actualSubscribeErr := <-subscribeErr
+
+	unsubscribeErr := make(chan error, 1)
+	go func() { unsubscribeErr <- ms.Unsubscribe(ctx, ProductIDBtcUsd) }()
+
+	require.Eventually(t, func() bool { return len(conn.WriteJSONCalls()) == 3 }, time.Second, time.Millisecond*10, "Unsubscribe request written")
+
+	readIn <- `{"type": "subscriptions", "channels": [{"name": "matches", "product_ids": ["ETH-USD"]}, {"name": "status"}]}`
+
+	actualUnsubscribeErr := <-unsubscribeErr
+
+	// Assert
+
+	assert.NoError(t, actualSubscribeErr, "Subscribe err")
+	assert.NoError(t, actualUnsubscribeErr, "Unsubscribe err")
+
+	assert.Equal(
+		t,
+		SubscribeRequest{
+			Type: "subscribe",
+			Channels: []SubscribeChannelRequest{
+				{Name: ChannelNameMatches, ProductIDs: []ProductID{ProductIDEthUsd}},
+				{Name: ChannelNameStatus},
+				{Name: ChannelNameHeartbeat, ProductIDs: []ProductID{ProductIDEthUsd}},
+			},
+		},
+		conn.WriteJSONCalls()[1].V,
+		"Subscribe request",
+	)
+	assert.Equal(
+		t,
+		SubscribeRequest{
+			Type: "unsubscribe",
+			Channels: []SubscribeChannelRequest{
+				{Name: ChannelNameMatches, ProductIDs: []ProductID{ProductIDBtcUsd}},
+				{Name: ChannelNameHeartbeat, ProductIDs: []ProductID{ProductIDBtcUsd}},
+			},
+		},
+		conn.WriteJSONCalls()[2].V,
+		"Unsubscribe request",
+	)
+}
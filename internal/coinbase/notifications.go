@@ -0,0 +1,169 @@
+package coinbase
+
+import (
+	"fmt"
+	"time"
+)
+
+// Notification is a non-fatal event reported on a subscription's read channel.
+// Use a type switch to determine which event occurred.
+type Notification interface {
+	fmt.Stringer
+
+	isNotification()
+}
+
+// ReconnectingNotification is reported when a subscription's connection has failed
+// and a reconnect attempt is about to be made.
+type ReconnectingNotification struct {
+	// The attempt about to be made, starting at 1.
+	Attempt int
+
+	// The backoff before the attempt is made.
+	Backoff time.Duration
+
+	// The cause of the (re)connection failure.
+	Err error
+}
+
+func (*ReconnectingNotification) isNotification() {}
+
+func (r *ReconnectingNotification) String() string {
+	return fmt.Sprintf("reconnecting (attempt %d in %v): %v", r.Attempt, r.Backoff, r.Err)
+}
+
+// ReconnectedNotification is reported when a subscription has reconnected and
+// resubscribed.
+type ReconnectedNotification struct {
+	// The number of attempts it took to reconnect.
+	Attempts int
+}
+
+func (*ReconnectedNotification) isNotification() {}
+
+func (r *ReconnectedNotification) String() string {
+	return fmt.Sprintf("reconnected after %d attempt(s)", r.Attempts)
+}
+
+// ReconnectGaveUpNotification is reported when a subscription has exhausted its
+// reconnect attempts. No further messages are read.
+type ReconnectGaveUpNotification struct {
+	// The number of attempts made.
+	Attempts int
+
+	// The cause of the last (re)connection failure.
+	Err error
+}
+
+func (*ReconnectGaveUpNotification) isNotification() {}
+
+func (r *ReconnectGaveUpNotification) String() string {
+	return fmt.Sprintf("gave up reconnecting after %d attempt(s): %v", r.Attempts, r.Err)
+}
+
+// SequenceGapNotification is reported when a match's trade ID skips ahead of the
+// one expected, indicating matches were missed.
+//
+// Expected and Received are trade IDs, not the sequence numbers of the feed. A
+// product's sequence numbers are shared by all its messages on the full channel,
+// so consecutive matches skip sequence numbers even when none are missed, whereas
+// its trade IDs are consecutive.
+type SequenceGapNotification struct {
+	ProductID ProductID
+
+	// The trade ID expected (one after the last received).
+	Expected int64
+
+	// The trade ID received.
+	Received int64
+}
+
+func (*SequenceGapNotification) isNotification() {}
+
+func (s *SequenceGapNotification) String() string {
+	return fmt.Sprintf(
+		"sequence gap for %s: expected %d, received %d (%d missing)",
+		s.ProductID, s.Expected, s.Received, s.Received-s.Expected,
+	)
+}
+
+// SequenceOutOfOrderNotification is reported when a match's trade ID is not after
+// the last received. The match itself is not read. As for SequenceGapNotification,
+// Expected and Received are trade IDs, not the sequence numbers of the feed.
+type SequenceOutOfOrderNotification struct {
+	ProductID ProductID
+
+	// The trade ID expected (one after the last received).
+	Expected int64
+
+	// The trade ID received.
+	Received int64
+
+	// True if Received is the same as the last trade ID received.
+	Duplicate bool
+}
+
+func (*SequenceOutOfOrderNotification) isNotification() {}
+
+func (s *SequenceOutOfOrderNotification) String() string {
+	if s.Duplicate {
+		return fmt.Sprintf("duplicate sequence for %s: received %d again", s.ProductID, s.Received)
+	}
+
+	return fmt.Sprintf("out of order sequence for %s: expected %d, received %d", s.ProductID, s.Expected, s.Received)
+}
+
+// BackfillNotification is reported when trades missing from a subscription (a gap
+// in trade IDs) have been fetched and read. If the fetch failed, Err is populated
+// and the trades remain missing.
+type BackfillNotification struct {
+	ProductID ProductID
+
+	// The trade ID received before the gap.
+	AfterTradeID int64
+
+	// The trade ID received after the gap.
+	BeforeTradeID int64
+
+	// The number of trades backfilled.
+	Count int
+
+	Err error
+}
+
+func (*BackfillNotification) isNotification() {}
+
+func (b *BackfillNotification) String() string {
+	if b.Err != nil {
+		return fmt.Sprintf(
+			"backfill for %s of trades between %d and %d failed: %v",
+			b.ProductID, b.AfterTradeID, b.BeforeTradeID, b.Err,
+		)
+	}
+
+	return fmt.Sprintf(
+		"backfilled %d trade(s) for %s between %d and %d",
+		b.Count, b.ProductID, b.AfterTradeID, b.BeforeTradeID,
+	)
+}
+
+// StaleNotification is reported when neither a heartbeat nor a match has been
+// received for a product within the liveness timeout (see Client.LivenessTimeout).
+type StaleNotification struct {
+	ProductID ProductID
+
+	// When a heartbeat or match was last received.
+	LastSeen time.Time
+
+	// The liveness timeout exceeded.
+	Timeout time.Duration
+}
+
+func (*StaleNotification) isNotification() {}
+
+func (s *StaleNotification) String() string {
+	return fmt.Sprintf(
+		"no heartbeat or match for %s since %s (timeout %v)",
+		s.ProductID, s.LastSeen.Format(time.RFC3339), s.Timeout,
+	)
+}
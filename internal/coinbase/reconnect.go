@@ -0,0 +1,92 @@
+package coinbase
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ReconnectPolicy configures how a subscription reconnects after its connection
+// fails. Backoff between attempts grows exponentially from InitialBackoff up to
+// MaxBackoff, with an optional random jitter.
+//
+// The zero-value of this type reconnects indefinitely using the defaults outlined
+// for each field.
+type ReconnectPolicy struct {
+	// The maximum number of consecutive attempts made before giving up. If 0,
+	// attempts are unlimited.
+	MaxAttempts int
+
+	// The backoff before the first attempt. If 0, 500ms is used.
+	InitialBackoff time.Duration
+
+	// The upper bound of any backoff. If 0, 30s is used.
+	MaxBackoff time.Duration
+
+	// The factor the backoff grows by after each attempt. If less than 1, 2 is used.
+	Multiplier float64
+
+	// The fraction (between 0 and 1) of each backoff that is randomised. For example,
+	// 0.2 will see each backoff reduced by up to 20%. If 0, there is no jitter.
+	Jitter float64
+}
+
+// backoff returns the backoff before attempt (starting at 1). random should be
+// a value in the range [0, 1) and is used to apply jitter.
+func (r *ReconnectPolicy) backoff(attempt int, random float64) time.Duration {
+	initialBackoff := r.InitialBackoff
+	if initialBackoff <= 0 {
+		initialBackoff = time.Millisecond * 500
+	}
+
+	maxBackoff := r.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = time.Second * 30
+	}
+
+	multiplier := r.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	backoff := float64(initialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if backoff > float64(maxBackoff) {
+		backoff = float64(maxBackoff)
+	}
+
+	jitter := math.Max(0, math.Min(1, r.Jitter))
+	backoff -= backoff * jitter * random
+
+	return time.Duration(backoff)
+}
+
+// exhausted returns true if no attempts remain after attempts have been made.
+func (r *ReconnectPolicy) exhausted(attempts int) bool {
+	return r.MaxAttempts > 0 && attempts >= r.MaxAttempts
+}
+
+// reconnector is used by a subscription to reconnect.
+type reconnector struct {
+	policy ReconnectPolicy
+
+	// Dials a new connection.
+	dial func(ctx context.Context) (Conn, error)
+
+	// Returns a random value in the range [0, 1), used for jitter.
+	random func() float64
+}
+
+// newReconnector creates a new reconnector that uses dial to create connections.
+// Returns nil if policy is nil.
+func newReconnector(policy *ReconnectPolicy, dial func(ctx context.Context) (Conn, error)) *reconnector {
+	if policy == nil {
+		return nil
+	}
+
+	return &reconnector{
+		policy: *policy,
+		dial:   dial,
+		random: rand.Float64, //nolint:gosec // Jitter doesn't need to be cryptographically secure.
+	}
+}
@@ -0,0 +1,113 @@
+package coinbase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconnectPolicyBackoff(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name        string
+		with        *ReconnectPolicy
+		giveAttempt int
+		giveRandom  float64
+		expected    time.Duration
+	}{
+		{
+			name:        "zero_value_first_attempt",
+			with:        &ReconnectPolicy{},
+			giveAttempt: 1,
+			expected:    time.Millisecond * 500,
+		},
+		{
+			name:        "zero_value_third_attempt",
+			with:        &ReconnectPolicy{},
+			giveAttempt: 3,
+			expected:    time.Second * 2,
+		},
+		{
+			name:        "zero_value_capped",
+			with:        &ReconnectPolicy{},
+			giveAttempt: 20,
+			expected:    time.Second * 30,
+		},
+		{
+			name:        "multiplier",
+			with:        &ReconnectPolicy{InitialBackoff: time.Second, Multiplier: 3},
+			giveAttempt: 3,
+			expected:    time.Second * 9,
+		},
+		{
+			name:        "max_backoff",
+			with:        &ReconnectPolicy{InitialBackoff: time.Second, MaxBackoff: time.Second * 3},
+			giveAttempt: 5,
+			expected:    time.Second * 3,
+		},
+		{
+			name:        "jitter",
+			with:        &ReconnectPolicy{InitialBackoff: time.Second, Jitter: 0.5},
+			giveAttempt: 1,
+			giveRandom:  0.5,
+			expected:    time.Millisecond * 750,
+		},
+		{
+			name:        "jitter_clamped",
+			with:        &ReconnectPolicy{InitialBackoff: time.Second, Jitter: 5},
+			giveAttempt: 1,
+			giveRandom:  0.5,
+			expected:    time.Millisecond * 500,
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := tc.with.backoff(tc.giveAttempt, tc.giveRandom)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestReconnectPolicyExhausted(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name         string
+		with         *ReconnectPolicy
+		giveAttempts int
+		expected     bool
+	}{
+		{
+			name:         "unlimited",
+			with:         &ReconnectPolicy{},
+			giveAttempts: 1000,
+			expected:     false,
+		},
+		{
+			name:         "remaining",
+			with:         &ReconnectPolicy{MaxAttempts: 3},
+			giveAttempts: 2,
+			expected:     false,
+		},
+		{
+			name:         "none_remaining",
+			with:         &ReconnectPolicy{MaxAttempts: 3},
+			giveAttempts: 3,
+			expected:     true,
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := tc.with.exhausted(tc.giveAttempts)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
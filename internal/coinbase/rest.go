@@ -0,0 +1,306 @@
+package coinbase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// RESTClient can be used to integrate with the Coinbase Exchange REST API.
+type RESTClient struct {
+	// The base URL of the API. If empty, https://api.exchange.coinbase.com is used.
+	BaseURL string
+
+	// The HTTP client used to make requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+// RESTTrade is a trade returned by the [Get product trades] endpoint.
+//
+// [Get product trades]: https://docs.cloud.coinbase.com/exchange/reference/exchangerestapi_getproducttrades
+type RESTTrade struct {
+	TradeID int64     `json:"trade_id"`
+	Time    time.Time `json:"time"`
+	Size    string    `json:"size"`
+	Price   string    `json:"price"`
+
+	// The side of the maker order.
+	Side Side `json:"side"`
+}
+
+// ToMatch converts the trade to a Match for productID. The Match has no sequence
+// or order IDs.
+func (r *RESTTrade) ToMatch(productID ProductID) Match {
+	return Match{
+		Type:      MessageTypeMatch,
+		TradeID:   r.TradeID,
+		Time:      r.Time,
+		ProductID: productID,
+		Size:      r.Size,
+		Price:     r.Price,
+		Side:      r.Side,
+	}
+}
+
+// Level3Book is a snapshot of the whole (order by order) book returned by the
+// [Get product book] endpoint at level 3.
+//
+// [Get product book]: https://docs.cloud.coinbase.com/exchange/reference/exchangerestapi_getproductbook
+type Level3Book struct {
+	// The sequence of the last message applied to the snapshot.
+	Sequence int64 `json:"sequence"`
+
+	// Orders in priority order (i.e. best price first, then oldest first).
+	Bids []Level3Order `json:"bids"`
+	Asks []Level3Order `json:"asks"`
+}
+
+// Level3Order is an order of a Level3Book. It's decoded from a JSON array of
+// strings, e.g. ["295.96", "0.05088265", "3b0f1225-7f84-490b-a29f-0faef9de823a"].
+type Level3Order struct {
+	Price   string
+	Size    string
+	OrderID string
+}
+
+// UnmarshalJSON decodes the Level3Order from a JSON array.
+func (l *Level3Order) UnmarshalJSON(b []byte) error {
+	fields, err := unmarshalStrings(b, 3)
+	if err != nil {
+		return fmt.Errorf("level3 order: %w", err)
+	}
+
+	l.Price, l.Size, l.OrderID = fields[0], fields[1], fields[2]
+
+	return nil
+}
+
+// RESTProduct is a product returned by the [Get single product] endpoint. Numeric
+// fields are left in their string encoding.
+//
+// [Get single product]: https://docs.cloud.coinbase.com/exchange/reference/exchangerestapi_getproduct
+type RESTProduct struct {
+	ID             ProductID `json:"id"`
+	BaseCurrency   string    `json:"base_currency"`
+	QuoteCurrency  string    `json:"quote_currency"`
+	BaseIncrement  string    `json:"base_increment"`
+	QuoteIncrement string    `json:"quote_increment"`
+	DisplayName    string    `json:"display_name"`
+	Status         string    `json:"status"`
+}
+
+// restErrorResponse is the body of an unsuccessful response.
+type restErrorResponse struct {
+	Message string `json:"message"`
+}
+
+// maxTradesPageSize is the maximum number of trades the API returns per page.
+const maxTradesPageSize = 1000
+
+// maxTradesPages is the maximum number of pages GetTradesBetween gets, bounding the
+// requests made to fill one gap.
+const maxTradesPages = 10
+
+// maxRateLimitedRetries is the number of times a request is retried after the API
+// responds 429 (Too Many Requests).
+const maxRateLimitedRetries = 3
+
+// defaultRetryAfter is how long to wait before retrying a request the API responded
+// 429 to, if it didn't say (with a Retry-After header).
+const defaultRetryAfter = time.Second
+
+func (r *RESTClient) baseURLOrDefault() string {
+	if r.BaseURL != "" {
+		return r.BaseURL
+	}
+
+	return "https://api.exchange.coinbase.com"
+}
+
+func (r *RESTClient) httpClientOrDefault() *http.Client {
+	if r.HTTPClient != nil {
+		return r.HTTPClient
+	}
+
+	return http.DefaultClient
+}
+
+// GetTrades gets a single page of (at most limit) trades for productID, newest
+// first. If after is not empty, only trades older than the after cursor are
+// returned. The cursor for the next (older) page is returned, which is empty if
+// there are no more pages.
+func (r *RESTClient) GetTrades(ctx context.Context, productID ProductID, after string, limit int) ([]RESTTrade, string, error) {
+	query := url.Values{}
+	if after != "" {
+		query.Set("after", after)
+	}
+
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var trades []RESTTrade
+
+	header, err := r.get(ctx, "/products/"+url.PathEscape(string(productID))+"/trades", query, &trades)
+	if err != nil {
+		return nil, "", fmt.Errorf("get trades for product %s: %w", productID, err)
+	}
+
+	return trades, header.Get("CB-AFTER"), nil
+}
+
+// GetTradesBetween gets all trades for productID with a trade ID after
+// afterTradeID and before beforeTradeID (both exclusive), paginating as required.
+// Trades are returned oldest first. Trade IDs are consecutive, so if there are more
+// trades between them than fit in maxTradesPages pages, none are fetched and an
+// error is returned.
+func (r *RESTClient) GetTradesBetween(ctx context.Context, productID ProductID, afterTradeID, beforeTradeID int64) ([]RESTTrade, error) {
+	if missing := beforeTradeID - afterTradeID - 1; missing > maxTradesPages*maxTradesPageSize {
+		return nil, fmt.Errorf("get trades for product %s: %d trades between %d and %d is more than the %d that can be fetched", productID, missing, afterTradeID, beforeTradeID, maxTradesPages*maxTradesPageSize)
+	}
+
+	var trades []RESTTrade
+
+	cursor := strconv.FormatInt(beforeTradeID, 10)
+	for pages := 0; cursor != ""; pages++ {
+		if pages == maxTradesPages {
+			return nil, fmt.Errorf("get trades for product %s: more than %d pages between %d and %d", productID, maxTradesPages, afterTradeID, beforeTradeID)
+		}
+
+		page, nextCursor, err := r.GetTrades(ctx, productID, cursor, maxTradesPageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		done := len(page) == 0
+		for _, trade := range page {
+			if trade.TradeID <= afterTradeID {
+				done = true
+				break
+			}
+
+			if trade.TradeID < beforeTradeID {
+				trades = append(trades, trade)
+			}
+		}
+
+		if done {
+			break
+		}
+
+		cursor = nextCursor
+	}
+
+	// Pages are newest first.
+	for a, b := 0, len(trades)-1; a < b; a, b = a+1, b-1 {
+		trades[a], trades[b] = trades[b], trades[a]
+	}
+
+	return trades, nil
+}
+
+// GetLevel3Book gets a snapshot of the whole book for productID, order by order.
+func (r *RESTClient) GetLevel3Book(ctx context.Context, productID ProductID) (*Level3Book, error) {
+	query := url.Values{}
+	query.Set("level", "3")
+
+	book := &Level3Book{}
+
+	if _, err := r.get(ctx, "/products/"+url.PathEscape(string(productID))+"/book", query, book); err != nil {
+		return nil, fmt.Errorf("get level3 book for product %s: %w", productID, err)
+	}
+
+	return book, nil
+}
+
+// GetProduct gets the details of productID, e.g. its quote increment.
+func (r *RESTClient) GetProduct(ctx context.Context, productID ProductID) (*RESTProduct, error) {
+	product := &RESTProduct{}
+
+	if _, err := r.get(ctx, "/products/"+url.PathEscape(string(productID)), nil, product); err != nil {
+		return nil, fmt.Errorf("get product %s: %w", productID, err)
+	}
+
+	return product, nil
+}
+
+// get makes a GET request to path with query, decoding the JSON response body into
+// v. The response header is returned. A failure to make the request, or a status
+// other than 200, is returned as a *TransportError (see IsRetryable).
+//
+// If the API responds 429 (Too Many Requests), the request is retried (up to
+// maxRateLimitedRetries times) after waiting as long as it says to.
+func (r *RESTClient) get(ctx context.Context, path string, query url.Values, v interface{}) (http.Header, error) {
+	for retries := 0; ; retries++ {
+		header, err := r.getOnce(ctx, path, query, v)
+
+		var transportErr *TransportError
+		if retries == maxRateLimitedRetries || !errors.As(err, &transportErr) || transportErr.StatusCode != http.StatusTooManyRequests {
+			return header, err
+		}
+
+		timer := time.NewTimer(transportErr.RetryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// getOnce makes a GET request as get does, without retrying.
+func (r *RESTClient) getOnce(ctx context.Context, path string, query url.Values, v interface{}) (http.Header, error) {
+	u := r.baseURLOrDefault() + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("User-Agent", "coinbase_vwap")
+
+	response, err := r.httpClientOrDefault().Do(request)
+	if err != nil {
+		return nil, &TransportError{Op: "do request", Err: err}
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		errorResponse := restErrorResponse{}
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		_ = json.Unmarshal(body, &errorResponse)
+
+		transportErr := &TransportError{
+			Op:         "request",
+			StatusCode: response.StatusCode,
+			Err:        fmt.Errorf("unexpected status %d: %q", response.StatusCode, errorResponse.Message),
+		}
+
+		if response.StatusCode == http.StatusTooManyRequests {
+			transportErr.RetryAfter = defaultRetryAfter
+			if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+				transportErr.RetryAfter = time.Duration(seconds) * time.Second
+			}
+		}
+
+		return nil, transportErr
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	return response.Header, nil
+}
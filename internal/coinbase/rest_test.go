@@ -0,0 +1,394 @@
+package coinbase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRESTClientGetTradesBetween(t *testing.T) {
+	t.Parallel()
+
+	tradeTime := time.Date(2022, 11, 7, 8, 19, 27, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name              string
+		giveAfterTradeID  int64
+		giveBeforeTradeID int64
+		expectedTradeIDs  []int64
+		expectedRequests  int32
+		expectedErr       string
+	}{
+		{
+			name:              "single_page",
+			giveAfterTradeID:  95,
+			giveBeforeTradeID: 99,
+			expectedTradeIDs:  []int64{96, 97, 98},
+			expectedRequests:  1,
+		},
+		{
+			name:              "many_pages",
+			giveAfterTradeID:  80,
+			giveBeforeTradeID: 100,
+			expectedTradeIDs:  []int64{81, 82, 83, 84, 85, 86, 87, 88, 89, 90, 91, 92, 93, 94, 95, 96, 97, 98, 99},
+			expectedRequests:  3,
+		},
+		{
+			name:              "runs_out_of_pages",
+			giveAfterTradeID:  -10,
+			giveBeforeTradeID: 4,
+			expectedTradeIDs:  []int64{1, 2, 3},
+			expectedRequests:  1,
+		},
+		{
+			name:              "no_gap",
+			giveAfterTradeID:  98,
+			giveBeforeTradeID: 99,
+			expectedRequests:  1,
+		},
+		{
+			name:              "too_many_pages",
+			giveAfterTradeID:  0,
+			giveBeforeTradeID: 100,
+			expectedRequests:  10,
+			expectedErr:       "get trades for product BTC-USD: more than 10 pages between 0 and 100",
+		},
+		{
+			name:              "too_many_trades",
+			giveAfterTradeID:  0,
+			giveBeforeTradeID: 20000,
+			expectedErr:       "get trades for product BTC-USD: 19999 trades between 0 and 20000 is more than the 10000 that can be fetched",
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+
+			var requests int32
+			restClient := &RESTClient{BaseURL: newTradesServerFake(t, tradeTime, 100, 8, &requests).URL}
+
+			// Do
+
+			actual, err := restClient.GetTradesBetween(context.Background(), ProductIDBtcUsd, tc.giveAfterTradeID, tc.giveBeforeTradeID)
+
+			// Assert
+
+			assert.Equal(t, tc.expectedRequests, atomic.LoadInt32(&requests), "Requests")
+
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr, "GetTradesBetween err")
+				assert.Nil(t, actual, "Trades")
+
+				return
+			}
+
+			require.NoError(t, err, "GetTradesBetween err")
+
+			var actualTradeIDs []int64
+			for _, trade := range actual {
+				actualTradeIDs = append(actualTradeIDs, trade.TradeID)
+			}
+
+			assert.Equal(t, tc.expectedTradeIDs, actualTradeIDs, "Trade IDs")
+		})
+	}
+}
+
+func TestRESTClientGetTrades(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		tradeTime := time.Date(2022, 11, 7, 8, 19, 27, 0, time.UTC)
+
+		var requests int32
+		restClient := &RESTClient{BaseURL: newTradesServerFake(t, tradeTime, 100, 2, &requests).URL}
+
+		actual, actualCursor, err := restClient.GetTrades(context.Background(), ProductIDBtcUsd, "", 2)
+
+		require.NoError(t, err, "GetTrades err")
+		assert.Equal(
+			t,
+			[]RESTTrade{
+				{TradeID: 100, Time: tradeTime, Size: "100", Price: "1.5", Side: SideBuy},
+				{TradeID: 99, Time: tradeTime, Size: "99", Price: "1.5", Side: SideBuy},
+			},
+			actual,
+			"Trades",
+		)
+		assert.Equal(t, "99", actualCursor, "Cursor")
+	})
+
+	t.Run("error_status", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"NotFound"}`)
+		}))
+		t.Cleanup(server.Close)
+
+		restClient := &RESTClient{BaseURL: server.URL}
+
+		actual, actualCursor, err := restClient.GetTrades(context.Background(), ProductIDBtcUsd, "", 0)
+
+		assert.Nil(t, actual, "Trades")
+		assert.Empty(t, actualCursor, "Cursor")
+		assert.EqualError(t, err, "get trades for product BTC-USD: request: unexpected status 404: \"NotFound\"")
+
+		var transportErr *TransportError
+		require.True(t, errors.As(err, &transportErr), "Err %v is a *TransportError", err)
+		assert.Equal(t, http.StatusNotFound, transportErr.StatusCode, "Status code")
+		assert.False(t, IsRetryable(err), "Retryable")
+	})
+
+	t.Run("rate_limited", func(t *testing.T) {
+		t.Parallel()
+
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"message":"Slow down"}`)
+		}))
+		t.Cleanup(server.Close)
+
+		restClient := &RESTClient{BaseURL: server.URL}
+
+		_, _, err := restClient.GetTrades(context.Background(), ProductIDBtcUsd, "", 0)
+
+		assert.EqualError(t, err, "get trades for product BTC-USD: request: unexpected status 429: \"Slow down\"")
+		assert.True(t, IsRetryable(err), "Retryable")
+		assert.Equal(t, int32(maxRateLimitedRetries+1), atomic.LoadInt32(&requests), "Requests")
+	})
+
+	t.Run("rate_limited_then_success", func(t *testing.T) {
+		t.Parallel()
+
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+
+				return
+			}
+
+			fmt.Fprint(w, `[{"trade_id":1}]`)
+		}))
+		t.Cleanup(server.Close)
+
+		restClient := &RESTClient{BaseURL: server.URL}
+
+		actual, _, err := restClient.GetTrades(context.Background(), ProductIDBtcUsd, "", 0)
+
+		require.NoError(t, err, "GetTrades err")
+		assert.Equal(t, []RESTTrade{{TradeID: 1}}, actual, "Trades")
+		assert.Equal(t, int32(2), atomic.LoadInt32(&requests), "Requests")
+	})
+
+	t.Run("rate_limited_until_done", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		t.Cleanup(server.Close)
+
+		restClient := &RESTClient{BaseURL: server.URL}
+
+		ctx, ctxCancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+		t.Cleanup(ctxCancel)
+
+		_, _, err := restClient.GetTrades(ctx, ProductIDBtcUsd, "", 0)
+
+		var transportErr *TransportError
+		require.True(t, errors.As(err, &transportErr), "Err %v is a *TransportError", err)
+		assert.Equal(t, time.Minute, transportErr.RetryAfter, "Retry after")
+	})
+
+	t.Run("transport_failure", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.Close()
+
+		restClient := &RESTClient{BaseURL: server.URL}
+
+		_, _, err := restClient.GetTrades(context.Background(), ProductIDBtcUsd, "", 0)
+
+		var transportErr *TransportError
+		require.True(t, errors.As(err, &transportErr), "Err %v is a *TransportError", err)
+		assert.Equal(t, "do request", transportErr.Op, "Op")
+		assert.True(t, IsRetryable(err), "Retryable")
+	})
+
+	t.Run("invalid_body", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{`)
+		}))
+		t.Cleanup(server.Close)
+
+		restClient := &RESTClient{BaseURL: server.URL}
+
+		_, _, err := restClient.GetTrades(context.Background(), ProductIDBtcUsd, "", 0)
+
+		assert.EqualError(t, err, "get trades for product BTC-USD: decode response: unexpected EOF")
+	})
+}
+
+// newTradesServerFake creates a test server faking the Get product trades endpoint.
+// Trades have IDs from 1 to latestTradeID and are served newest first, paginated
+// by the "after" cursor (defaulting to pageSize). Each request increments requests.
+func TestRESTClientGetLevel3Book(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/products/BTC-USD/book", r.URL.Path, "Path")
+			assert.Equal(t, "3", r.URL.Query().Get("level"), "Level")
+
+			fmt.Fprint(w, `{"sequence": 3, "bids": [["295.96", "0.05088265", "3b0f1225-7f84-490b-a29f-0faef9de823a"]], "asks": []}`)
+		}))
+		t.Cleanup(server.Close)
+
+		actual, err := (&RESTClient{BaseURL: server.URL}).GetLevel3Book(context.Background(), ProductIDBtcUsd)
+
+		require.NoError(t, err, "GetLevel3Book err")
+		assert.Equal(
+			t,
+			&Level3Book{
+				Sequence: 3,
+				Bids:     []Level3Order{{Price: "295.96", Size: "0.05088265", OrderID: "3b0f1225-7f84-490b-a29f-0faef9de823a"}},
+				Asks:     []Level3Order{},
+			},
+			actual,
+			"Actual",
+		)
+	})
+
+	t.Run("invalid_order", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"sequence": 3, "bids": [["295.96", "0.05088265"]]}`)
+		}))
+		t.Cleanup(server.Close)
+
+		_, err := (&RESTClient{BaseURL: server.URL}).GetLevel3Book(context.Background(), ProductIDBtcUsd)
+
+		assert.EqualError(t, err, "get level3 book for product BTC-USD: decode response: level3 order: expected 3 fields, got 2", "Err")
+	})
+}
+
+func TestRESTClientGetProduct(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/products/BTC-USD", r.URL.Path, "Path")
+
+			fmt.Fprint(w, `{"id": "BTC-USD", "base_currency": "BTC", "quote_currency": "USD", "quote_increment": "0.01", "base_increment": "0.00000001", "status": "online"}`)
+		}))
+		t.Cleanup(server.Close)
+
+		actual, err := (&RESTClient{BaseURL: server.URL}).GetProduct(context.Background(), ProductIDBtcUsd)
+
+		require.NoError(t, err, "GetProduct err")
+		assert.Equal(
+			t,
+			&RESTProduct{
+				ID:             ProductIDBtcUsd,
+				BaseCurrency:   "BTC",
+				QuoteCurrency:  "USD",
+				BaseIncrement:  "0.00000001",
+				QuoteIncrement: "0.01",
+				Status:         "online",
+			},
+			actual,
+			"Actual",
+		)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "NotFound"}`)
+		}))
+		t.Cleanup(server.Close)
+
+		_, err := (&RESTClient{BaseURL: server.URL}).GetProduct(context.Background(), ProductIDBtcUsd)
+
+		assert.EqualError(t, err, `get product BTC-USD: request: unexpected status 404: "NotFound"`, "Err")
+	})
+}
+
+func newTradesServerFake(t *testing.T, tradeTime time.Time, latestTradeID int64, pageSize int, requests *int32) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+
+		if r.URL.Path != "/products/BTC-USD/trades" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		limit := pageSize
+		if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l < limit {
+			limit = l
+		}
+
+		from := latestTradeID
+		if after, err := strconv.ParseInt(r.URL.Query().Get("after"), 10, 64); err == nil {
+			from = after - 1
+		}
+
+		body := "["
+		tradeID := from
+		for ; tradeID > 0 && tradeID > from-int64(limit); tradeID-- {
+			if tradeID != from {
+				body += ","
+			}
+
+			body += fmt.Sprintf(
+				`{"trade_id":%d,"time":%q,"size":"%d","price":"1.5","side":"buy"}`,
+				tradeID, tradeTime.Format(time.RFC3339Nano), tradeID,
+			)
+		}
+		body += "]"
+
+		if tradeID > 0 {
+			w.Header().Set("CB-AFTER", strconv.FormatInt(tradeID+1, 10))
+		}
+
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
@@ -0,0 +1,100 @@
+package coinbase
+
+import "sync"
+
+// SequenceStats counts sequence irregularities observed for a product. For matches,
+// the sequence is of trade IDs rather than the sequence numbers of the feed (see
+// SequenceGapNotification).
+type SequenceStats struct {
+	// The number of gaps detected.
+	Gaps int64
+
+	// The total number of sequence values missing across all gaps.
+	Missing int64
+
+	// The number of messages received with a sequence lower than the last.
+	OutOfOrder int64
+
+	// The number of messages received with a sequence equal to the last.
+	Duplicates int64
+}
+
+// sequenceTracker tracks the last value of a consecutive sequence (e.g. trade IDs)
+// received per product. It is safe for concurrent use.
+type sequenceTracker struct {
+	mu    sync.Mutex
+	last  map[ProductID]int64
+	stats map[ProductID]SequenceStats
+}
+
+func newSequenceTracker() *sequenceTracker {
+	return &sequenceTracker{
+		last:  make(map[ProductID]int64),
+		stats: make(map[ProductID]SequenceStats),
+	}
+}
+
+// track records sequence as received for productID. If sequence is irregular,
+// a notification describing it is returned. The returned bool is false if sequence
+// is not after the last received (i.e. the message is a duplicate or out of order).
+//
+// A sequence of 0 is treated as unset and is not tracked.
+func (s *sequenceTracker) track(productID ProductID, sequence int64) (Notification, bool) {
+	if sequence == 0 {
+		return nil, true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last, ok := s.last[productID]
+	if !ok {
+		s.last[productID] = sequence
+
+		return nil, true
+	}
+
+	stats := s.stats[productID]
+	defer func() { s.stats[productID] = stats }()
+
+	expected := last + 1
+
+	switch {
+	case sequence == expected:
+		s.last[productID] = sequence
+
+		return nil, true
+	case sequence > expected:
+		s.last[productID] = sequence
+
+		stats.Gaps++
+		stats.Missing += sequence - expected
+
+		return &SequenceGapNotification{ProductID: productID, Expected: expected, Received: sequence}, true
+	case sequence == last:
+		stats.Duplicates++
+
+		return &SequenceOutOfOrderNotification{ProductID: productID, Expected: expected, Received: sequence, Duplicate: true}, false
+	default:
+		stats.OutOfOrder++
+
+		return &SequenceOutOfOrderNotification{ProductID: productID, Expected: expected, Received: sequence}, false
+	}
+}
+
+// statsFor returns the SequenceStats for productID.
+func (s *sequenceTracker) statsFor(productID ProductID) SequenceStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stats[productID]
+}
+
+// forget stops tracking the last sequence value for productID (e.g. after
+// unsubscribing), so the next received isn't compared with it. Stats are kept.
+func (s *sequenceTracker) forget(productID ProductID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.last, productID)
+}
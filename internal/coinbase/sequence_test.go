@@ -0,0 +1,79 @@
+package coinbase
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSequenceTrackerTrack(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name                 string
+		giveSequences        []int64
+		expectedNotification Notification
+		expectedInOrder      bool
+		expectedStats        SequenceStats
+	}{
+		{
+			name:            "first",
+			giveSequences:   []int64{5},
+			expectedInOrder: true,
+		},
+		{
+			name:            "consecutive",
+			giveSequences:   []int64{5, 6, 7},
+			expectedInOrder: true,
+		},
+		{
+			name:            "unset",
+			giveSequences:   []int64{5, 0},
+			expectedInOrder: true,
+		},
+		{
+			name:                 "gap",
+			giveSequences:        []int64{5, 6, 9},
+			expectedNotification: &SequenceGapNotification{ProductID: ProductIDBtcUsd, Expected: 7, Received: 9},
+			expectedInOrder:      true,
+			expectedStats:        SequenceStats{Gaps: 1, Missing: 2},
+		},
+		{
+			name:                 "duplicate",
+			giveSequences:        []int64{5, 6, 6},
+			expectedNotification: &SequenceOutOfOrderNotification{ProductID: ProductIDBtcUsd, Expected: 7, Received: 6, Duplicate: true},
+			expectedStats:        SequenceStats{Duplicates: 1},
+		},
+		{
+			name:                 "out_of_order",
+			giveSequences:        []int64{5, 6, 4},
+			expectedNotification: &SequenceOutOfOrderNotification{ProductID: ProductIDBtcUsd, Expected: 7, Received: 4},
+			expectedStats:        SequenceStats{OutOfOrder: 1},
+		},
+		{
+			name:            "after_gap_and_out_of_order",
+			giveSequences:   []int64{5, 8, 7, 9},
+			expectedInOrder: true,
+			expectedStats:   SequenceStats{Gaps: 1, Missing: 2, OutOfOrder: 1},
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := newSequenceTracker()
+			s.track(ProductIDEthUsd, 100) // Other products shouldn't interfere.
+
+			var actualNotification Notification
+			var actualInOrder bool
+			for _, sequence := range tc.giveSequences {
+				actualNotification, actualInOrder = s.track(ProductIDBtcUsd, sequence)
+			}
+
+			assert.Equal(t, tc.expectedNotification, actualNotification, "Notification")
+			assert.Equal(t, tc.expectedInOrder, actualInOrder, "In order")
+			assert.Equal(t, tc.expectedStats, s.statsFor(ProductIDBtcUsd), "Stats")
+		})
+	}
+}
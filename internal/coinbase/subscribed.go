@@ -0,0 +1,164 @@
+package coinbase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// subscribedTracker tracks the channels (and products) the server has confirmed
+// are subscribed to, from the "subscriptions" messages it sends in response to
+// every subscribe or unsubscribe request. It is safe for concurrent use.
+type subscribedTracker struct {
+	mu       sync.Mutex
+	channels []SubscribeChannelRequest
+
+	// The number of error messages received, and the last of them.
+	errCount int
+	lastErr  *RemoteError
+
+	// Closed (and replaced) whenever channels or lastErr change.
+	changed chan struct{}
+}
+
+func newSubscribedTracker() *subscribedTracker {
+	return &subscribedTracker{changed: make(chan struct{})}
+}
+
+// confirm records channels as those currently subscribed to.
+func (s *subscribedTracker) confirm(channels []SubscribeChannelRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.channels = copyChannels(channels)
+	s.notify()
+}
+
+// fail records err as received from the server, which may be in response to a
+// subscribe or unsubscribe request.
+func (s *subscribedTracker) fail(err *RemoteError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.errCount++
+	s.lastErr = err
+	s.notify()
+}
+
+// notify signals everything waiting for a change. s.mu must be held.
+func (s *subscribedTracker) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// subscribed returns a copy of the channels currently subscribed to.
+func (s *subscribedTracker) subscribed() []SubscribeChannelRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return copyChannels(s.channels)
+}
+
+// errs returns the number of error messages received so far, see wait.
+func (s *subscribedTracker) errs() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.errCount
+}
+
+// wait waits until the channels subscribed to are confirmed to satisfy confirmed.
+// If an error message is received after the first errs were, it is returned as
+// the server's response. Returns an error if ctx or done are done first.
+func (s *subscribedTracker) wait(ctx context.Context, done <-chan struct{}, errs int, confirmed func([]SubscribeChannelRequest) bool) (*RemoteError, error) {
+	for {
+		s.mu.Lock()
+		ok := confirmed(s.channels)
+		errCount, lastErr, changed := s.errCount, s.lastErr, s.changed
+		s.mu.Unlock()
+
+		switch {
+		case ok:
+			return nil, nil
+		case errCount > errs:
+			return lastErr, nil
+		}
+
+		select {
+		case <-changed:
+		case <-done:
+			return nil, fmt.Errorf("subscription is done")
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait for subscriptions message: %w", ctx.Err())
+		}
+	}
+}
+
+// channelsInclude returns true if every one of channels includes every one of
+// productIDs in subscribed.
+func channelsInclude(subscribed []SubscribeChannelRequest, channels []ChannelName, productIDs []ProductID) bool {
+	for _, channel := range channels {
+		for _, productID := range productIDs {
+			if !channelIncludes(subscribed, channel, productID) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// channelsExclude returns true if none of channels includes any of productIDs in
+// subscribed. Channels that aren't per product aren't unsubscribed from, so are
+// ignored.
+func channelsExclude(subscribed []SubscribeChannelRequest, channels []ChannelName, productIDs []ProductID) bool {
+	for _, channel := range channels {
+		if !channel.isPerProduct() {
+			continue
+		}
+
+		for _, productID := range productIDs {
+			if channelIncludes(subscribed, channel, productID) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// channelIncludes returns true if channel includes productID in subscribed. A
+// channel that isn't per product includes every product once subscribed to.
+func channelIncludes(subscribed []SubscribeChannelRequest, channel ChannelName, productID ProductID) bool {
+	for _, subscribedChannel := range subscribed {
+		if subscribedChannel.Name != channel {
+			continue
+		}
+
+		if !channel.isPerProduct() {
+			return true
+		}
+
+		for _, subscribedProductID := range subscribedChannel.ProductIDs {
+			if subscribedProductID == productID {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// copyChannels returns a deep copy of channels.
+func copyChannels(channels []SubscribeChannelRequest) []SubscribeChannelRequest {
+	if channels == nil {
+		return nil
+	}
+
+	copied := make([]SubscribeChannelRequest, len(channels))
+	for a, channel := range channels {
+		copied[a] = SubscribeChannelRequest{Name: channel.Name, ProductIDs: append([]ProductID(nil), channel.ProductIDs...)}
+	}
+
+	return copied
+}
@@ -0,0 +1,86 @@
+package coinbase
+
+import "context"
+
+// subscription is embedded by each type of subscription (e.g. MatchesSubscription)
+// for the behaviour they share, over a feed of R. Each type reads its own messages
+// (see their ReadProduct).
+type subscription[R response] struct {
+	feed *feed[R]
+}
+
+// ProductID returns the Product ID for this subscription. If the subscription is
+// for multiple products, this is the first of them.
+func (s *subscription[R]) ProductID() ProductID {
+	return s.feed.productID()
+}
+
+// ProductIDs returns all Product IDs for this subscription, in the order they were
+// subscribed to.
+func (s *subscription[R]) ProductIDs() []ProductID {
+	return s.feed.copyProductIDs()
+}
+
+// Read can be used to read from this subscription for the product returned by
+// ProductID. See ReadProduct.
+func (s *subscription[R]) Read() <-chan R {
+	return s.feed.readProduct(s.ProductID())
+}
+
+// Subscribe subscribes to the subscription's channels for productIDs, in addition
+// to the products already subscribed to, over the existing connection (or the next,
+// if reconnecting). It then waits until the server confirms the subscription with
+// a "subscriptions" message (see Subscribed), an "error" message is received (which
+// is taken as the server rejecting it) or ctx is done. If ctx is done the
+// subscription may still be confirmed later.
+//
+// The read channel of each product (see ReadProduct) is available once this is
+// invoked.
+func (s *subscription[R]) Subscribe(ctx context.Context, productIDs ...ProductID) error {
+	return s.feed.subscribe(ctx, productIDs)
+}
+
+// Unsubscribe unsubscribes from the subscription's channels for productIDs over
+// the existing connection, waiting for confirmation as Subscribe does. Nothing more
+// is read for productIDs once this is invoked, but their read channels are only
+// closed when the subscription is done. The subscription can't be unsubscribed
+// from every product, see Close.
+func (s *subscription[R]) Unsubscribe(ctx context.Context, productIDs ...ProductID) error {
+	return s.feed.unsubscribe(ctx, productIDs)
+}
+
+// Subscribed returns the channels, and their products, the server last confirmed
+// are subscribed to over the connection.
+func (s *subscription[R]) Subscribed() []SubscribeChannelRequest {
+	return s.feed.subscribedChannels()
+}
+
+// Dropped returns the number of messages for productID discarded because its read
+// channel was full, as per the overflow policy (see Client.Backpressure). Always 0
+// when blocking, which Level2 and Full subscriptions always do.
+func (s *subscription[R]) Dropped(productID ProductID) uint64 {
+	return s.feed.droppedCount(productID)
+}
+
+// Done returns a channel that's closed when the subscription has stopped reading,
+// either because it was closed or because its connection failed (and was not
+// reconnected). See Err.
+func (s *subscription[R]) Done() <-chan struct{} {
+	return s.feed.done
+}
+
+// Err returns the error that stopped the subscription reading, or nil if the
+// subscription is not done or was stopped by Close.
+func (s *subscription[R]) Err() error {
+	return s.feed.doneErr()
+}
+
+// Close can be used to close the subscription. A close message is sent to the
+// server and the read loop is given until ctx is done to exit, before the connection
+// is closed regardless. Close always returns once ctx is done.
+//
+// Close is safe to invoke more than once, and concurrently. Subsequent invocations
+// wait for the first to return and return the same error.
+func (s *subscription[R]) Close(ctx context.Context) error {
+	return s.feed.Close(ctx)
+}
@@ -2,151 +2,255 @@ package coinbase
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"sync"
+	"time"
 )
 
 // MatchesSubscription is created by a Client to manage a subscription to the [Matches Channel].
+// A single subscription can multiplex many products over one connection, with each
+// match routed to a per-product read channel.
 //
 // [Matches Channel]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#matches-channel
 type MatchesSubscription struct {
-	productID ProductID
+	subscription[*MatchResponse]
 
-	conn Conn
+	// Tracks the trade IDs of matches read, per product.
+	sequences *sequenceTracker
 
-	// A read channel, pushed to by the connection read loop
-	read chan *MatchResponse
-
-	// A channel to signal the read loop to stop.
-	stopReading chan struct{}
-
-	// True if the reading loop has been stopped.
-	isReadingStopped bool
+	// The products being backfilled, each with the matches received for it since,
+	// which are handled once its backfill completes.
+	backfilling   map[ProductID][]Match
+	backfillingMu sync.Mutex
 }
 
 // newMatchesSubscription creates a new MatchesSubscription. It will first subscribe
-// to the Matches Channel for productID over conn (using ctx). If this is successful,
-// the read loop is started.
-func newMatchesSubscription(ctx context.Context, conn Conn, productID ProductID) (*MatchesSubscription, error) {
-	if productID == ProductIDUnknown {
-		return nil, fmt.Errorf("productID is required")
-	}
-
-	request := SubscribeRequest{
-		Type: "subscribe",
-		Channels: []SubscribeChannelRequest{
-			{
-				Name: ChannelNameMatches,
-				ProductIDs: []ProductID{
-					productID,
-				},
-			},
+// to the Matches and Heartbeat Channels for all productIDs over conn (using ctx),
+// in a single request. If this is successful, the read loop is started.
+func newMatchesSubscription(ctx context.Context, conn Conn, options subscriptionOptions, productIDs ...ProductID) (*MatchesSubscription, error) {
+	f, err := newFeed(
+		conn,
+		feedKind{
+			channels:           []ChannelName{ChannelNameMatches},
+			channelDescription: "Matches",
+			messageDescription: "match",
+			decoders:           defaultDecoders,
 		},
-	}
-	if err := conn.WriteJSON(request); err != nil {
-		return nil, fmt.Errorf("subscribing to Matches channel for product %s: %w", productID, err)
+		options,
+		productIDs,
+		func(err error) *MatchResponse { return &MatchResponse{Err: err} },
+		func(notification Notification) *MatchResponse { return &MatchResponse{Notification: notification} },
+	)
+	if err != nil {
+		return nil, err
 	}
 
 	m := &MatchesSubscription{
-		productID:   productID,
-		conn:        conn,
-		read:        make(chan *MatchResponse, 10),
-		stopReading: make(chan struct{}),
+		subscription: subscription[*MatchResponse]{feed: f},
+		sequences:    newSequenceTracker(),
+		backfilling:  make(map[ProductID][]Match),
 	}
 
-	m.startReading()
+	f.handle = m.handleMessage
+	f.start()
 
 	return m, nil
 }
 
-// ProductID returns the Product ID for this subscription.
-func (m *MatchesSubscription) ProductID() ProductID {
-	return m.productID
+// ReadProduct can be used to read from this subscription for productID. Only
+// messages of type "match", "last_match" or "error" are read. Errors that aren't
+// specific to a product (e.g. "error" messages) are read for every product. On
+// connection error, the error is read and the subscription is done (see Done),
+// unless the subscription is reconnecting (see Client.Reconnect). Reconnect events
+// are read as a MatchResponse.Notification for every product. The channel is
+// closed when the subscription is done.
+//
+// Match trade IDs are tracked per product (a match's sequence value isn't, as it's
+// shared with the product's other messages on the Full Channel, so isn't
+// consecutive between matches). A gap is read as a SequenceGapNotification before
+// the match that revealed it. Matches that are duplicated or out of order are not
+// read, a SequenceOutOfOrderNotification is read in their place.
+//
+// If the subscription has a liveness timeout (see Client.LivenessTimeout), a
+// StaleNotification is read when neither a heartbeat nor a match is received for
+// the product within it. If the subscription is also reconnecting, the connection
+// is then reconnected.
+//
+// If the subscription backfills (see Client.Backfill), matches missing between
+// trade IDs are fetched and read (in order, before the match that revealed them)
+// with MatchResponse.Backfilled set, followed by a BackfillNotification. Backfills
+// run in the background so other products aren't held up, the product's matches
+// received meanwhile are read once its backfill completes.
+//
+// If the channel isn't read from quickly enough and fills up, the subscription's
+// overflow policy applies (see Client.Backpressure and Dropped).
+//
+// Returns nil if the subscription is not for productID.
+func (m *MatchesSubscription) ReadProduct(productID ProductID) <-chan *MatchResponse {
+	return m.feed.readProduct(productID)
+}
+
+// SequenceStats returns counts of the sequence irregularities observed for
+// productID so far. See ReadProduct.
+func (m *MatchesSubscription) SequenceStats(productID ProductID) SequenceStats {
+	return m.sequences.statsFor(productID)
 }
 
-// Read can be used to read from this subscription. Only messages of type "match",
-// "last_match" or "error" are read. On connection error, no further messages are
-// read.
-func (m *MatchesSubscription) Read() <-chan *MatchResponse {
-	return m.read
+// Subscribe subscribes to the Matches Channel for productIDs, in addition to the
+// products already subscribed to, over the existing connection (or the next, if
+// reconnecting). It then waits until the server confirms the subscription with a
+// "subscriptions" message (see Subscribed), an "error" message is received (which
+// is taken as the server rejecting it) or ctx is done. If ctx is done the
+// subscription may still be confirmed later.
+//
+// The read channel of each product (see ReadProduct) is available once this is
+// invoked. Trade IDs are tracked afresh for products previously unsubscribed from.
+func (m *MatchesSubscription) Subscribe(ctx context.Context, productIDs ...ProductID) error {
+	for _, productID := range productIDs {
+		if m.feed.read(productID) != nil {
+			m.sequences.forget(productID)
+		}
+	}
+
+	return m.feed.subscribe(ctx, productIDs)
 }
 
-// Close can be used to close the subscription.
-func (m *MatchesSubscription) Close(ctx context.Context) error {
-	stopReadingDone := make(chan struct{})
-	go func() {
-		m.signalStopReading()
+// handleMessage handles a message of type "match" or "last_match".
+func (m *MatchesSubscription) handleMessage(_ messageHeader, message Message) bool {
+	match, ok := message.(*Match)
+	if !ok {
+		return false
+	}
 
-		close(stopReadingDone)
-	}()
+	m.handleMatch(*match)
 
-	ctxDoneBeforeReadStopped := false
+	return true
+}
 
-	// Wait for ctx or read loop to exit gracefully.
-	select {
-	case <-ctx.Done():
-		ctxDoneBeforeReadStopped = true
-	case <-stopReadingDone:
+// handleMatch pushes match to the relevant read channel, unless its product is
+// being backfilled, in which case it's queued until the backfill completes.
+func (m *MatchesSubscription) handleMatch(match Match) {
+	read, ok := m.feed.readFor(match.Type, match.ProductID)
+	if !ok {
+		return
 	}
 
-	// If the read loop is blocked, this will unblock it.
-	if err := m.conn.Close(); err != nil {
-		return fmt.Errorf("close connection: %w", err)
+	m.feed.seen(match.ProductID)
+
+	m.backfillingMu.Lock()
+	if queued, ok := m.backfilling[match.ProductID]; ok {
+		m.backfilling[match.ProductID] = append(queued, match)
+		m.backfillingMu.Unlock()
+
+		return
 	}
+	m.backfillingMu.Unlock()
 
-	if ctxDoneBeforeReadStopped {
-		// Ideally this package wouldn't be logging on a whim like this.
-		log.Printf("[WAR] Read loop took to long to exit: %v", ctx.Err())
+	gap := m.track(read, match, m.feed.closing)
+	if gap == nil {
+		return
 	}
 
-	return nil
+	m.backfillingMu.Lock()
+	m.backfilling[match.ProductID] = nil
+	m.backfillingMu.Unlock()
+
+	m.feed.goBackground(func() { m.backfill(read, match, gap) })
 }
 
-func (m *MatchesSubscription) signalStopReading() {
-	if !m.isReadingStopped {
-		m.stopReading <- struct{}{} // blocks until the read loop has exited.
+// track tracks the trade ID of match and pushes it (after any notification) to
+// read, blocking until stop is closed if need be. If match reveals a gap to be
+// backfilled, it isn't pushed and the gap is returned instead.
+func (m *MatchesSubscription) track(read *readChannel[*MatchResponse], match Match, stop <-chan struct{}) *SequenceGapNotification {
+	notification, inOrder := m.sequences.track(match.ProductID, match.TradeID)
+	if notification != nil {
+		m.feed.pushUntil(read, &MatchResponse{Notification: notification}, stop)
+	}
 
-		m.isReadingStopped = true
+	if !inOrder {
+		return nil
+	}
 
-		close(m.stopReading)
+	if gap, ok := notification.(*SequenceGapNotification); ok && m.feed.options.backfill != nil {
+		return gap
 	}
-}
 
-func (m *MatchesSubscription) startReading() {
-	go func() {
-		defer func() {
-			close(m.read)
-		}()
+	m.feed.pushUntil(read, &MatchResponse{Match: match}, stop)
 
-		for {
-			select {
-			case <-m.stopReading:
-				return
-			default:
-			}
+	return nil
+}
 
-			message := Match{}
-			if err := m.conn.ReadJSON(&message); err != nil {
-				// if m.read's buffer is full and not being drained, this would
-				// block indefinitely.
-				m.read <- &MatchResponse{Err: fmt.Errorf("read match: %w", err)}
+// backfill runs in the background (see feed.goBackground). It fetches the trades
+// missing before match (revealed by gap) and pushes them to read, followed by
+// match. The matches queued for the product meanwhile are then handled, backfilling
+// again if one reveals another gap, until there are none.
+func (m *MatchesSubscription) backfill(read *readChannel[*MatchResponse], match Match, gap *SequenceGapNotification) {
+	stop := m.feed.stopBackground
 
-				// There's no recovery here, if we keep invoking conn.ReadJSON it
-				// will eventually panic. Wait for the signal to exit.
-				<-m.stopReading
+	for gap != nil {
+		m.fetch(read, match.ProductID, gap.Expected-1, gap.Received, stop)
+		m.feed.pushUntil(read, &MatchResponse{Match: match}, stop)
 
+		for gap = nil; gap == nil; {
+			var ok bool
+			if match, ok = m.dequeue(match.ProductID); !ok {
 				return
 			}
 
-			switch message.Type {
-			case MessageTypeError:
-				m.read <- &MatchResponse{Err: fmt.Errorf("error message received: %q", message.Message)}
-			case MessageTypeLastMatch, MessageTypeMatch:
-				m.read <- &MatchResponse{Match: message}
-			case MessageTypeSubscriptions:
-			default:
-				m.read <- &MatchResponse{Err: fmt.Errorf("received unexpected message with type %q", message.Type)}
-			}
+			gap = m.track(read, match, stop)
 		}
-	}()
+	}
+}
+
+// dequeue returns the next match queued for productID while it was backfilled. If
+// there are none, its backfill is complete and false is returned.
+func (m *MatchesSubscription) dequeue(productID ProductID) (Match, bool) {
+	m.backfillingMu.Lock()
+	defer m.backfillingMu.Unlock()
+
+	queued := m.backfilling[productID]
+	if len(queued) == 0 {
+		delete(m.backfilling, productID)
+
+		return Match{}, false
+	}
+
+	m.backfilling[productID] = queued[1:]
+
+	return queued[0], true
+}
+
+// fetch fetches the trades for productID between afterTradeID and beforeTradeID
+// (exclusive), and pushes them to read followed by a BackfillNotification.
+func (m *MatchesSubscription) fetch(read *readChannel[*MatchResponse], productID ProductID, afterTradeID, beforeTradeID int64, stop <-chan struct{}) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer ctxCancel()
+
+	ctx, ctxCancel = m.feed.cancelOnClosing(ctx)
+	defer ctxCancel()
+
+	ctx, ctxCancel = cancelOn(ctx, stop)
+	defer ctxCancel()
+
+	trades, err := m.feed.options.backfill.GetTradesBetween(ctx, productID, afterTradeID, beforeTradeID)
+	if err != nil {
+		m.feed.pushUntil(read, &MatchResponse{Notification: &BackfillNotification{
+			ProductID:     productID,
+			AfterTradeID:  afterTradeID,
+			BeforeTradeID: beforeTradeID,
+			Err:           err,
+		}}, stop)
+
+		return
+	}
+
+	for a := range trades {
+		m.feed.pushUntil(read, &MatchResponse{Match: trades[a].ToMatch(productID), Backfilled: true}, stop)
+	}
+
+	m.feed.pushUntil(read, &MatchResponse{Notification: &BackfillNotification{
+		ProductID:     productID,
+		AfterTradeID:  afterTradeID,
+		BeforeTradeID: beforeTradeID,
+		Count:         len(trades),
+	}}, stop)
 }
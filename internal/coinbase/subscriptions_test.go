@@ -2,10 +2,16 @@ package coinbase
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -22,14 +28,14 @@ func TestMatchesSubscriptionRead(t *testing.T) {
 	}{
 		{
 			name:       "read_match",
-			readMatch:  &Match{Type: MessageTypeMatch},
-			expected:   &MatchResponse{Match: Match{Type: MessageTypeMatch}},
+			readMatch:  &Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd},
+			expected:   &MatchResponse{Match: Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd}},
 			expectedOk: true,
 		},
 		{
 			name:       "read_last_match",
-			readMatch:  &Match{Type: MessageTypeLastMatch},
-			expected:   &MatchResponse{Match: Match{Type: MessageTypeLastMatch}},
+			readMatch:  &Match{Type: MessageTypeLastMatch, ProductID: ProductIDBtcUsd},
+			expected:   &MatchResponse{Match: Match{Type: MessageTypeLastMatch, ProductID: ProductIDBtcUsd}},
 			expectedOk: true,
 		},
 		{
@@ -39,19 +45,25 @@ func TestMatchesSubscriptionRead(t *testing.T) {
 		{
 			name:       "read_error",
 			readMatch:  &Match{Type: MessageTypeError, Message: "TestABC"},
-			expected:   &MatchResponse{Err: fmt.Errorf("error message received: \"TestABC\"")},
+			expected:   &MatchResponse{Err: &RemoteError{Message: "TestABC"}},
+			expectedOk: true,
+		},
+		{
+			name:       "read_match_unsubscribed_product",
+			readMatch:  &Match{Type: MessageTypeMatch, ProductID: ProductIDEthUsd},
+			expected:   &MatchResponse{Err: &ProtocolError{MessageType: MessageTypeMatch, UnsubscribedProductID: ProductIDEthUsd}},
 			expectedOk: true,
 		},
 		{
 			name:       "read_unknown",
 			readMatch:  &Match{Type: MessageTypeUnknown},
-			expected:   &MatchResponse{Err: fmt.Errorf("received unexpected message with type \"\"")},
+			expected:   &MatchResponse{Err: &ProtocolError{}},
 			expectedOk: true,
 		},
 		{
 			name:       "err_reading",
 			readErr:    fmt.Errorf("TestABC"),
-			expected:   &MatchResponse{Err: fmt.Errorf("read match: %w", fmt.Errorf("TestABC"))},
+			expected:   &MatchResponse{Err: &TransportError{Op: "read match", Err: fmt.Errorf("TestABC")}},
 			expectedOk: true,
 		},
 	} {
@@ -65,40 +77,877 @@ func TestMatchesSubscriptionRead(t *testing.T) {
 			ctx, ctxCancel := context.WithCancel(context.Background())
 			t.Cleanup(ctxCancel)
 
-			ms := newMatchesSubscriptionWithNext(ctx, t, nil, nil)
+			ms := newMatchesSubscriptionWithNext(ctx, t, nil, nil, subscriptionOptions{})
 			t.Cleanup(func() {
 				ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second)
 				defer ctxCancel()
 
 				ms.fillAndDrainRead(ctx, t) // Make sure the read isn't blocked
 
-				if err := ms.matchesSubscription.Close(ctx); err != nil {
-					t.Errorf("Failed to close test MatchesSubscription: %v", err)
-				}
-			})
+				if err := ms.matchesSubscription.Close(ctx); err != nil {
+					t.Errorf("Failed to close test MatchesSubscription: %v", err)
+				}
+			})
+
+			ms.readIn <- &matchesNext{match: tc.readMatch, err: tc.readErr}
+
+			// Do
+
+			var actual *MatchResponse
+			var actualOk bool
+
+			select {
+			case actual, actualOk = <-ms.matchesSubscription.Read():
+				break
+			case <-time.NewTimer(time.Millisecond * 300).C:
+				// Need to give the above read a chance first, but this is prone
+				// to flakiness.
+				break
+			}
+
+			// Assert
+
+			assert.Equal(t, tc.expected, actual, "Actual")
+			assert.Equal(t, tc.expectedOk, actualOk, "Actual Ok")
+		})
+	}
+}
+
+func TestMatchesSubscriptionReadProduct(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	t.Cleanup(ctxCancel)
+
+	ms := newMatchesSubscriptionWithNext(ctx, t, nil, nil, subscriptionOptions{}, ProductIDBtcUsd, ProductIDEthUsd)
+	t.Cleanup(func() {
+		ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second)
+		defer ctxCancel()
+
+		ms.fillAndDrainRead(ctx, t) // Make sure the read isn't blocked
+
+		if err := ms.matchesSubscription.Close(ctx); err != nil {
+			t.Errorf("Failed to close test MatchesSubscription: %v", err)
+		}
+	})
+
+	// Do
+
+	ms.readIn <- &matchesNext{match: &Match{Type: MessageTypeMatch, ProductID: ProductIDEthUsd, Size: "1"}}
+	ms.readIn <- &matchesNext{match: &Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd, Size: "2"}}
+	ms.readIn <- &matchesNext{match: &Match{Type: MessageTypeError, Message: "TestABC"}}
+
+	// Assert
+
+	assert.Equal(t, []ProductID{ProductIDBtcUsd, ProductIDEthUsd}, ms.matchesSubscription.ProductIDs(), "ProductIDs")
+	assert.Nil(t, ms.matchesSubscription.ReadProduct(ProductIDEthBtc), "ReadProduct for unsubscribed product")
+
+	for _, tc := range []struct {
+		productID ProductID
+		expected  []*MatchResponse
+	}{
+		{
+			productID: ProductIDBtcUsd,
+			expected: []*MatchResponse{
+				{Match: Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd, Size: "2"}},
+				{Err: &RemoteError{Message: "TestABC"}},
+			},
+		},
+		{
+			productID: ProductIDEthUsd,
+			expected: []*MatchResponse{
+				{Match: Match{Type: MessageTypeMatch, ProductID: ProductIDEthUsd, Size: "1"}},
+				{Err: &RemoteError{Message: "TestABC"}},
+			},
+		},
+	} {
+		read := ms.matchesSubscription.ReadProduct(tc.productID)
+
+		for _, expected := range tc.expected {
+			select {
+			case actual := <-read:
+				assert.Equal(t, expected, actual, "Actual for %s", tc.productID)
+			case <-time.NewTimer(time.Millisecond * 300).C:
+				t.Fatalf("Timed out reading for %s", tc.productID)
+			}
+		}
+	}
+}
+
+func TestMatchesSubscriptionReadSequence(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	t.Cleanup(ctxCancel)
+
+	ms := newMatchesSubscriptionWithNext(ctx, t, nil, nil, subscriptionOptions{})
+	t.Cleanup(func() {
+		ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second)
+		defer ctxCancel()
+
+		ms.fillAndDrainRead(ctx, t) // Make sure the read isn't blocked
+
+		if err := ms.matchesSubscription.Close(ctx); err != nil {
+			t.Errorf("Failed to close test MatchesSubscription: %v", err)
+		}
+	})
+
+	// Do
+
+	// Sequence values aren't consecutive between matches, so only trade IDs are tracked.
+	for _, tradeID := range []int64{1, 2, 5, 5, 6} {
+		ms.readIn <- &matchesNext{match: &Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd, TradeID: tradeID, Sequence: tradeID * 10}}
+	}
+
+	// Assert
+
+	match := func(tradeID int64) *MatchResponse {
+		return &MatchResponse{Match: Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd, TradeID: tradeID, Sequence: tradeID * 10}}
+	}
+
+	for _, expected := range []*MatchResponse{
+		match(1),
+		match(2),
+		{Notification: &SequenceGapNotification{ProductID: ProductIDBtcUsd, Expected: 3, Received: 5}},
+		match(5),
+		{Notification: &SequenceOutOfOrderNotification{ProductID: ProductIDBtcUsd, Expected: 6, Received: 5, Duplicate: true}},
+		match(6),
+	} {
+		select {
+		case actual := <-ms.matchesSubscription.Read():
+			assert.Equal(t, expected, actual, "Actual")
+		case <-time.NewTimer(time.Millisecond * 300).C:
+			t.Fatalf("Timed out reading")
+		}
+	}
+
+	assert.Equal(
+		t,
+		SequenceStats{Gaps: 1, Missing: 2, Duplicates: 1},
+		ms.matchesSubscription.SequenceStats(ProductIDBtcUsd),
+		"Sequence stats",
+	)
+}
+
+func TestMatchesSubscriptionReadBackfill(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	tradeTime := time.Date(2022, 11, 7, 8, 19, 27, 0, time.UTC)
+
+	var requests int32
+	server := newTradesServerFake(t, tradeTime, 100, 2, &requests)
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	t.Cleanup(ctxCancel)
+
+	ms := newMatchesSubscriptionWithNext(ctx, t, nil, nil, subscriptionOptions{backfill: &RESTClient{BaseURL: server.URL}})
+	t.Cleanup(func() {
+		ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second)
+		defer ctxCancel()
+
+		ms.fillAndDrainRead(ctx, t) // Make sure the read isn't blocked
+
+		if err := ms.matchesSubscription.Close(ctx); err != nil {
+			t.Errorf("Failed to close test MatchesSubscription: %v", err)
+		}
+	})
+
+	// Do
+
+	ms.readIn <- &matchesNext{match: &Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd, TradeID: 90}}
+	ms.readIn <- &matchesNext{match: &Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd, TradeID: 94}}
+
+	// Assert
+
+	backfilledMatch := func(tradeID int64) *MatchResponse {
+		return &MatchResponse{
+			Match: Match{
+				Type:      MessageTypeMatch,
+				TradeID:   tradeID,
+				Time:      tradeTime,
+				ProductID: ProductIDBtcUsd,
+				Size:      strconv.FormatInt(tradeID, 10),
+				Price:     "1.5",
+				Side:      SideBuy,
+			},
+			Backfilled: true,
+		}
+	}
+
+	for _, expected := range []*MatchResponse{
+		{Match: Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd, TradeID: 90}},
+		{Notification: &SequenceGapNotification{ProductID: ProductIDBtcUsd, Expected: 91, Received: 94}},
+		backfilledMatch(91),
+		backfilledMatch(92),
+		backfilledMatch(93),
+		{Notification: &BackfillNotification{ProductID: ProductIDBtcUsd, AfterTradeID: 90, BeforeTradeID: 94, Count: 3}},
+		{Match: Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd, TradeID: 94}},
+	} {
+		select {
+		case actual := <-ms.matchesSubscription.Read():
+			assert.Equal(t, expected, actual, "Actual")
+		case <-time.NewTimer(time.Millisecond * 300).C:
+			t.Fatalf("Timed out reading")
+		}
+	}
+}
+
+func TestMatchesSubscriptionReadBackfillInBackground(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	tradeTime := time.Date(2022, 11, 7, 8, 19, 27, 0, time.UTC)
+
+	var requests int32
+	tradesServer := newTradesServerFake(t, tradeTime, 100, 2, &requests)
+
+	// Holds up the backfill until released.
+	release := make(chan struct{})
+	releaseOnce := sync.Once{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		tradesServer.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	// Before the server is closed, which waits for the handler.
+	t.Cleanup(func() { releaseOnce.Do(func() { close(release) }) })
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	t.Cleanup(ctxCancel)
+
+	ms := newMatchesSubscriptionWithNext(
+		ctx,
+		t,
+		nil,
+		nil,
+		subscriptionOptions{backfill: &RESTClient{BaseURL: server.URL}},
+		ProductIDBtcUsd,
+		ProductIDEthUsd,
+	)
+	t.Cleanup(func() {
+		ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second)
+		defer ctxCancel()
+
+		ms.fillAndDrainRead(ctx, t) // Make sure the read isn't blocked
+
+		if err := ms.matchesSubscription.Close(ctx); err != nil {
+			t.Errorf("Failed to close test MatchesSubscription: %v", err)
+		}
+	})
+
+	readNext := func(productID ProductID) *MatchResponse {
+		select {
+		case actual := <-ms.matchesSubscription.ReadProduct(productID):
+			return actual
+		case <-time.NewTimer(time.Millisecond * 300).C:
+			t.Fatalf("Timed out reading %s", productID)
+
+			return nil
+		}
+	}
+
+	// Do
+
+	ms.readIn <- &matchesNext{match: &Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd, TradeID: 90}}
+	ms.readIn <- &matchesNext{match: &Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd, TradeID: 94}}
+	ms.readIn <- &matchesNext{match: &Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd, TradeID: 95}}
+	ms.readIn <- &matchesNext{match: &Match{Type: MessageTypeMatch, ProductID: ProductIDEthUsd, TradeID: 1}}
+
+	// Assert
+
+	// Other products aren't held up by the backfill.
+	assert.Equal(t, &MatchResponse{Match: Match{Type: MessageTypeMatch, ProductID: ProductIDEthUsd, TradeID: 1}}, readNext(ProductIDEthUsd), "Other product")
+
+	assert.Equal(t, &MatchResponse{Match: Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd, TradeID: 90}}, readNext(ProductIDBtcUsd), "Before gap")
+	assert.Equal(t, &MatchResponse{Notification: &SequenceGapNotification{ProductID: ProductIDBtcUsd, Expected: 91, Received: 94}}, readNext(ProductIDBtcUsd), "Gap")
+	assert.Empty(t, ms.matchesSubscription.ReadProduct(ProductIDBtcUsd), "Read while backfilling")
+
+	releaseOnce.Do(func() { close(release) })
+
+	for _, expected := range []int64{91, 92, 93} {
+		actual := readNext(ProductIDBtcUsd)
+		assert.True(t, actual.Backfilled, "Backfilled")
+		assert.Equal(t, expected, actual.Match.TradeID, "Backfilled trade ID")
+	}
+
+	assert.Equal(
+		t,
+		&MatchResponse{Notification: &BackfillNotification{ProductID: ProductIDBtcUsd, AfterTradeID: 90, BeforeTradeID: 94, Count: 3}},
+		readNext(ProductIDBtcUsd),
+		"Backfill notification",
+	)
+
+	// Then the matches queued while backfilling, in order.
+	assert.Equal(t, &MatchResponse{Match: Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd, TradeID: 94}}, readNext(ProductIDBtcUsd), "Revealed gap")
+	assert.Equal(t, &MatchResponse{Match: Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd, TradeID: 95}}, readNext(ProductIDBtcUsd), "Queued")
+}
+
+func TestMatchesSubscriptionLiveness(t *testing.T) {
+	t.Parallel()
+
+	t.Run("stale", func(t *testing.T) {
+		t.Parallel()
+
+		// Setup
+
+		ctx, ctxCancel := context.WithCancel(context.Background())
+		t.Cleanup(ctxCancel)
+
+		ms := newMatchesSubscriptionWithNext(ctx, t, nil, nil, subscriptionOptions{livenessTimeout: time.Millisecond * 20})
+		t.Cleanup(func() {
+			ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second)
+			defer ctxCancel()
+
+			ms.fillAndDrainRead(ctx, t) // Make sure the read isn't blocked
+
+			if err := ms.matchesSubscription.Close(ctx); err != nil {
+				t.Errorf("Failed to close test MatchesSubscription: %v", err)
+			}
+		})
+
+		// Do
+
+		var actual *MatchResponse
+
+		select {
+		case actual = <-ms.matchesSubscription.Read():
+		case <-time.NewTimer(time.Millisecond * 300).C:
+			t.Fatalf("Timed out reading")
+		}
+
+		// Assert
+
+		if assert.IsType(t, (*StaleNotification)(nil), actual.Notification, "Notification") {
+			notification := actual.Notification.(*StaleNotification)
+
+			assert.Equal(t, ProductIDBtcUsd, notification.ProductID, "Product ID")
+			assert.Equal(t, time.Millisecond*20, notification.Timeout, "Timeout")
+		}
+	})
+
+	t.Run("tiny_timeout", func(t *testing.T) {
+		t.Parallel()
+
+		// Setup
+
+		ctx, ctxCancel := context.WithCancel(context.Background())
+		t.Cleanup(ctxCancel)
+
+		// A quarter of which rounds down to 0, which must not panic the watchdog.
+		ms := newMatchesSubscriptionWithNext(ctx, t, nil, nil, subscriptionOptions{livenessTimeout: time.Nanosecond * 3})
+		t.Cleanup(func() {
+			ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second)
+			defer ctxCancel()
+
+			ms.fillAndDrainRead(ctx, t) // Make sure the read isn't blocked
+
+			if err := ms.matchesSubscription.Close(ctx); err != nil {
+				t.Errorf("Failed to close test MatchesSubscription: %v", err)
+			}
+		})
+
+		// Do
+
+		var actual *MatchResponse
+
+		select {
+		case actual = <-ms.matchesSubscription.Read():
+		case <-time.NewTimer(time.Millisecond * 300).C:
+			t.Fatalf("Timed out reading")
+		}
+
+		// Assert
+
+		assert.IsType(t, (*StaleNotification)(nil), actual.Notification, "Notification")
+	})
+
+	t.Run("heartbeats_keep_alive", func(t *testing.T) {
+		t.Parallel()
+
+		// Setup
+
+		ctx, ctxCancel := context.WithCancel(context.Background())
+		t.Cleanup(ctxCancel)
+
+		ms := newMatchesSubscriptionWithNext(ctx, t, nil, nil, subscriptionOptions{livenessTimeout: time.Millisecond * 100})
+		t.Cleanup(func() {
+			ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second)
+			defer ctxCancel()
+
+			ms.fillAndDrainRead(ctx, t) // Make sure the read isn't blocked
+
+			if err := ms.matchesSubscription.Close(ctx); err != nil {
+				t.Errorf("Failed to close test MatchesSubscription: %v", err)
+			}
+		})
+
+		// Do
+
+		heartbeatsDone := time.NewTimer(time.Millisecond * 300)
+		heartbeatTicker := time.NewTicker(time.Millisecond * 10)
+		t.Cleanup(heartbeatTicker.Stop)
+
+	heartbeats:
+		for {
+			select {
+			case <-heartbeatsDone.C:
+				break heartbeats
+			case <-heartbeatTicker.C:
+				ms.readIn <- &matchesNext{heartbeat: &Heartbeat{Type: MessageTypeHeartbeat, ProductID: ProductIDBtcUsd}}
+			}
+		}
+
+		// Assert
+
+		select {
+		case actual := <-ms.matchesSubscription.Read():
+			t.Errorf("Unexpected read: %v", actual)
+		default:
+		}
+	})
+
+	t.Run("stale_reconnects", func(t *testing.T) {
+		t.Parallel()
+
+		// Setup
+
+		closed := make(chan struct{})
+		closedOnce := sync.Once{}
+		staleConn := &ConnMock{
+			WriteJSONFunc: func(v interface{}) error { return nil },
+			ReadJSONFunc: func(v interface{}) error {
+				<-closed
+				return fmt.Errorf("TestABC")
+			},
+			CloseFunc: func() error {
+				closedOnce.Do(func() { close(closed) })
+				return nil
+			},
+		}
+
+		r := &reconnector{
+			policy: ReconnectPolicy{MaxAttempts: 1, InitialBackoff: time.Millisecond},
+			dial: func(_ context.Context) (Conn, error) {
+				return nil, fmt.Errorf("TestDEF")
+			},
+			random: func() float64 { return 0 },
+		}
+
+		ms, err := newMatchesSubscription(
+			context.Background(),
+			staleConn,
+			subscriptionOptions{reconnector: r, livenessTimeout: time.Millisecond * 20},
+			ProductIDBtcUsd,
+		)
+		require.NoError(t, err, "newMatchesSubscription")
+
+		// Do
+
+		var actual []*MatchResponse
+		for a := 0; a < 4; a++ {
+			select {
+			case matchResponse := <-ms.Read():
+				actual = append(actual, matchResponse)
+			case <-time.NewTimer(time.Millisecond * 300).C:
+				t.Fatalf("Timed out reading")
+			}
+		}
+
+		// Assert
+
+		require.Len(t, actual, 4, "Actual")
+		assert.IsType(t, (*StaleNotification)(nil), actual[0].Notification, "Stale")
+		assert.Equal(
+			t,
+			[]*MatchResponse{
+				{Notification: &ReconnectingNotification{Attempt: 1, Backoff: time.Millisecond, Err: &TransportError{Op: "read match", Err: fmt.Errorf("TestABC")}}},
+				{Notification: &ReconnectGaveUpNotification{Attempts: 1, Err: fmt.Errorf("TestDEF")}},
+				{Err: &TransportError{Op: "read match", Err: fmt.Errorf("TestABC")}},
+			},
+			actual[1:],
+			"Reconnect",
+		)
+
+		assert.NoError(t, ms.Close(context.Background()), "Close")
+	})
+}
+
+func TestNewMatchesSubscriptionErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name           string
+		giveOptions    subscriptionOptions
+		giveProductIDs []ProductID
+		expectedErr    string
+	}{
+		{
+			name:        "no_productids",
+			expectedErr: "productID is required",
+		},
+		{
+			name:           "unknown_productid",
+			giveProductIDs: []ProductID{ProductIDBtcUsd, ProductIDUnknown},
+			expectedErr:    "productID is required",
+		},
+		{
+			name:           "duplicate_productid",
+			giveProductIDs: []ProductID{ProductIDBtcUsd, ProductIDEthUsd, ProductIDBtcUsd},
+			expectedErr:    "productID BTC-USD is duplicated",
+		},
+		{
+			name:           "negative_buffer_size",
+			giveOptions:    subscriptionOptions{backpressure: Backpressure{BufferSize: -1}},
+			giveProductIDs: []ProductID{ProductIDBtcUsd},
+			expectedErr:    "backpressure buffer size must not be negative, got -1",
+		},
+		{
+			name:           "unknown_overflow_policy",
+			giveOptions:    subscriptionOptions{backpressure: Backpressure{Overflow: "TestABC"}},
+			giveProductIDs: []ProductID{ProductIDBtcUsd},
+			expectedErr:    `backpressure: unknown overflow policy "TestABC"`,
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := newMatchesSubscription(context.Background(), &ConnMock{}, tc.giveOptions, tc.giveProductIDs...)
+
+			assert.Nil(t, actual, "Actual")
+			assert.EqualError(t, actualErr, tc.expectedErr, "Actual err")
+		})
+	}
+}
+
+func TestMatchesSubscriptionBackpressure(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name            string
+		giveOverflow    OverflowPolicy
+		expectedTradeID []int64
+		expectedDropped uint64
+	}{
+		{
+			name:            "drop_oldest",
+			giveOverflow:    OverflowDropOldest,
+			expectedTradeID: []int64{2, 3, 4},
+			expectedDropped: 1,
+		},
+		{
+			name:            "drop_newest",
+			giveOverflow:    OverflowDropNewest,
+			expectedTradeID: []int64{1, 2, 3},
+			expectedDropped: 1,
+		},
+		{
+			name:            "coalesce",
+			giveOverflow:    OverflowCoalesce,
+			expectedTradeID: []int64{4},
+			expectedDropped: 3,
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+
+			ctx, ctxCancel := context.WithCancel(context.Background())
+			t.Cleanup(ctxCancel)
+
+			ms := newMatchesSubscriptionWithNext(
+				ctx,
+				t,
+				nil,
+				nil,
+				subscriptionOptions{backpressure: Backpressure{BufferSize: 3, Overflow: tc.giveOverflow}},
+			)
+			t.Cleanup(func() {
+				ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second)
+				defer ctxCancel()
+
+				ms.fillAndDrainRead(ctx, t) // Make sure the read isn't blocked
+
+				if err := ms.matchesSubscription.Close(ctx); err != nil {
+					t.Errorf("Failed to close test MatchesSubscription: %v", err)
+				}
+			})
+
+			// Do
+
+			for a := int64(1); a <= 4; a++ {
+				ms.readIn <- &matchesNext{match: &Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd, TradeID: a, Sequence: a}}
+			}
+
+			// Once this is read, the last match has been pushed.
+			ms.readIn <- &matchesNext{heartbeat: &Heartbeat{Type: MessageTypeHeartbeat, ProductID: ProductIDBtcUsd}}
+
+			// Assert
+
+			read := ms.matchesSubscription.Read()
+
+			actualTradeID := make([]int64, len(tc.expectedTradeID))
+			for a := range actualTradeID {
+				select {
+				case response := <-read:
+					actualTradeID[a] = response.Match.TradeID
+				case <-time.NewTimer(time.Millisecond * 300).C:
+					t.Fatalf("Timed out reading")
+				}
+			}
+
+			assert.Equal(t, tc.expectedTradeID, actualTradeID, "Trade IDs read")
+			assert.Equal(t, tc.expectedDropped, ms.matchesSubscription.Dropped(ProductIDBtcUsd), "Dropped")
+			assert.Zero(t, ms.matchesSubscription.Dropped(ProductIDEthUsd), "Dropped (other product)")
+		})
+	}
+}
+
+func TestMatchesSubscriptionReconnect(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reconnects", func(t *testing.T) {
+		t.Parallel()
+
+		// Setup
+
+		failingConn := &ConnMock{
+			WriteJSONFunc: func(v interface{}) error { return nil },
+			ReadJSONFunc:  func(v interface{}) error { return fmt.Errorf("TestABC") },
+			CloseFunc:     func() error { return nil },
+		}
+
+		readCount := 0
+		reconnectedConn := &ConnMock{
+			WriteJSONFunc: func(v interface{}) error { return nil },
+			ReadJSONFunc: func(v interface{}) error {
+				readCount++
+				if readCount > 1 {
+					return fmt.Errorf("TestDEF")
+				}
+
+				setReadJSON(t, v, Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd})
+
+				return nil
+			},
+			CloseFunc: func() error { return nil },
+		}
+
+		dialCount := 0
+		r := &reconnector{
+			policy: ReconnectPolicy{MaxAttempts: 1, InitialBackoff: time.Millisecond},
+			dial: func(_ context.Context) (Conn, error) {
+				dialCount++
+				if dialCount > 1 {
+					return nil, fmt.Errorf("TestGHI")
+				}
+
+				return reconnectedConn, nil
+			},
+			random: func() float64 { return 0 },
+		}
+
+		ms, err := newMatchesSubscription(context.Background(), failingConn, subscriptionOptions{reconnector: r}, ProductIDBtcUsd)
+		require.NoError(t, err, "newMatchesSubscription")
+
+		// Do
+
+		var actual []*MatchResponse
+		for a := 0; a < 6; a++ {
+			select {
+			case matchResponse := <-ms.Read():
+				actual = append(actual, matchResponse)
+			case <-time.NewTimer(time.Millisecond * 300).C:
+				t.Fatalf("Timed out reading")
+			}
+		}
+
+		// Assert
+
+		assert.Equal(
+			t,
+			[]*MatchResponse{
+				{Notification: &ReconnectingNotification{Attempt: 1, Backoff: time.Millisecond, Err: &TransportError{Op: "read match", Err: fmt.Errorf("TestABC")}}},
+				{Notification: &ReconnectedNotification{Attempts: 1}},
+				{Match: Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd}},
+				{Notification: &ReconnectingNotification{Attempt: 1, Backoff: time.Millisecond, Err: &TransportError{Op: "read match", Err: fmt.Errorf("TestDEF")}}},
+				{Notification: &ReconnectGaveUpNotification{Attempts: 1, Err: fmt.Errorf("TestGHI")}},
+				{Err: &TransportError{Op: "read match", Err: fmt.Errorf("TestDEF")}},
+			},
+			actual,
+			"Actual",
+		)
+		assert.Len(t, failingConn.CloseCalls(), 1, "Failing conn close calls")
+		if assert.Len(t, reconnectedConn.WriteJSONCalls(), 1, "Reconnected conn write calls") {
+			assert.Equal(t, ms.feed.subscribeRequest(), reconnectedConn.WriteJSONCalls()[0].V, "Resubscribe request")
+		}
+
+		assert.NoError(t, ms.Close(context.Background()), "Close")
+	})
+
+	t.Run("resubscribe_fails", func(t *testing.T) {
+		t.Parallel()
+
+		// Setup
+
+		r := &reconnector{
+			policy: ReconnectPolicy{MaxAttempts: 1, InitialBackoff: time.Millisecond},
+			dial: func(_ context.Context) (Conn, error) {
+				return &ConnMock{
+					WriteJSONFunc: func(v interface{}) error { return fmt.Errorf("TestDEF") },
+					CloseFunc:     func() error { return nil },
+				}, nil
+			},
+			random: func() float64 { return 0 },
+		}
+
+		ms, err := newMatchesSubscription(
+			context.Background(),
+			&ConnMock{
+				WriteJSONFunc: func(v interface{}) error { return nil },
+				ReadJSONFunc:  func(v interface{}) error { return fmt.Errorf("TestABC") },
+				CloseFunc:     func() error { return nil },
+			},
+			subscriptionOptions{reconnector: r},
+			ProductIDBtcUsd,
+			ProductIDEthUsd,
+		)
+		require.NoError(t, err, "newMatchesSubscription")
+
+		// Do
+
+		var actual []*MatchResponse
+		for a := 0; a < 3; a++ {
+			select {
+			case matchResponse := <-ms.ReadProduct(ProductIDEthUsd):
+				actual = append(actual, matchResponse)
+			case <-time.NewTimer(time.Millisecond * 300).C:
+				t.Fatalf("Timed out reading")
+			}
+		}
+
+		// Assert
+
+		readErr := &TransportError{Op: "read match", Err: fmt.Errorf("TestABC")}
+
+		assert.Equal(
+			t,
+			[]*MatchResponse{
+				{Notification: &ReconnectingNotification{Attempt: 1, Backoff: time.Millisecond, Err: readErr}},
+				{Notification: &ReconnectGaveUpNotification{Attempts: 1, Err: &TransportError{Op: "resubscribing to Matches channel for product BTC-USD,ETH-USD", Err: fmt.Errorf("TestDEF")}}},
+				{Err: readErr},
+			},
+			actual,
+			"Actual",
+		)
+
+		assert.NoError(t, ms.Close(context.Background()), "Close")
+	})
+
+	t.Run("gives_up_when_not_retryable", func(t *testing.T) {
+		t.Parallel()
+
+		// Setup
+
+		dialErr := &TransportError{Op: "dialing coinbase", StatusCode: http.StatusUnauthorized, Err: fmt.Errorf("TestDEF")}
+
+		r := &reconnector{
+			policy: ReconnectPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond},
+			dial: func(_ context.Context) (Conn, error) {
+				return nil, dialErr
+			},
+			random: func() float64 { return 0 },
+		}
+
+		ms, err := newMatchesSubscription(
+			context.Background(),
+			&ConnMock{
+				WriteJSONFunc: func(v interface{}) error { return nil },
+				ReadJSONFunc:  func(v interface{}) error { return fmt.Errorf("TestABC") },
+				CloseFunc:     func() error { return nil },
+			},
+			subscriptionOptions{reconnector: r},
+			ProductIDBtcUsd,
+		)
+		require.NoError(t, err, "newMatchesSubscription")
+
+		// Do
+
+		var actual []*MatchResponse
+		for matchResponse := range ms.Read() {
+			actual = append(actual, matchResponse)
+		}
+
+		// Assert
+
+		assert.Equal(
+			t,
+			[]*MatchResponse{
+				{Notification: &ReconnectingNotification{Attempt: 1, Backoff: time.Millisecond, Err: &TransportError{Op: "read match", Err: fmt.Errorf("TestABC")}}},
+				{Notification: &ReconnectGaveUpNotification{Attempts: 1, Err: dialErr}},
+				{Err: &TransportError{Op: "read match", Err: fmt.Errorf("TestABC")}},
+			},
+			actual,
+			"Actual",
+		)
+		assert.NoError(t, ms.Close(context.Background()), "Close")
+	})
+
+	t.Run("close_while_reconnecting", func(t *testing.T) {
+		t.Parallel()
+
+		// Setup
+
+		r := &reconnector{
+			policy: ReconnectPolicy{InitialBackoff: time.Hour},
+			dial: func(_ context.Context) (Conn, error) {
+				return nil, fmt.Errorf("TestABC")
+			},
+			random: func() float64 { return 0 },
+		}
+
+		ms, err := newMatchesSubscription(
+			context.Background(),
+			&ConnMock{
+				WriteJSONFunc: func(v interface{}) error { return nil },
+				ReadJSONFunc:  func(v interface{}) error { return fmt.Errorf("TestABC") },
+				CloseFunc:     func() error { return nil },
+			},
+			subscriptionOptions{reconnector: r},
+			ProductIDBtcUsd,
+		)
+		require.NoError(t, err, "newMatchesSubscription")
 
-			ms.readIn <- &matchesNext{match: tc.readMatch, err: tc.readErr}
+		<-ms.Read() // Reconnecting notification
 
-			// Do
+		// Do
 
-			var actual *MatchResponse
-			var actualOk bool
+		closeCtx, closeCtxCancel := context.WithTimeout(context.Background(), time.Millisecond*300)
+		t.Cleanup(closeCtxCancel)
 
-			select {
-			case actual, actualOk = <-ms.matchesSubscription.Read():
-				break
-			case <-time.NewTimer(time.Millisecond * 300).C:
-				// Need to give the above read a chance first, but this is prone
-				// to flakiness.
-				break
-			}
+		closeErr := make(chan error)
+		go func() { closeErr <- ms.Close(closeCtx) }()
 
-			// Assert
+		// Assert
 
-			assert.Equal(t, tc.expected, actual, "Actual")
-			assert.Equal(t, tc.expectedOk, actualOk, "Actual Ok")
-		})
-	}
+		_, ok := <-ms.Read()
+		assert.False(t, ok, "Read after close")
+		assert.NoError(t, <-closeErr, "Close err")
+		assert.NoError(t, ms.Err(), "Err")
+	})
 }
 
 func TestMatchesSubscriptionClose(t *testing.T) {
@@ -112,7 +961,7 @@ func TestMatchesSubscriptionClose(t *testing.T) {
 		ctx, ctxCancel := context.WithCancel(context.Background())
 		t.Cleanup(ctxCancel)
 
-		ms := newMatchesSubscriptionWithNext(ctx, t, nil, nil)
+		ms := newMatchesSubscriptionWithNext(ctx, t, nil, nil, subscriptionOptions{})
 
 		ms.fillAndDrainRead(ctx, t)
 
@@ -136,7 +985,7 @@ func TestMatchesSubscriptionClose(t *testing.T) {
 		ctx, ctxCancel := context.WithCancel(context.Background())
 		t.Cleanup(ctxCancel)
 
-		ms := newMatchesSubscriptionWithNext(ctx, t, nil, func() error { return fmt.Errorf("TestABC") })
+		ms := newMatchesSubscriptionWithNext(ctx, t, nil, func() error { return fmt.Errorf("TestABC") }, subscriptionOptions{})
 
 		ms.fillAndDrainRead(ctx, t)
 
@@ -160,9 +1009,9 @@ func TestMatchesSubscriptionClose(t *testing.T) {
 		ctx, ctxCancel := context.WithCancel(context.Background())
 		t.Cleanup(ctxCancel)
 
-		ms := newMatchesSubscriptionWithNext(ctx, t, nil, nil)
+		ms := newMatchesSubscriptionWithNext(ctx, t, nil, nil, subscriptionOptions{})
 
-		ms.readIn <- &matchesNext{match: &Match{Type: MessageTypeMatch}} // Block until we know the read loop is running.
+		ms.readIn <- &matchesNext{match: &Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd}} // Block until we know the read loop is running.
 
 		// Do
 
@@ -184,9 +1033,9 @@ func TestMatchesSubscriptionClose(t *testing.T) {
 		ctx, ctxCancel := context.WithCancel(context.Background())
 		t.Cleanup(ctxCancel)
 
-		ms := newMatchesSubscriptionWithNext(ctx, t, nil, func() error { return fmt.Errorf("TestABC") })
+		ms := newMatchesSubscriptionWithNext(ctx, t, nil, func() error { return fmt.Errorf("TestABC") }, subscriptionOptions{})
 
-		ms.readIn <- &matchesNext{match: &Match{Type: MessageTypeMatch}} // Block until we know the read loop is running.
+		ms.readIn <- &matchesNext{match: &Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd}} // Block until we know the read loop is running.
 
 		// Do
 
@@ -199,13 +1048,200 @@ func TestMatchesSubscriptionClose(t *testing.T) {
 
 		assert.EqualError(t, err, "close connection: TestABC", "Close err")
 	})
+
+	t.Run("close_handshake_ignored_returns_by_deadline", func(t *testing.T) {
+		t.Parallel()
+
+		// Setup
+
+		reading := make(chan struct{})
+		closed := make(chan struct{})
+		ms, err := newMatchesSubscription(
+			context.Background(),
+			&ConnMock{
+				WriteJSONFunc: func(v interface{}) error { return nil },
+				ReadJSONFunc: func(v interface{}) error {
+					close(reading)
+					<-closed
+					return fmt.Errorf("TestABC")
+				},
+				WriteControlFunc: func(messageType int, data []byte, deadline time.Time) error {
+					return nil
+				},
+				SetReadDeadlineFunc: func(t time.Time) error { return nil },
+				CloseFunc: func() error {
+					close(closed)
+					return nil
+				},
+			},
+			subscriptionOptions{},
+			ProductIDBtcUsd,
+		)
+		require.NoError(t, err, "newMatchesSubscription")
+
+		<-reading // Block until we know the read loop is reading.
+
+		// Do
+
+		closeCtx, closeCtxCancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+		t.Cleanup(closeCtxCancel)
+
+		start := time.Now()
+		err = ms.Close(closeCtx)
+		elapsed := time.Since(start)
+
+		// Assert
+
+		assert.EqualError(t, err, "wait for read loop to exit: context deadline exceeded", "Close err")
+		assert.Less(t, elapsed, time.Millisecond*300, "Close duration")
+
+		select {
+		case <-ms.Done():
+		case <-time.NewTimer(time.Millisecond * 300).C:
+			t.Fatalf("Timed out waiting for done")
+		}
+
+		assert.NoError(t, ms.Err(), "Err")
+	})
+
+	t.Run("close_handshake", func(t *testing.T) {
+		t.Parallel()
+
+		// Setup
+
+		ctx, ctxCancel := context.WithCancel(context.Background())
+		t.Cleanup(ctxCancel)
+
+		ms := newMatchesSubscriptionWithNext(ctx, t, nil, nil, subscriptionOptions{})
+		conn := ms.matchesSubscription.feed.currentConn().(*ConnMock)
+
+		closeCtx, closeCtxCancel := context.WithTimeout(context.Background(), time.Millisecond*300)
+		t.Cleanup(closeCtxCancel)
+
+		// Do
+
+		err := ms.matchesSubscription.Close(closeCtx)
+
+		// Assert
+
+		assert.NoError(t, err, "Close err")
+
+		if assert.Len(t, conn.WriteControlCalls(), 1, "WriteControl calls") {
+			call := conn.WriteControlCalls()[0]
+			deadline, _ := closeCtx.Deadline()
+
+			assert.Equal(t, websocket.CloseMessage, call.MessageType, "Message type")
+			assert.Equal(t, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), call.Data, "Data")
+			assert.Equal(t, deadline, call.Deadline, "Deadline")
+		}
+
+		if assert.Len(t, conn.SetReadDeadlineCalls(), 1, "SetReadDeadline calls") {
+			deadline, _ := closeCtx.Deadline()
+
+			assert.Equal(t, deadline, conn.SetReadDeadlineCalls()[0].T, "Read deadline")
+		}
+
+		assert.Len(t, conn.CloseCalls(), 1, "Close calls")
+
+		_, ok := <-ms.matchesSubscription.Read()
+		assert.False(t, ok, "Read open")
+		assert.NoError(t, ms.matchesSubscription.Err(), "Err")
+	})
+
+	t.Run("concurrent_and_repeated", func(t *testing.T) {
+		t.Parallel()
+
+		// Setup
+
+		ctx, ctxCancel := context.WithCancel(context.Background())
+		t.Cleanup(ctxCancel)
+
+		ms := newMatchesSubscriptionWithNext(ctx, t, nil, func() error { return fmt.Errorf("TestABC") }, subscriptionOptions{})
+
+		closeCtx, closeCtxCancel := context.WithTimeout(context.Background(), time.Millisecond*300)
+		t.Cleanup(closeCtxCancel)
+
+		// Do
+
+		errs := make(chan error, 5)
+		wg := sync.WaitGroup{}
+		for a := 0; a < cap(errs); a++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				errs <- ms.matchesSubscription.Close(closeCtx)
+			}()
+		}
+
+		wg.Wait()
+		close(errs)
+
+		// Assert
+
+		for err := range errs {
+			assert.EqualError(t, err, "close connection: TestABC", "Close err")
+		}
+
+		assert.EqualError(t, ms.matchesSubscription.Close(closeCtx), "close connection: TestABC", "Repeated close err")
+	})
+}
+
+func TestMatchesSubscriptionDone(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	t.Cleanup(ctxCancel)
+
+	ms := newMatchesSubscriptionWithNext(ctx, t, nil, nil, subscriptionOptions{})
+	t.Cleanup(func() {
+		if err := ms.matchesSubscription.Close(context.Background()); err != nil {
+			t.Errorf("Failed to close test MatchesSubscription: %v", err)
+		}
+	})
+
+	assert.NoError(t, ms.matchesSubscription.Err(), "Err before done")
+
+	// Do
+
+	ms.readIn <- &matchesNext{err: fmt.Errorf("TestABC")}
+
+	// Assert
+
+	assert.Equal(t, &MatchResponse{Err: &TransportError{Op: "read match", Err: fmt.Errorf("TestABC")}}, <-ms.matchesSubscription.Read(), "Read")
+
+	select {
+	case <-ms.matchesSubscription.Done():
+	case <-time.NewTimer(time.Millisecond * 300).C:
+		t.Fatalf("Timed out waiting for done")
+	}
+
+	_, ok := <-ms.matchesSubscription.Read()
+	assert.False(t, ok, "Read open")
+	assert.EqualError(t, ms.matchesSubscription.Err(), "read match: TestABC", "Err")
 }
 
-// matchesNext is used by matchesSubscriptionWithNext to return a Match or err from
-// the MatchesSubscription.Conn's ReadJSON method.
+// matchesNext is used by matchesSubscriptionWithNext to return a Match, Heartbeat
+// or err from the MatchesSubscription.Conn's ReadJSON method.
 type matchesNext struct {
-	match *Match
-	err   error
+	match     *Match
+	heartbeat *Heartbeat
+	err       error
+}
+
+// setReadJSON sets v (as passed to a Conn's ReadJSON method) to the JSON encoding
+// of message.
+func setReadJSON(t *testing.T, v interface{}, message interface{}) {
+	t.Helper()
+
+	require.IsType(t, (*json.RawMessage)(nil), v, "")
+
+	b, err := json.Marshal(message)
+	require.NoError(t, err, "marshal message")
+
+	*v.(*json.RawMessage) = b
 }
 
 // matchesSubscriptionWithNext wraps a MatchesSubscription and provides some helper
@@ -223,9 +1259,16 @@ type matchesSubscriptionWithNext struct {
 //   - ctx - can be used to signal that the push loop should exit. See field matchesSubscriptionWithNext.readIn.
 //   - writeJSONFunc- override for MatchesSubscription.Conn's WriteJSON method. If nil, a default is used.
 //   - closeFunc - override for MatchesSubscription.Conn's Close method. If nil, a default is used.
-func newMatchesSubscriptionWithNext(ctx context.Context, t *testing.T, writeJSONFunc func(v interface{}) error, closeFunc func() error) *matchesSubscriptionWithNext {
+//     Writing a close message (i.e. the close handshake) unblocks ReadJSON with an error.
+//   - options - the MatchesSubscription's options.
+//   - productIDs - the products subscribed to. If empty, ProductIDBtcUsd is used.
+func newMatchesSubscriptionWithNext(ctx context.Context, t *testing.T, writeJSONFunc func(v interface{}) error, closeFunc func() error, options subscriptionOptions, productIDs ...ProductID) *matchesSubscriptionWithNext {
 	t.Helper()
 
+	if len(productIDs) == 0 {
+		productIDs = []ProductID{ProductIDBtcUsd}
+	}
+
 	if writeJSONFunc == nil {
 		writeJSONFunc = func(v interface{}) error { return nil }
 	}
@@ -236,17 +1279,20 @@ func newMatchesSubscriptionWithNext(ctx context.Context, t *testing.T, writeJSON
 
 	read := make(chan *matchesNext)
 
+	// Closed when a close message is written (i.e. the close handshake is started).
+	closeWritten := make(chan struct{})
+	closeWrittenOnce := sync.Once{}
+
 	matchesSubscription, err := newMatchesSubscription(
 		context.Background(), // This shouldn't matter given the tests relying on this method.
 		&ConnMock{
 			WriteJSONFunc: writeJSONFunc,
 			ReadJSONFunc: func(v interface{}) error {
-				require.IsType(t, (*Match)(nil), v, "")
-				matchIn := v.(*Match)
-
 				select {
 				case <-ctx.Done():
 					return fmt.Errorf("matchesSubscriptionWithNext ctx expired: %w", ctx.Err())
+				case <-closeWritten:
+					return fmt.Errorf("matchesSubscriptionWithNext close message written")
 				case matchOut, ok := <-read:
 					if !ok {
 						break
@@ -256,21 +1302,34 @@ func newMatchesSubscriptionWithNext(ctx context.Context, t *testing.T, writeJSON
 						return matchOut.err
 					}
 
-					*matchIn = *matchOut.match
+					if matchOut.heartbeat != nil {
+						setReadJSON(t, v, matchOut.heartbeat)
+					} else {
+						setReadJSON(t, v, matchOut.match)
+					}
+				}
+
+				return nil
+			},
+			WriteControlFunc: func(messageType int, data []byte, deadline time.Time) error {
+				if messageType == websocket.CloseMessage {
+					closeWrittenOnce.Do(func() { close(closeWritten) })
 				}
 
 				return nil
 			},
-			CloseFunc: closeFunc,
+			SetReadDeadlineFunc: func(t time.Time) error { return nil },
+			CloseFunc:           closeFunc,
 		},
-		ProductIDBtcUsd, // This shouldn't matter given the tests relying on this method.
+		options,
+		productIDs...,
 	)
 	require.NoError(t, err, "create newMatchesSubscriptionWithNext")
 
 	return &matchesSubscriptionWithNext{matchesSubscription: matchesSubscription, readIn: read}
 }
 
-// fillAndDrainRead will fill and drain the MatchesSubscription's Read channel. This
+// fillAndDrainRead will fill and drain the MatchesSubscription's read channels. This
 // is useful in simulating a read loop not blocked on MatchesSubscription.Conn's
 // ReadJSON method.
 func (m *matchesSubscriptionWithNext) fillAndDrainRead(ctx context.Context, t *testing.T) {
@@ -281,17 +1340,276 @@ func (m *matchesSubscriptionWithNext) fillAndDrainRead(ctx context.Context, t *t
 			select {
 			case <-ctx.Done():
 				return
-			default:
-				m.readIn <- &matchesNext{match: &Match{Type: MessageTypeMatch}}
+			case m.readIn <- &matchesNext{match: &Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd}}:
 			}
 		}
 	}()
 
-	go func() {
-		for {
-			if _, ok := <-m.matchesSubscription.Read(); ok != true {
+	for _, productID := range m.matchesSubscription.ProductIDs() {
+		read := m.matchesSubscription.ReadProduct(productID)
+
+		go func() {
+			for {
+				if _, ok := <-read; ok != true {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func TestMatchesSubscriptionSubscribe(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name            string
+		giveProductIDs  []ProductID
+		giveResponse    string
+		expectedErr     error
+		expectedRequest *SubscribeRequest
+	}{
+		{
+			name:           "confirmed",
+			giveProductIDs: []ProductID{ProductIDEthUsd},
+			giveResponse:   `{"type": "subscriptions", "channels": [{"name": "matches", "product_ids": ["BTC-USD", "ETH-USD"]}]}`,
+			expectedRequest: &SubscribeRequest{
+				Type: "subscribe",
+				Channels: []SubscribeChannelRequest{
+					{Name: ChannelNameMatches, ProductIDs: []ProductID{ProductIDEthUsd}},
+					{Name: ChannelNameHeartbeat, ProductIDs: []ProductID{ProductIDEthUsd}},
+				},
+			},
+		},
+		{
+			name:           "error_message",
+			giveProductIDs: []ProductID{ProductIDEthUsd},
+			giveResponse:   `{"type": "error", "message": "TestABC", "reason": "TestDEF"}`,
+			expectedErr: &SubscriptionRejectedError{
+				RequestType: "subscribe",
+				ProductIDs:  []ProductID{ProductIDEthUsd},
+				Err:         &RemoteError{Message: "TestABC", Reason: "TestDEF"},
+			},
+			expectedRequest: &SubscribeRequest{
+				Type: "subscribe",
+				Channels: []SubscribeChannelRequest{
+					{Name: ChannelNameMatches, ProductIDs: []ProductID{ProductIDEthUsd}},
+					{Name: ChannelNameHeartbeat, ProductIDs: []ProductID{ProductIDEthUsd}},
+				},
+			},
+		},
+		{
+			name:           "already_subscribed",
+			giveProductIDs: []ProductID{ProductIDBtcUsd},
+			expectedErr:    fmt.Errorf("productID BTC-USD is already subscribed"),
+		},
+		{
+			name:           "duplicated",
+			giveProductIDs: []ProductID{ProductIDEthUsd, ProductIDEthUsd},
+			expectedErr:    fmt.Errorf("productID ETH-USD is duplicated"),
+		},
+		{
+			name:        "no_product",
+			expectedErr: fmt.Errorf("productID is required"),
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+
+			conn, readIn := newRawMessageConn(t)
+
+			ms, err := newMatchesSubscription(context.Background(), conn, subscriptionOptions{}, ProductIDBtcUsd)
+			require.NoError(t, err, "newMatchesSubscription")
+
+			t.Cleanup(func() {
+				if err := ms.Close(context.Background()); err != nil {
+					t.Errorf("Failed to close test MatchesSubscription: %v", err)
+				}
+			})
+
+			ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second)
+			t.Cleanup(ctxCancel)
+
+			// Do
+
+			subscribeErr := make(chan error, 1)
+			go func() { subscribeErr <- ms.Subscribe(ctx, tc.giveProductIDs...) }()
+
+			if tc.expectedRequest != nil {
+				require.Eventually(t, func() bool { return len(conn.WriteJSONCalls()) == 2 }, time.Second, time.Millisecond*10, "Subscribe request written")
+
+				readIn <- tc.giveResponse
+			}
+
+			// Assert
+
+			assert.Equal(t, tc.expectedErr, <-subscribeErr, "Err")
+
+			if tc.expectedRequest != nil {
+				assert.Equal(t, *tc.expectedRequest, conn.WriteJSONCalls()[1].V, "Subscribe request")
+			}
+
+			if tc.expectedErr != nil {
+				assert.Equal(t, []ProductID{ProductIDBtcUsd}, ms.ProductIDs(), "ProductIDs")
+
 				return
 			}
+
+			assert.Equal(t, []ProductID{ProductIDBtcUsd, ProductIDEthUsd}, ms.ProductIDs(), "ProductIDs")
+			assert.Equal(
+				t,
+				[]SubscribeChannelRequest{{Name: ChannelNameMatches, ProductIDs: []ProductID{ProductIDBtcUsd, ProductIDEthUsd}}},
+				ms.Subscribed(),
+				"Subscribed",
+			)
+
+			readIn <- `{"type": "match", "product_id": "ETH-USD", "trade_id": 1}`
+
+			select {
+			case actual := <-ms.ReadProduct(ProductIDEthUsd):
+				assert.Equal(t, &MatchResponse{Match: Match{Type: MessageTypeMatch, ProductID: ProductIDEthUsd, TradeID: 1}}, actual, "Read")
+			case <-time.NewTimer(time.Millisecond * 300).C:
+				t.Fatalf("Timed out reading")
+			}
+		})
+	}
+}
+
+func TestMatchesSubscriptionSubscribeCopiesProductIDs(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	conn, readIn := newRawMessageConn(t)
+
+	// Room to append to, which the subscription mustn't write to.
+	productIDs := make([]ProductID, 1, 2)
+	productIDs[0] = ProductIDBtcUsd
+
+	ms, err := newMatchesSubscription(context.Background(), conn, subscriptionOptions{}, productIDs...)
+	require.NoError(t, err, "newMatchesSubscription")
+
+	t.Cleanup(func() {
+		if err := ms.Close(context.Background()); err != nil {
+			t.Errorf("Failed to close test MatchesSubscription: %v", err)
 		}
-	}()
+	})
+
+	ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(ctxCancel)
+
+	// Do
+
+	subscribeErr := make(chan error, 1)
+	go func() { subscribeErr <- ms.Subscribe(ctx, ProductIDEthUsd) }()
+
+	require.Eventually(t, func() bool { return len(conn.WriteJSONCalls()) == 2 }, time.Second, time.Millisecond*10, "Subscribe request written")
+
+	readIn <- `{"type": "subscriptions", "channels": [{"name": "matches", "product_ids": ["BTC-USD", "ETH-USD"]}]}`
+
+	// Assert
+
+	require.NoError(t, <-subscribeErr, "Err")
+	assert.Equal(t, []ProductID{ProductIDBtcUsd, ProductIDEthUsd}, ms.ProductIDs(), "ProductIDs")
+	assert.Equal(t, []ProductID{ProductIDBtcUsd, ProductIDUnknown}, productIDs[:2], "Caller's productIDs")
+}
+
+func TestMatchesSubscriptionUnsubscribe(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name           string
+		giveProductIDs []ProductID
+		giveResponse   string
+		expectedErr    error
+	}{
+		{
+			name:           "confirmed",
+			giveProductIDs: []ProductID{ProductIDEthUsd},
+			giveResponse:   `{"type": "subscriptions", "channels": [{"name": "matches", "product_ids": ["BTC-USD"]}]}`,
+		},
+		{
+			name:           "not_subscribed",
+			giveProductIDs: []ProductID{ProductIDEthBtc},
+			expectedErr:    fmt.Errorf("productID ETH-BTC is not subscribed"),
+		},
+		{
+			name:           "every_product",
+			giveProductIDs: []ProductID{ProductIDBtcUsd, ProductIDEthUsd},
+			expectedErr:    fmt.Errorf("unsubscribing from every product is not supported, use Close"),
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+
+			conn, readIn := newRawMessageConn(t)
+
+			ms, err := newMatchesSubscription(context.Background(), conn, subscriptionOptions{}, ProductIDBtcUsd, ProductIDEthUsd)
+			require.NoError(t, err, "newMatchesSubscription")
+
+			t.Cleanup(func() {
+				if err := ms.Close(context.Background()); err != nil {
+					t.Errorf("Failed to close test MatchesSubscription: %v", err)
+				}
+			})
+
+			readIn <- `{"type": "subscriptions", "channels": [{"name": "matches", "product_ids": ["BTC-USD", "ETH-USD"]}]}`
+
+			ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second)
+			t.Cleanup(ctxCancel)
+
+			// Do
+
+			unsubscribeErr := make(chan error, 1)
+			go func() { unsubscribeErr <- ms.Unsubscribe(ctx, tc.giveProductIDs...) }()
+
+			if tc.giveResponse != "" {
+				require.Eventually(t, func() bool { return len(conn.WriteJSONCalls()) == 2 }, time.Second, time.Millisecond*10, "Unsubscribe request written")
+
+				readIn <- tc.giveResponse
+			}
+
+			// Assert
+
+			assert.Equal(t, tc.expectedErr, <-unsubscribeErr, "Err")
+
+			if tc.expectedErr != nil {
+				return
+			}
+
+			assert.Equal(
+				t,
+				SubscribeRequest{
+					Type: "unsubscribe",
+					Channels: []SubscribeChannelRequest{
+						{Name: ChannelNameMatches, ProductIDs: []ProductID{ProductIDEthUsd}},
+						{Name: ChannelNameHeartbeat, ProductIDs: []ProductID{ProductIDEthUsd}},
+					},
+				},
+				conn.WriteJSONCalls()[1].V,
+				"Unsubscribe request",
+			)
+			assert.Equal(t, []ProductID{ProductIDBtcUsd}, ms.ProductIDs(), "ProductIDs")
+
+			// A match still in flight for the unsubscribed product isn't read.
+			readIn <- `{"type": "match", "product_id": "ETH-USD", "trade_id": 1}`
+			readIn <- `{"type": "match", "product_id": "BTC-USD", "trade_id": 2}`
+
+			select {
+			case actual := <-ms.ReadProduct(ProductIDBtcUsd):
+				assert.Equal(t, &MatchResponse{Match: Match{Type: MessageTypeMatch, ProductID: ProductIDBtcUsd, TradeID: 2}}, actual, "Read")
+			case <-time.NewTimer(time.Millisecond * 300).C:
+				t.Fatalf("Timed out reading")
+			}
+
+			assert.Empty(t, ms.ReadProduct(ProductIDEthUsd), "Read unsubscribed")
+		})
+	}
 }
@@ -0,0 +1,68 @@
+package coinbase
+
+// TickerSubscription is created by a Client to manage a subscription to the [Ticker Channel].
+// Like a MatchesSubscription, it can multiplex many products over one connection,
+// with each ticker routed to a per-product read channel.
+//
+// [Ticker Channel]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#ticker-channel
+type TickerSubscription struct {
+	subscription[*TickerResponse]
+}
+
+// newTickerSubscription creates a new TickerSubscription. It will first subscribe
+// to the Ticker and Heartbeat Channels for all productIDs over conn, in a single
+// request. If this is successful, the read loop is started.
+func newTickerSubscription(conn Conn, options subscriptionOptions, productIDs ...ProductID) (*TickerSubscription, error) {
+	f, err := newFeed(
+		conn,
+		feedKind{
+			channels:           []ChannelName{ChannelNameTicker},
+			channelDescription: "Ticker",
+			messageDescription: "ticker",
+			decoders:           defaultDecoders,
+		},
+		options,
+		productIDs,
+		func(err error) *TickerResponse { return &TickerResponse{Err: err} },
+		func(notification Notification) *TickerResponse { return &TickerResponse{Notification: notification} },
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &TickerSubscription{subscription: subscription[*TickerResponse]{feed: f}}
+
+	f.handle = s.handleMessage
+	f.start()
+
+	return s, nil
+}
+
+// ReadProduct can be used to read from this subscription for productID. Only
+// messages of type "ticker" or "error" are read. Errors, reconnect events and
+// staleness are read as they are for MatchesSubscription.ReadProduct. Tickers
+// aren't sent for every sequence, so gaps aren't reported. The channel is closed
+// when the subscription is done.
+//
+// Returns nil if the subscription is not for productID.
+func (s *TickerSubscription) ReadProduct(productID ProductID) <-chan *TickerResponse {
+	return s.feed.readProduct(productID)
+}
+
+// handleMessage pushes a message of type "ticker" to the relevant read channel.
+func (s *TickerSubscription) handleMessage(_ messageHeader, message Message) bool {
+	ticker, ok := message.(*Ticker)
+	if !ok {
+		return false
+	}
+
+	read, ok := s.feed.readFor(ticker.Type, ticker.ProductID)
+	if !ok {
+		return true
+	}
+
+	s.feed.seen(ticker.ProductID)
+	s.feed.push(read, &TickerResponse{Ticker: *ticker})
+
+	return true
+}
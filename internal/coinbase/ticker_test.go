@@ -0,0 +1,88 @@
+package coinbase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+func TestTickerSubscriptionReadProduct(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	conn, readIn := newRawMessageConn(t)
+
+	ts, err := newTickerSubscription(conn, subscriptionOptions{}, ProductIDBtcUsd, ProductIDEthUsd)
+	require.NoError(t, err, "newTickerSubscription")
+
+	t.Cleanup(func() {
+		if err := ts.Close(context.Background()); err != nil {
+			t.Errorf("Failed to close test TickerSubscription: %v", err)
+		}
+	})
+
+	// Do
+
+	readIn <- `{"type": "subscriptions", "channels": []}`
+	readIn <- `{"type": "ticker", "product_id": "ETH-USD", "best_bid": "1.5", "best_ask": "2"}`
+	readIn <- `{"type": "heartbeat", "product_id": "BTC-USD"}`
+	readIn <- `{"type": "ticker", "product_id": "BTC-USD", "price": "10"}`
+	readIn <- `{"type": "ticker", "product_id": "ETH-BTC"}`
+	readIn <- `{"type": "match", "product_id": "BTC-USD"}`
+
+	// Assert
+
+	if assert.Len(t, conn.WriteJSONCalls(), 1, "WriteJSON calls") {
+		assert.Equal(
+			t,
+			SubscribeRequest{
+				Type: "subscribe",
+				Channels: []SubscribeChannelRequest{
+					{Name: ChannelNameTicker, ProductIDs: []ProductID{ProductIDBtcUsd, ProductIDEthUsd}},
+					{Name: ChannelNameHeartbeat, ProductIDs: []ProductID{ProductIDBtcUsd, ProductIDEthUsd}},
+				},
+			},
+			conn.WriteJSONCalls()[0].V,
+			"Subscribe request",
+		)
+	}
+
+	for _, tc := range []struct {
+		productID ProductID
+		expected  []*TickerResponse
+	}{
+		{
+			productID: ProductIDBtcUsd,
+			expected: []*TickerResponse{
+				{Ticker: Ticker{Type: MessageTypeTicker, ProductID: ProductIDBtcUsd, Price: decimal.MustParse("10")}},
+				{Err: &ProtocolError{MessageType: MessageTypeTicker, UnsubscribedProductID: ProductIDEthBtc}},
+				{Err: &ProtocolError{MessageType: MessageTypeMatch}},
+			},
+		},
+		{
+			productID: ProductIDEthUsd,
+			expected: []*TickerResponse{
+				{Ticker: Ticker{Type: MessageTypeTicker, ProductID: ProductIDEthUsd, BestBid: decimal.MustParse("1.5"), BestAsk: decimal.MustParse("2")}},
+				{Err: &ProtocolError{MessageType: MessageTypeTicker, UnsubscribedProductID: ProductIDEthBtc}},
+				{Err: &ProtocolError{MessageType: MessageTypeMatch}},
+			},
+		},
+	} {
+		read := ts.ReadProduct(tc.productID)
+
+		for _, expected := range tc.expected {
+			select {
+			case actual := <-read:
+				assert.Equal(t, expected, actual, "Actual for %s", tc.productID)
+			case <-time.NewTimer(time.Millisecond * 300).C:
+				t.Fatalf("Timed out reading for %s", tc.productID)
+			}
+		}
+	}
+}
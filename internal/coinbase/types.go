@@ -1,8 +1,12 @@
 package coinbase
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
+	"time"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
 )
 
 // ProductID is a Coinbase [Product ID].
@@ -23,9 +27,19 @@ type MessageType string
 const (
 	MessageTypeUnknown       MessageType = ""
 	MessageTypeError         MessageType = "error"
+	MessageTypeHeartbeat     MessageType = "heartbeat"
 	MessageTypeLastMatch     MessageType = "last_match"
 	MessageTypeMatch         MessageType = "match"
 	MessageTypeSubscriptions MessageType = "subscriptions"
+	MessageTypeTicker        MessageType = "ticker"
+	MessageTypeSnapshot      MessageType = "snapshot"
+	MessageTypeL2Update      MessageType = "l2update"
+	MessageTypeReceived      MessageType = "received"
+	MessageTypeOpen          MessageType = "open"
+	MessageTypeDone          MessageType = "done"
+	MessageTypeChange        MessageType = "change"
+	MessageTypeActivate      MessageType = "activate"
+	MessageTypeStatus        MessageType = "status"
 )
 
 // channelName is a Coinbase [Channel name].
@@ -34,7 +48,35 @@ const (
 type ChannelName string
 
 const (
-	ChannelNameMatches ChannelName = "matches"
+	ChannelNameHeartbeat ChannelName = "heartbeat"
+	ChannelNameMatches   ChannelName = "matches"
+	ChannelNameTicker    ChannelName = "ticker"
+
+	// Level2 requires authentication (see Client.Credentials), Level2Batch doesn't.
+	ChannelNameLevel2      ChannelName = "level2"
+	ChannelNameLevel2Batch ChannelName = "level2_batch"
+
+	ChannelNameFull ChannelName = "full"
+
+	// Status isn't for any product, it's subscribed to without product IDs.
+	ChannelNameStatus ChannelName = "status"
+)
+
+// isPerProduct returns true if the channel is subscribed to for products.
+func (c ChannelName) isPerProduct() bool {
+	return c != ChannelNameStatus
+}
+
+// Side is the side of a Coinbase order. For a [Match], this is the side of the
+// maker order.
+//
+// [Match]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#match
+type Side string
+
+const (
+	SideUnknown Side = ""
+	SideBuy     Side = "buy"
+	SideSell    Side = "sell"
 )
 
 // SubscribeRequest can be used to [Subscribe to Coinbase Channels].
@@ -44,6 +86,12 @@ type SubscribeRequest struct {
 	Type       string                    `json:"type"`
 	ProductIDs []ProductID               `json:"product_ids"`
 	Channels   []SubscribeChannelRequest `json:"channels"`
+
+	// Authentication, see Credentials.SignSubscribeRequest.
+	Signature  string `json:"signature,omitempty"`
+	Key        string `json:"key,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+	Timestamp  string `json:"timestamp,omitempty"`
 }
 
 // SubscribeChannelRequest nests under SubscribeRequest.
@@ -56,11 +104,350 @@ type SubscribeChannelRequest struct {
 //
 // [Coinbase Match]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#match
 type Match struct {
+	Type         MessageType `json:"type"`
+	TradeID      int64       `json:"trade_id"`
+	Sequence     int64       `json:"sequence"`
+	MakerOrderID string      `json:"maker_order_id"`
+	TakerOrderID string      `json:"taker_order_id"`
+	Time         time.Time   `json:"time"`
+	ProductID    ProductID   `json:"product_id"`
+	Size         string      `json:"size"`
+	Price        string      `json:"price"`
+	Side         Side        `json:"side"`
+	Message      string      `json:"message"`
+}
+
+// Heartbeat is a [Coinbase Heartbeat].
+//
+// [Coinbase Heartbeat]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#heartbeat-channel
+type Heartbeat struct {
+	Type        MessageType `json:"type"`
+	Sequence    int64       `json:"sequence"`
+	LastTradeID int64       `json:"last_trade_id"`
+	ProductID   ProductID   `json:"product_id"`
+	Time        time.Time   `json:"time"`
+}
+
+// Ticker is a Coinbase [Ticker], sent for every match. Prices and sizes are parsed
+// from their string encoding as exact decimals.
+//
+// [Ticker]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#ticker-channel
+type Ticker struct {
 	Type      MessageType `json:"type"`
+	Sequence  int64       `json:"sequence"`
 	ProductID ProductID   `json:"product_id"`
-	Size      string      `json:"size"`
+	TradeID   int64       `json:"trade_id"`
+	Time      time.Time   `json:"time"`
+
+	// The side of the taker order (unlike Match.Side).
+	Side Side `json:"side"`
+
+	// The price and size of the last match.
+	Price    decimal.Decimal `json:"price"`
+	LastSize decimal.Decimal `json:"last_size"`
+
+	BestBid     decimal.Decimal `json:"best_bid"`
+	BestBidSize decimal.Decimal `json:"best_bid_size"`
+	BestAsk     decimal.Decimal `json:"best_ask"`
+	BestAskSize decimal.Decimal `json:"best_ask_size"`
+
+	Open24h   decimal.Decimal `json:"open_24h"`
+	High24h   decimal.Decimal `json:"high_24h"`
+	Low24h    decimal.Decimal `json:"low_24h"`
+	Volume24h decimal.Decimal `json:"volume_24h"`
+	Volume30d decimal.Decimal `json:"volume_30d"`
+}
+
+// Spread returns the difference between the best ask and best bid, rounded (half
+// to even) to a multiple of increment (e.g. the product's quote increment).
+func (t Ticker) Spread(increment decimal.Decimal) decimal.Decimal {
+	return t.BestAsk.Sub(t.BestBid).Quantize(increment)
+}
+
+// TickerResponse represents a Coinbase [Ticker] Message returned over the websocket.
+//
+// [Ticker]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#ticker-channel
+type TickerResponse struct {
+	// Ticker is populated if a valid Ticker message is received.
+	Ticker Ticker
+
+	// Err is populated in the event Ticker is not.
+	Err error
+
+	// Notification is populated for non-fatal events (e.g. reconnecting), in which
+	// case neither Ticker nor Err are.
+	Notification Notification
+}
+
+// isMessage returns true if the response is a message, see response.
+func (r *TickerResponse) isMessage() bool {
+	return r.Err == nil && r.Notification == nil
+}
+
+// PriceLevel is a price and the total size of orders at it. It's decoded from a
+// JSON array of strings, e.g. ["10101.10", "0.45054140"], as exact decimals.
+type PriceLevel struct {
+	Price decimal.Decimal
+	Size  decimal.Decimal
+}
+
+// UnmarshalJSON decodes the PriceLevel from a JSON array.
+func (p *PriceLevel) UnmarshalJSON(b []byte) error {
+	fields, err := unmarshalStrings(b, 2)
+	if err != nil {
+		return fmt.Errorf("price level: %w", err)
+	}
+
+	if p.Price, err = decimal.Parse(fields[0]); err != nil {
+		return fmt.Errorf("price level: parse price: %w", err)
+	}
+
+	if p.Size, err = decimal.Parse(fields[1]); err != nil {
+		return fmt.Errorf("price level: parse size: %w", err)
+	}
+
+	return nil
+}
+
+// Level2Change is a change to a PriceLevel of a side of the book. It's decoded
+// from a JSON array of strings, e.g. ["buy", "10101.80000000", "0.162567"]. A Size
+// of 0 removes the level.
+type Level2Change struct {
+	Side Side
+	PriceLevel
+}
+
+// UnmarshalJSON decodes the Level2Change from a JSON array.
+func (l *Level2Change) UnmarshalJSON(b []byte) error {
+	fields, err := unmarshalStrings(b, 3)
+	if err != nil {
+		return fmt.Errorf("level2 change: %w", err)
+	}
+
+	l.Side = Side(fields[0])
+	if l.Side != SideBuy && l.Side != SideSell {
+		return fmt.Errorf("level2 change: unknown side %q", l.Side)
+	}
+
+	if l.Price, err = decimal.Parse(fields[1]); err != nil {
+		return fmt.Errorf("level2 change: parse price: %w", err)
+	}
+
+	if l.Size, err = decimal.Parse(fields[2]); err != nil {
+		return fmt.Errorf("level2 change: parse size: %w", err)
+	}
+
+	return nil
+}
+
+// unmarshalStrings decodes b as a JSON array of at least n strings.
+func unmarshalStrings(b []byte, n int) ([]string, error) {
+	var fields []string
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+
+	if len(fields) < n {
+		return nil, fmt.Errorf("expected %d fields, got %d", n, len(fields))
+	}
+
+	return fields, nil
+}
+
+// Level2Snapshot is a Coinbase level2 [Snapshot] of the whole book.
+//
+// [Snapshot]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#level2-channel
+type Level2Snapshot struct {
+	Type      MessageType  `json:"type"`
+	ProductID ProductID    `json:"product_id"`
+	Bids      []PriceLevel `json:"bids"`
+	Asks      []PriceLevel `json:"asks"`
+}
+
+// Level2Update is a Coinbase level2 [Update] of changes to the book since the last.
+//
+// [Update]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#level2-channel
+type Level2Update struct {
+	Type      MessageType    `json:"type"`
+	ProductID ProductID      `json:"product_id"`
+	Time      time.Time      `json:"time"`
+	Changes   []Level2Change `json:"changes"`
+}
+
+// Level2Response represents a Coinbase level2 message returned over the websocket.
+type Level2Response struct {
+	// Snapshot is populated if a valid snapshot message is received.
+	Snapshot *Level2Snapshot
+
+	// Update is populated if a valid l2update message is received.
+	Update *Level2Update
+
+	// Err is populated in the event neither Snapshot nor Update are.
+	Err error
+
+	// Notification is populated for non-fatal events (e.g. reconnecting), in which
+	// case no other field is.
+	Notification Notification
+}
+
+// isMessage returns true if the response is a message, see response.
+func (r *Level2Response) isMessage() bool {
+	return r.Err == nil && r.Notification == nil
+}
+
+// FullMessage is a message of the Coinbase [Full Channel]. Which fields are populated
+// depends on Type (one of "received", "open", "done", "match", "change" or "activate").
+// Numeric fields are left as strings, as for Match.
+//
+// [Full Channel]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#full-channel
+type FullMessage struct {
+	Type      MessageType `json:"type"`
+	Time      time.Time   `json:"time"`
+	ProductID ProductID   `json:"product_id"`
+	Sequence  int64       `json:"sequence"`
+	OrderID   string      `json:"order_id"`
+	Side      Side        `json:"side"`
 	Price     string      `json:"price"`
-	Message   string      `json:"message"`
+
+	// Populated for "received" and "match" messages.
+	Size string `json:"size"`
+
+	// Populated for "open" and "done" messages.
+	RemainingSize string `json:"remaining_size"`
+
+	// Populated for "received" messages.
+	OrderType string `json:"order_type"`
+	ClientOID string `json:"client_oid"`
+	Funds     string `json:"funds"`
+
+	// Populated for "done" messages, either "filled" or "canceled".
+	Reason string `json:"reason"`
+
+	// Populated for "change" messages.
+	NewSize  string `json:"new_size"`
+	OldSize  string `json:"old_size"`
+	NewFunds string `json:"new_funds"`
+	OldFunds string `json:"old_funds"`
+
+	// Populated for "match" messages.
+	TradeID      int64  `json:"trade_id"`
+	MakerOrderID string `json:"maker_order_id"`
+	TakerOrderID string `json:"taker_order_id"`
+}
+
+// ToMatch converts a "match" FullMessage to a Match.
+func (f *FullMessage) ToMatch() Match {
+	return Match{
+		Type:         MessageTypeMatch,
+		TradeID:      f.TradeID,
+		Sequence:     f.Sequence,
+		MakerOrderID: f.MakerOrderID,
+		TakerOrderID: f.TakerOrderID,
+		Time:         f.Time,
+		ProductID:    f.ProductID,
+		Size:         f.Size,
+		Price:        f.Price,
+		Side:         f.Side,
+	}
+}
+
+// FullResponse represents a Coinbase [Full Channel] message returned over the websocket.
+//
+// [Full Channel]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#full-channel
+type FullResponse struct {
+	// Message is populated if a valid message is received.
+	Message FullMessage
+
+	// Err is populated in the event Message is not.
+	Err error
+
+	// Notification is populated for non-fatal events (e.g. reconnecting), in which
+	// case neither Message nor Err are.
+	Notification Notification
+}
+
+// isMessage returns true if the response is a message, see response.
+func (r *FullResponse) isMessage() bool {
+	return r.Err == nil && r.Notification == nil
+}
+
+// MessageResponse represents any Coinbase message returned over the websocket, see
+// MessageSubscription.
+type MessageResponse struct {
+	// Message is populated if a valid message is received. Use a type switch to
+	// determine which it is.
+	Message Message
+
+	// Err is populated in the event Message is not.
+	Err error
+
+	// Notification is populated for non-fatal events (e.g. reconnecting), in which
+	// case neither Message nor Err are.
+	Notification Notification
+}
+
+// isMessage returns true if the response is a message, see response.
+func (r *MessageResponse) isMessage() bool {
+	return r.Err == nil && r.Notification == nil
+}
+
+// ErrorMessage is a Coinbase message of type "error".
+type ErrorMessage struct {
+	Type    MessageType `json:"type"`
+	Message string      `json:"message"`
+	Reason  string      `json:"reason"`
+}
+
+// SubscriptionsMessage is a Coinbase message of type "subscriptions", sent in
+// response to every subscribe or unsubscribe request. It lists all channels (and
+// products) currently subscribed to over the connection.
+type SubscriptionsMessage struct {
+	Type     MessageType               `json:"type"`
+	Channels []SubscribeChannelRequest `json:"channels"`
+}
+
+// Status is a message of the Coinbase [Status Channel], describing all products
+// and currencies. Numeric fields are left in their string encoding.
+//
+// [Status Channel]: https://docs.cloud.coinbase.com/exchange/docs/websocket-channels#status-channel
+type Status struct {
+	Type       MessageType      `json:"type"`
+	Products   []StatusProduct  `json:"products"`
+	Currencies []StatusCurrency `json:"currencies"`
+}
+
+// StatusProduct nests under Status.
+type StatusProduct struct {
+	ID             ProductID `json:"id"`
+	BaseCurrency   string    `json:"base_currency"`
+	QuoteCurrency  string    `json:"quote_currency"`
+	BaseIncrement  string    `json:"base_increment"`
+	QuoteIncrement string    `json:"quote_increment"`
+	DisplayName    string    `json:"display_name"`
+	Status         string    `json:"status"`
+	StatusMessage  string    `json:"status_message"`
+	MinMarketFunds string    `json:"min_market_funds"`
+	PostOnly       bool      `json:"post_only"`
+	LimitOnly      bool      `json:"limit_only"`
+	CancelOnly     bool      `json:"cancel_only"`
+}
+
+// StatusCurrency nests under Status.
+type StatusCurrency struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	MinSize       string   `json:"min_size"`
+	Status        string   `json:"status"`
+	StatusMessage string   `json:"status_message"`
+	MaxPrecision  string   `json:"max_precision"`
+	ConvertibleTo []string `json:"convertible_to"`
+}
+
+// messageHeader holds the fields common to all Coinbase messages, used to
+// determine how a message should be decoded and which product it's for (if any).
+type messageHeader struct {
+	Type      MessageType `json:"type"`
+	ProductID ProductID   `json:"product_id"`
 }
 
 // MatchResponse represents a Coinbase [Match] Message returned over the websocket.
@@ -72,6 +459,19 @@ type MatchResponse struct {
 
 	// Err is populated in the event Match is not.
 	Err error
+
+	// Notification is populated for non-fatal events (e.g. reconnecting), in which
+	// case neither Match nor Err are.
+	Notification Notification
+
+	// Backfilled is true if Match was fetched to fill a gap, rather than received
+	// over the websocket.
+	Backfilled bool
+}
+
+// isMessage returns true if the response is a message, see response.
+func (m *MatchResponse) isMessage() bool {
+	return m.Err == nil && m.Notification == nil
 }
 
 // ToUnitsAndUnitPrice parses units & price from the MatchResponse.
@@ -80,6 +480,10 @@ func (m *MatchResponse) ToUnitsAndUnitPrice() (units, unitPrice float64, err err
 		return 0, 0, fmt.Errorf("match response: %w", m.Err)
 	}
 
+	if m.Notification != nil {
+		return 0, 0, fmt.Errorf("match response is a notification: %v", m.Notification)
+	}
+
 	units, err = strconv.ParseFloat(m.Match.Size, 64)
 	if err != nil {
 		return 0, 0, fmt.Errorf("parse units from size: %w", err)
@@ -92,3 +496,74 @@ func (m *MatchResponse) ToUnitsAndUnitPrice() (units, unitPrice float64, err err
 
 	return
 }
+
+// ToDecimalUnitsAndUnitPrice parses units & price from the MatchResponse exactly,
+// see ToUnitsAndUnitPrice.
+func (m *MatchResponse) ToDecimalUnitsAndUnitPrice() (units, unitPrice decimal.Decimal, err error) {
+	if m.Err != nil {
+		return decimal.Decimal{}, decimal.Decimal{}, fmt.Errorf("match response: %w", m.Err)
+	}
+
+	if m.Notification != nil {
+		return decimal.Decimal{}, decimal.Decimal{}, fmt.Errorf("match response is a notification: %v", m.Notification)
+	}
+
+	units, err = decimal.Parse(m.Match.Size)
+	if err != nil {
+		return decimal.Decimal{}, decimal.Decimal{}, fmt.Errorf("parse units from size: %w", err)
+	}
+
+	unitPrice, err = decimal.Parse(m.Match.Price)
+	if err != nil {
+		return decimal.Decimal{}, decimal.Decimal{}, fmt.Errorf("parse unitPrice from price: %w", err)
+	}
+
+	return
+}
+
+// Trade is a Match with all fields parsed.
+type Trade struct {
+	ProductID    ProductID
+	TradeID      int64
+	Sequence     int64
+	MakerOrderID string
+	TakerOrderID string
+	Time         time.Time
+
+	// The side of the maker order.
+	Side Side
+
+	// The number of units traded (parsed from size).
+	Units float64
+
+	// The price per unit (parsed from price).
+	UnitPrice float64
+}
+
+// ToTrade parses a Trade from the MatchResponse.
+func (m *MatchResponse) ToTrade() (Trade, error) {
+	units, unitPrice, err := m.ToUnitsAndUnitPrice()
+	if err != nil {
+		return Trade{}, err
+	}
+
+	if m.Match.Side != SideBuy && m.Match.Side != SideSell {
+		return Trade{}, fmt.Errorf("parse side: unknown side %q", m.Match.Side)
+	}
+
+	if m.Match.Time.IsZero() {
+		return Trade{}, fmt.Errorf("parse time: time is missing")
+	}
+
+	return Trade{
+		ProductID:    m.Match.ProductID,
+		TradeID:      m.Match.TradeID,
+		Sequence:     m.Match.Sequence,
+		MakerOrderID: m.Match.MakerOrderID,
+		TakerOrderID: m.Match.TakerOrderID,
+		Time:         m.Match.Time,
+		Side:         m.Match.Side,
+		Units:        units,
+		UnitPrice:    unitPrice,
+	}, nil
+}
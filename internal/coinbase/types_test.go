@@ -1,10 +1,15 @@
 package coinbase
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
 )
 
 func TestMatchResponseToUnitsAndUnitPrice(t *testing.T) {
@@ -28,6 +33,11 @@ func TestMatchResponseToUnitsAndUnitPrice(t *testing.T) {
 			with:        &MatchResponse{Err: fmt.Errorf("TestABC")},
 			expectedErr: "match response: TestABC",
 		},
+		{
+			name:        "notification",
+			with:        &MatchResponse{Notification: &ReconnectedNotification{Attempts: 2}},
+			expectedErr: "match response is a notification: reconnected after 2 attempt(s)",
+		},
 		{
 			name:        "invalid_size",
 			with:        &MatchResponse{Match: Match{Size: "abc", Price: "10.1"}},
@@ -62,3 +72,315 @@ func TestMatchResponseToUnitsAndUnitPrice(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchResponseToDecimalUnitsAndUnitPrice(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name              string
+		with              *MatchResponse
+		expectedUnits     string
+		expectedUnitPrice string
+		expectedErr       string
+	}{
+		{
+			name:              "match",
+			with:              &MatchResponse{Match: Match{Size: "0.05088265", Price: "295.96"}},
+			expectedUnits:     "0.05088265",
+			expectedUnitPrice: "295.96",
+		},
+		{
+			name:              "err",
+			with:              &MatchResponse{Err: fmt.Errorf("TestABC")},
+			expectedUnits:     "0",
+			expectedUnitPrice: "0",
+			expectedErr:       "match response: TestABC",
+		},
+		{
+			name:              "invalid_size",
+			with:              &MatchResponse{Match: Match{Size: "abc", Price: "10.1"}},
+			expectedUnits:     "0",
+			expectedUnitPrice: "0",
+			expectedErr:       "parse units from size: parse decimal \"abc\": invalid syntax",
+		},
+		{
+			name:              "invalid_price",
+			with:              &MatchResponse{Match: Match{Size: "5.5", Price: "abc"}},
+			expectedUnits:     "0",
+			expectedUnitPrice: "0",
+			expectedErr:       "parse unitPrice from price: parse decimal \"abc\": invalid syntax",
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actualUnits, actualUnitPrice, err := tc.with.ToDecimalUnitsAndUnitPrice()
+
+			assert.Equal(t, tc.expectedUnits, actualUnits.String(), "Units")
+			assert.Equal(t, tc.expectedUnitPrice, actualUnitPrice.String(), "Unit price")
+
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestMatchUnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	var actual Match
+	err := json.Unmarshal([]byte(`{
+		"type": "match",
+		"trade_id": 10,
+		"sequence": 50,
+		"maker_order_id": "ac928c66-ca53-498f-9c13-a110027a60e8",
+		"taker_order_id": "132fb6ae-456b-4654-b4e0-d681ac05cea1",
+		"time": "2014-11-07T08:19:27.028459Z",
+		"product_id": "BTC-USD",
+		"size": "5.23512",
+		"price": "400.23",
+		"side": "sell"
+	}`), &actual)
+
+	require.NoError(t, err, "Unmarshal")
+	assert.Equal(
+		t,
+		Match{
+			Type:         MessageTypeMatch,
+			TradeID:      10,
+			Sequence:     50,
+			MakerOrderID: "ac928c66-ca53-498f-9c13-a110027a60e8",
+			TakerOrderID: "132fb6ae-456b-4654-b4e0-d681ac05cea1",
+			Time:         time.Date(2014, 11, 7, 8, 19, 27, 28459000, time.UTC),
+			ProductID:    ProductIDBtcUsd,
+			Size:         "5.23512",
+			Price:        "400.23",
+			Side:         SideSell,
+		},
+		actual,
+	)
+}
+
+func TestTickerUnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	var actual Ticker
+	err := json.Unmarshal([]byte(`{
+		"type": "ticker",
+		"sequence": 37475248783,
+		"product_id": "ETH-USD",
+		"price": "1285.22",
+		"open_24h": "1310.79",
+		"volume_24h": "245532.79269678",
+		"low_24h": "1280.52",
+		"high_24h": "1313.8",
+		"volume_30d": "9788783.60117027",
+		"best_bid": "1285.04",
+		"best_bid_size": "0.46688654",
+		"best_ask": "1285.27",
+		"best_ask_size": "1.56637040",
+		"side": "buy",
+		"time": "2022-10-19T23:28:22.061769Z",
+		"trade_id": 370843401,
+		"last_size": "11.4396987"
+	}`), &actual)
+
+	require.NoError(t, err, "Unmarshal")
+	assert.Equal(
+		t,
+		Ticker{
+			Type:        MessageTypeTicker,
+			Sequence:    37475248783,
+			ProductID:   ProductIDEthUsd,
+			TradeID:     370843401,
+			Time:        time.Date(2022, 10, 19, 23, 28, 22, 61769000, time.UTC),
+			Side:        SideBuy,
+			Price:       decimal.MustParse("1285.22"),
+			LastSize:    decimal.MustParse("11.4396987"),
+			BestBid:     decimal.MustParse("1285.04"),
+			BestBidSize: decimal.MustParse("0.46688654"),
+			BestAsk:     decimal.MustParse("1285.27"),
+			BestAskSize: decimal.MustParse("1.56637040"),
+			Open24h:     decimal.MustParse("1310.79"),
+			High24h:     decimal.MustParse("1313.8"),
+			Low24h:      decimal.MustParse("1280.52"),
+			Volume24h:   decimal.MustParse("245532.79269678"),
+			Volume30d:   decimal.MustParse("9788783.60117027"),
+		},
+		actual,
+	)
+	assert.Equal(t, "0.23", actual.Spread(decimal.MustParse("0.01")).String(), "Spread")
+}
+
+func TestLevel2UnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	t.Run("snapshot", func(t *testing.T) {
+		t.Parallel()
+
+		var actual Level2Snapshot
+		err := json.Unmarshal([]byte(`{
+			"type": "snapshot",
+			"product_id": "BTC-USD",
+			"bids": [["10101.10", "0.45054140"]],
+			"asks": [["10102.55", "0.57753524"], ["10103.00", "1"]]
+		}`), &actual)
+
+		require.NoError(t, err, "Unmarshal")
+		assert.Equal(
+			t,
+			Level2Snapshot{
+				Type:      MessageTypeSnapshot,
+				ProductID: ProductIDBtcUsd,
+				Bids:      []PriceLevel{{Price: decimal.MustParse("10101.10"), Size: decimal.MustParse("0.45054140")}},
+				Asks: []PriceLevel{
+					{Price: decimal.MustParse("10102.55"), Size: decimal.MustParse("0.57753524")},
+					{Price: decimal.MustParse("10103.00"), Size: decimal.MustParse("1")},
+				},
+			},
+			actual,
+		)
+	})
+
+	t.Run("l2update", func(t *testing.T) {
+		t.Parallel()
+
+		var actual Level2Update
+		err := json.Unmarshal([]byte(`{
+			"type": "l2update",
+			"product_id": "BTC-USD",
+			"time": "2019-08-14T20:42:27.265Z",
+			"changes": [["buy", "10101.80000000", "0.162567"], ["sell", "10102.55", "0"]]
+		}`), &actual)
+
+		require.NoError(t, err, "Unmarshal")
+		assert.Equal(
+			t,
+			Level2Update{
+				Type:      MessageTypeL2Update,
+				ProductID: ProductIDBtcUsd,
+				Time:      time.Date(2019, 8, 14, 20, 42, 27, 265000000, time.UTC),
+				Changes: []Level2Change{
+					{Side: SideBuy, PriceLevel: PriceLevel{Price: decimal.MustParse("10101.80000000"), Size: decimal.MustParse("0.162567")}},
+					{Side: SideSell, PriceLevel: PriceLevel{Price: decimal.MustParse("10102.55"), Size: decimal.MustParse("0")}},
+				},
+			},
+			actual,
+		)
+	})
+
+	for _, tc := range []struct {
+		name        string
+		give        string
+		expectedErr string
+	}{
+		{
+			name:        "price_level_too_short",
+			give:        `{"bids": [["1"]]}`,
+			expectedErr: "price level: expected 2 fields, got 1",
+		},
+		{
+			name:        "price_level_invalid_size",
+			give:        `{"asks": [["1", "abc"]]}`,
+			expectedErr: "price level: parse size: parse decimal \"abc\": invalid syntax",
+		},
+		{
+			name:        "change_unknown_side",
+			give:        `{"changes": [["TestABC", "1", "1"]]}`,
+			expectedErr: "level2 change: unknown side \"TestABC\"",
+		},
+		{
+			name:        "change_invalid_price",
+			give:        `{"changes": [["buy", "abc", "1"]]}`,
+			expectedErr: "level2 change: parse price: parse decimal \"abc\": invalid syntax",
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var actual struct {
+				Level2Snapshot
+				Changes []Level2Change `json:"changes"`
+			}
+
+			assert.EqualError(t, json.Unmarshal([]byte(tc.give), &actual), tc.expectedErr, "Unmarshal err")
+		})
+	}
+}
+
+func TestMatchResponseToTrade(t *testing.T) {
+	t.Parallel()
+
+	matchTime := time.Date(2014, 11, 7, 8, 19, 27, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name        string
+		with        *MatchResponse
+		expected    Trade
+		expectedErr string
+	}{
+		{
+			name: "match",
+			with: &MatchResponse{Match: Match{
+				TradeID:      10,
+				Sequence:     50,
+				MakerOrderID: "maker",
+				TakerOrderID: "taker",
+				Time:         matchTime,
+				ProductID:    ProductIDBtcUsd,
+				Size:         "5.5",
+				Price:        "10.1",
+				Side:         SideBuy,
+			}},
+			expected: Trade{
+				ProductID:    ProductIDBtcUsd,
+				TradeID:      10,
+				Sequence:     50,
+				MakerOrderID: "maker",
+				TakerOrderID: "taker",
+				Time:         matchTime,
+				Side:         SideBuy,
+				Units:        5.5,
+				UnitPrice:    10.1,
+			},
+		},
+		{
+			name:        "err",
+			with:        &MatchResponse{Err: fmt.Errorf("TestABC")},
+			expectedErr: "match response: TestABC",
+		},
+		{
+			name:        "invalid_side",
+			with:        &MatchResponse{Match: Match{Size: "5.5", Price: "10.1", Side: "abc", Time: matchTime}},
+			expectedErr: "parse side: unknown side \"abc\"",
+		},
+		{
+			name:        "missing_time",
+			with:        &MatchResponse{Match: Match{Size: "5.5", Price: "10.1", Side: SideSell}},
+			expectedErr: "parse time: time is missing",
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := tc.with.ToTrade()
+
+			assert.Equal(t, tc.expected, actual, "Trade")
+
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
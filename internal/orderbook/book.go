@@ -0,0 +1,294 @@
+// package orderbook provides a price level order book, e.g. maintained from the
+// Coinbase level2 channel.
+package orderbook
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+// Side is a side of the Book.
+type Side string
+
+const (
+	SideBid Side = "bid"
+	SideAsk Side = "ask"
+)
+
+// fillPrecision is the number of decimal places beyond those of the prices filled
+// at the average price of a fill is calculated to.
+const fillPrecision = 8
+
+// Level is a price level of the Book. Prices are compared exactly, so "1.50" and
+// "1.5" are the same level.
+type Level struct {
+	Price decimal.Decimal
+
+	// The total size of all orders at the price.
+	Size decimal.Decimal
+}
+
+// Book is an order book of price levels, for a single product. It is not safe for
+// concurrent use.
+//
+// The zero-value of this type is an empty, unsynced book.
+type Book struct {
+	// Sorted best (highest) first.
+	bids []Level
+
+	// Sorted best (lowest) first.
+	asks []Level
+
+	// True once a snapshot has been applied, until the book is invalidated.
+	synced bool
+}
+
+// New creates a new, empty Book.
+func New() *Book {
+	return &Book{}
+}
+
+// Reset replaces all levels of the book with bids and asks (in any order) e.g.
+// from a snapshot. Levels with no size are ignored. The book is then synced.
+func (b *Book) Reset(bids, asks []Level) {
+	b.bids = sortedLevels(SideBid, bids)
+	b.asks = sortedLevels(SideAsk, asks)
+	b.synced = true
+}
+
+// Invalidate marks the book as no longer synced, e.g. because updates may have
+// been missed. It remains unsynced until the next Reset.
+func (b *Book) Invalidate() {
+	b.synced = false
+}
+
+// Synced returns true if the book has been Reset, and not since invalidated.
+func (b *Book) Synced() bool {
+	return b.synced
+}
+
+// Set sets the size of the level at price on side. A size of 0 removes the level.
+func (b *Book) Set(side Side, price, size decimal.Decimal) error {
+	levels, err := b.levels(side)
+	if err != nil {
+		return err
+	}
+
+	a := search(side, *levels, price)
+
+	switch {
+	case a < len(*levels) && (*levels)[a].Price.Cmp(price) == 0 && size.IsZero():
+		*levels = append((*levels)[:a], (*levels)[a+1:]...)
+	case a < len(*levels) && (*levels)[a].Price.Cmp(price) == 0:
+		(*levels)[a].Size = size
+	case !size.IsZero():
+		*levels = append(*levels, Level{})
+		copy((*levels)[a+1:], (*levels)[a:])
+		(*levels)[a] = Level{Price: price, Size: size}
+	}
+
+	return nil
+}
+
+// Best returns the best level of side, or false if side has no levels.
+func (b *Book) Best(side Side) (Level, bool) {
+	levels, err := b.levels(side)
+	if err != nil || len(*levels) == 0 {
+		return Level{}, false
+	}
+
+	return (*levels)[0], true
+}
+
+// BestBid returns the highest bid, or false if there are no bids.
+func (b *Book) BestBid() (Level, bool) {
+	return b.Best(SideBid)
+}
+
+// BestAsk returns the lowest ask, or false if there are no asks.
+func (b *Book) BestAsk() (Level, bool) {
+	return b.Best(SideAsk)
+}
+
+// Mid returns the price halfway between the best bid and ask, exactly (i.e. to a
+// decimal place more than the prices), or false if either side has no levels.
+func (b *Book) Mid() (decimal.Decimal, bool) {
+	bid, ask, ok := b.bestBidAndAsk()
+	if !ok {
+		return decimal.Decimal{}, false
+	}
+
+	return bid.Price.Add(ask.Price).Mul(decimal.New(5, 1)), true
+}
+
+// Spread returns the difference between the best ask and bid, or false if either
+// side has no levels.
+func (b *Book) Spread() (decimal.Decimal, bool) {
+	bid, ask, ok := b.bestBidAndAsk()
+	if !ok {
+		return decimal.Decimal{}, false
+	}
+
+	return ask.Price.Sub(bid.Price), true
+}
+
+// Levels returns a copy of up to depth levels of side, best first. If depth <= 0,
+// all levels are returned.
+func (b *Book) Levels(side Side, depth int) []Level {
+	levels, err := b.levels(side)
+	if err != nil {
+		return nil
+	}
+
+	if depth <= 0 || depth > len(*levels) {
+		depth = len(*levels)
+	}
+
+	result := make([]Level, depth)
+	copy(result, (*levels)[:depth])
+
+	return result
+}
+
+// Len returns the number of levels of side.
+func (b *Book) Len(side Side) int {
+	levels, err := b.levels(side)
+	if err != nil {
+		return 0
+	}
+
+	return len(*levels)
+}
+
+// SizeWithin returns the total size of the levels of side priced at or better
+// than price.
+func (b *Book) SizeWithin(side Side, price decimal.Decimal) decimal.Decimal {
+	levels, err := b.levels(side)
+	if err != nil {
+		return decimal.Decimal{}
+	}
+
+	end := search(side, *levels, price)
+	if end < len(*levels) && (*levels)[end].Price.Cmp(price) == 0 {
+		end++
+	}
+
+	var size decimal.Decimal
+	for _, level := range (*levels)[:end] {
+		size = size.Add(level.Size)
+	}
+
+	return size
+}
+
+// Fill walks the levels of side, best first, as a market order of size would
+// (e.g. a buy order fills against SideAsk). It returns the volume-weighted average
+// price of the fill (see fillPrecision) and the size filled, which is less than
+// size if the side doesn't have the liquidity.
+func (b *Book) Fill(side Side, size decimal.Decimal) (avgPrice, filled decimal.Decimal) {
+	levels, err := b.levels(side)
+	if err != nil {
+		return decimal.Decimal{}, decimal.Decimal{}
+	}
+
+	var total decimal.Decimal
+	var scale int32
+
+	for _, level := range *levels {
+		if filled.Cmp(size) >= 0 {
+			break
+		}
+
+		fill := level.Size
+		if remaining := size.Sub(filled); fill.Cmp(remaining) > 0 {
+			fill = remaining
+		}
+
+		filled = filled.Add(fill)
+		total = total.Add(fill.Mul(level.Price))
+
+		if level.Price.Scale() > scale {
+			scale = level.Price.Scale()
+		}
+	}
+
+	if filled.IsZero() {
+		return decimal.Decimal{}, decimal.Decimal{}
+	}
+
+	return total.DivRound(filled, scale+fillPrecision), filled
+}
+
+// Slippage returns how much worse the average price of filling size against side
+// (see Fill) is than the best price of side. Returns false if side can't fill
+// size.
+func (b *Book) Slippage(side Side, size decimal.Decimal) (decimal.Decimal, bool) {
+	best, ok := b.Best(side)
+	if !ok {
+		return decimal.Decimal{}, false
+	}
+
+	avgPrice, filled := b.Fill(side, size)
+	if filled.Cmp(size) < 0 {
+		return decimal.Decimal{}, false
+	}
+
+	if side == SideBid {
+		return best.Price.Sub(avgPrice), true
+	}
+
+	return avgPrice.Sub(best.Price), true
+}
+
+// levels returns the levels of side.
+func (b *Book) levels(side Side) (*[]Level, error) {
+	switch side {
+	case SideBid:
+		return &b.bids, nil
+	case SideAsk:
+		return &b.asks, nil
+	default:
+		return nil, fmt.Errorf("unknown side %q", side)
+	}
+}
+
+// bestBidAndAsk returns the best bid and ask, or false if either side has no levels.
+func (b *Book) bestBidAndAsk() (bid, ask Level, ok bool) {
+	bid, bidOK := b.BestBid()
+	ask, askOK := b.BestAsk()
+
+	return bid, ask, bidOK && askOK
+}
+
+// search returns the index of the first level of levels (of side) not priced better
+// than price.
+func search(side Side, levels []Level, price decimal.Decimal) int {
+	if side == SideBid {
+		return sort.Search(len(levels), func(a int) bool { return levels[a].Price.Cmp(price) <= 0 })
+	}
+
+	return sort.Search(len(levels), func(a int) bool { return levels[a].Price.Cmp(price) >= 0 })
+}
+
+// sortedLevels returns a copy of levels of side, without those with no size, sorted
+// best first.
+func sortedLevels(side Side, levels []Level) []Level {
+	sorted := make([]Level, 0, len(levels))
+	for _, level := range levels {
+		if !level.Size.IsZero() {
+			sorted = append(sorted, level)
+		}
+	}
+
+	sort.Slice(sorted, func(a, b int) bool {
+		if side == SideBid {
+			return sorted[a].Price.Cmp(sorted[b].Price) > 0
+		}
+
+		return sorted[a].Price.Cmp(sorted[b].Price) < 0
+	})
+
+	return sorted
+}
@@ -0,0 +1,237 @@
+package orderbook
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+// newTestBook creates a Book with bids at 99, 98 & 97 and asks at 101, 102 & 103,
+// each of size 1, 2 & 3 respectively (best first).
+func newTestBook() *Book {
+	b := New()
+	b.Reset(
+		newTestLevels("3@97", "1@99", "2@98"),
+		newTestLevels("3@103", "1@101", "2@102", "0@104"),
+	)
+
+	return b
+}
+
+// newTestLevels creates a Level from each of levels, formatted as "size@price".
+func newTestLevels(levels ...string) []Level {
+	result := make([]Level, len(levels))
+	for a, level := range levels {
+		size, price, _ := strings.Cut(level, "@")
+		result[a] = Level{Price: decimal.MustParse(price), Size: decimal.MustParse(size)}
+	}
+
+	return result
+}
+
+// formatLevels formats each of levels as "size@price", so levels can be compared
+// exactly (including the decimal places of each).
+func formatLevels(levels []Level) []string {
+	result := make([]string, len(levels))
+	for a, level := range levels {
+		result[a] = level.Size.String() + "@" + level.Price.String()
+	}
+
+	return result
+}
+
+func TestBookReset(t *testing.T) {
+	t.Parallel()
+
+	b := New()
+	assert.False(t, b.Synced(), "Synced before reset")
+
+	b = newTestBook()
+
+	assert.True(t, b.Synced(), "Synced")
+	assert.Equal(t, []string{"1@99", "2@98", "3@97"}, formatLevels(b.Levels(SideBid, 0)), "Bids")
+	assert.Equal(t, []string{"1@101", "2@102", "3@103"}, formatLevels(b.Levels(SideAsk, 0)), "Asks")
+
+	b.Invalidate()
+	assert.False(t, b.Synced(), "Synced after invalidate")
+}
+
+func TestBookSet(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name         string
+		giveSide     Side
+		givePrice    string
+		giveSize     string
+		expectedBids []string
+		expectedAsks []string
+		expectedErr  string
+	}{
+		{
+			name:         "insert_best_bid",
+			giveSide:     SideBid,
+			givePrice:    "100",
+			giveSize:     "5",
+			expectedBids: []string{"5@100", "1@99", "2@98", "3@97"},
+			expectedAsks: []string{"1@101", "2@102", "3@103"},
+		},
+		{
+			name:         "insert_middle_ask",
+			giveSide:     SideAsk,
+			givePrice:    "101.5",
+			giveSize:     "5",
+			expectedBids: []string{"1@99", "2@98", "3@97"},
+			expectedAsks: []string{"1@101", "5@101.5", "2@102", "3@103"},
+		},
+		{
+			name:         "insert_worst_ask",
+			giveSide:     SideAsk,
+			givePrice:    "110",
+			giveSize:     "5",
+			expectedBids: []string{"1@99", "2@98", "3@97"},
+			expectedAsks: []string{"1@101", "2@102", "3@103", "5@110"},
+		},
+		{
+			name:         "update_bid",
+			giveSide:     SideBid,
+			givePrice:    "98",
+			giveSize:     "5",
+			expectedBids: []string{"1@99", "5@98", "3@97"},
+			expectedAsks: []string{"1@101", "2@102", "3@103"},
+		},
+		{
+			name:         "remove_ask",
+			giveSide:     SideAsk,
+			givePrice:    "101",
+			giveSize:     "0",
+			expectedBids: []string{"1@99", "2@98", "3@97"},
+			expectedAsks: []string{"2@102", "3@103"},
+		},
+		{
+			name:         "remove_missing_bid",
+			giveSide:     SideBid,
+			givePrice:    "50",
+			giveSize:     "0",
+			expectedBids: []string{"1@99", "2@98", "3@97"},
+			expectedAsks: []string{"1@101", "2@102", "3@103"},
+		},
+		{
+			name:         "unknown_side",
+			giveSide:     "TestABC",
+			givePrice:    "100",
+			giveSize:     "1",
+			expectedBids: []string{"1@99", "2@98", "3@97"},
+			expectedAsks: []string{"1@101", "2@102", "3@103"},
+			expectedErr:  `unknown side "TestABC"`,
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+			b := newTestBook()
+
+			// Do
+			err := b.Set(tc.giveSide, decimal.MustParse(tc.givePrice), decimal.MustParse(tc.giveSize))
+
+			// Assert
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr, "Err")
+			} else {
+				assert.NoError(t, err, "Err")
+			}
+
+			assert.Equal(t, tc.expectedBids, formatLevels(b.Levels(SideBid, 0)), "Bids")
+			assert.Equal(t, tc.expectedAsks, formatLevels(b.Levels(SideAsk, 0)), "Asks")
+		})
+	}
+}
+
+func TestBookQueries(t *testing.T) {
+	t.Parallel()
+
+	b := newTestBook()
+
+	bestBid, ok := b.BestBid()
+	assert.True(t, ok, "Best bid ok")
+	assert.Equal(t, []string{"1@99"}, formatLevels([]Level{bestBid}), "Best bid")
+
+	bestAsk, ok := b.BestAsk()
+	assert.True(t, ok, "Best ask ok")
+	assert.Equal(t, []string{"1@101"}, formatLevels([]Level{bestAsk}), "Best ask")
+
+	mid, ok := b.Mid()
+	assert.True(t, ok, "Mid ok")
+	assert.Equal(t, "100.0", mid.String(), "Mid")
+
+	spread, ok := b.Spread()
+	assert.True(t, ok, "Spread ok")
+	assert.Equal(t, "2", spread.String(), "Spread")
+
+	assert.Equal(t, []string{"1@99", "2@98"}, formatLevels(b.Levels(SideBid, 2)), "Bids to depth")
+	assert.Equal(t, 3, b.Len(SideAsk), "Len")
+
+	assert.Equal(t, "3", b.SizeWithin(SideBid, decimal.MustParse("98")).String(), "Size within bid")
+	assert.Equal(t, "3", b.SizeWithin(SideAsk, decimal.MustParse("102.5")).String(), "Size within ask")
+	assert.Equal(t, "0", b.SizeWithin(SideAsk, decimal.MustParse("100")).String(), "Size within nothing")
+
+	// Buying 4 fills 1@101, 2@102 & 1@103.
+	avgPrice, filled := b.Fill(SideAsk, decimal.MustParse("4"))
+	assert.Equal(t, "102.00000000", avgPrice.String(), "Fill avg price")
+	assert.Equal(t, "4", filled.String(), "Filled")
+
+	avgPrice, filled = b.Fill(SideBid, decimal.MustParse("10"))
+	assert.Equal(t, "97.66666667", avgPrice.String(), "Partial fill avg price")
+	assert.Equal(t, "6", filled.String(), "Partially filled")
+
+	slippage, ok := b.Slippage(SideAsk, decimal.MustParse("4"))
+	assert.True(t, ok, "Slippage ok")
+	assert.Equal(t, "1.00000000", slippage.String(), "Slippage")
+
+	slippage, ok = b.Slippage(SideBid, decimal.MustParse("3"))
+	assert.True(t, ok, "Bid slippage ok")
+	assert.Equal(t, "0.66666667", slippage.String(), "Bid slippage")
+
+	_, ok = b.Slippage(SideBid, decimal.MustParse("10"))
+	assert.False(t, ok, "Slippage beyond liquidity")
+}
+
+func TestBookSetSamePriceDifferentScale(t *testing.T) {
+	t.Parallel()
+
+	b := New()
+	b.Reset(newTestLevels("1@99.50"), nil)
+
+	assert.NoError(t, b.Set(SideBid, decimal.MustParse("99.5"), decimal.MustParse("2")), "Set")
+	assert.Equal(t, []string{"2@99.50"}, formatLevels(b.Levels(SideBid, 0)), "Bids after set")
+
+	assert.NoError(t, b.Set(SideBid, decimal.MustParse("99.500"), decimal.MustParse("0.000")), "Remove")
+	assert.Empty(t, b.Levels(SideBid, 0), "Bids after remove")
+}
+
+func TestBookEmpty(t *testing.T) {
+	t.Parallel()
+
+	b := New()
+
+	_, ok := b.BestBid()
+	assert.False(t, ok, "Best bid ok")
+
+	_, ok = b.Mid()
+	assert.False(t, ok, "Mid ok")
+
+	_, ok = b.Spread()
+	assert.False(t, ok, "Spread ok")
+
+	assert.Empty(t, b.Levels(SideAsk, 5), "Levels")
+
+	avgPrice, filled := b.Fill(SideAsk, decimal.MustParse("1"))
+	assert.True(t, avgPrice.IsZero(), "Fill avg price")
+	assert.True(t, filled.IsZero(), "Filled")
+}
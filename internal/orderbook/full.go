@@ -0,0 +1,351 @@
+package orderbook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+const (
+	// The backoff before syncing again after a sync fails, doubled after each
+	// consecutive failure up to maxSyncBackoff.
+	minSyncBackoff = time.Millisecond * 250
+	maxSyncBackoff = time.Second * 10
+
+	// maxPending is the number of messages buffered while the book isn't synced.
+	// Beyond this the oldest are dropped, so the next snapshot must be more recent.
+	maxPending = 10000
+)
+
+// Level3Snapshotter gets snapshots of the whole book for a product, see
+// coinbase.RESTClient.
+type Level3Snapshotter interface {
+	GetLevel3Book(ctx context.Context, productID coinbase.ProductID) (*coinbase.Level3Book, error)
+}
+
+var _ Level3Snapshotter = (*coinbase.RESTClient)(nil)
+
+// FullBook maintains an OrderBook for a single product from a coinbase.FullSubscription,
+// synced against snapshots by sequence. It is not safe for concurrent use.
+//
+// The book is synced from a snapshot when the first message is applied, and resynced
+// whenever it drifts: on a sequence gap, on a message inconsistent with the book
+// or after the subscription reconnects. Messages applied while it isn't synced are
+// buffered, then replayed onto the next snapshot (those already in it skipped). A
+// snapshot older than the messages buffered can't be used, so after a failed sync
+// the next is backed off, with messages buffered meanwhile.
+//
+// Messages read from the subscription while a snapshot is fetched wait in its read
+// channel, which is why a FullSubscription only supports blocking when it's full.
+type FullBook struct {
+	productID coinbase.ProductID
+	snapshots Level3Snapshotter
+
+	book   *OrderBook
+	synced bool
+
+	// Messages applied while the book isn't synced, in order of sequence.
+	pending []coinbase.FullMessage
+
+	// The backoff after the last failed sync, and when the book can next be synced.
+	// Both zero after a successful sync.
+	syncBackoff time.Duration
+	nextSync    time.Time
+
+	// Tells the time, for the backoff.
+	now func() time.Time
+
+	// The sequence of the last message seen (whether applied to the book or not).
+	lastSequence int64
+
+	// The number of times the book has been synced from a snapshot.
+	syncs int
+}
+
+// NewFullBook creates a new FullBook for productID, synced using snapshots.
+func NewFullBook(productID coinbase.ProductID, snapshots Level3Snapshotter) *FullBook {
+	return &FullBook{
+		productID: productID,
+		snapshots: snapshots,
+		book:      NewOrderBook(),
+		now:       time.Now,
+	}
+}
+
+// Book returns the OrderBook, which must not be modified.
+func (f *FullBook) Book() *OrderBook {
+	return f.book
+}
+
+// Synced returns true if the book is synced with the feed.
+func (f *FullBook) Synced() bool {
+	return f.synced
+}
+
+// Syncs returns the number of times the book has been synced from a snapshot.
+// Every sync after the first is a resync.
+func (f *FullBook) Syncs() int {
+	return f.syncs
+}
+
+// Apply applies a response read from the subscription to the book, syncing it as
+// required. If the response is a match not seen before, it is returned (e.g. to
+// feed a vwap.SlidingWindowVWAP).
+func (f *FullBook) Apply(ctx context.Context, response *coinbase.FullResponse) (*coinbase.Match, error) {
+	switch {
+	case response.Err != nil:
+		return nil, fmt.Errorf("full response: %w", response.Err)
+	case response.Notification != nil:
+		if _, ok := response.Notification.(*coinbase.ReconnectingNotification); ok {
+			// Messages are missed while reconnecting, so those buffered can't be
+			// replayed.
+			f.synced = false
+			f.pending = nil
+		}
+
+		return nil, nil
+	}
+
+	message := &response.Message
+
+	var match *coinbase.Match
+	if message.Sequence > f.lastSequence {
+		f.lastSequence = message.Sequence
+
+		if message.Type == coinbase.MessageTypeMatch {
+			m := message.ToMatch()
+			match = &m
+		}
+	}
+
+	return match, f.applyMessage(ctx, message)
+}
+
+// Matches applies every response read from read to the book (see Apply), and
+// returns a channel of the matches derived from them. Errors are read as
+// MatchResponse.Err and notifications as MatchResponse.Notification. The channel
+// is closed once read is (or ctx is done). The book must not be used until then.
+func (f *FullBook) Matches(ctx context.Context, read <-chan *coinbase.FullResponse) <-chan *coinbase.MatchResponse {
+	matches := make(chan *coinbase.MatchResponse, 10)
+
+	go func() {
+		defer close(matches)
+
+		send := func(matchResponse *coinbase.MatchResponse) bool {
+			select {
+			case matches <- matchResponse:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for response := range read {
+			if response.Notification != nil && !send(&coinbase.MatchResponse{Notification: response.Notification}) {
+				return
+			}
+
+			match, err := f.Apply(ctx, response)
+			if match != nil && !send(&coinbase.MatchResponse{Match: *match}) {
+				return
+			}
+
+			if err != nil && !send(&coinbase.MatchResponse{Err: err}) {
+				return
+			}
+		}
+	}()
+
+	return matches
+}
+
+// applyMessage applies message to the book if it's synced, otherwise it's buffered
+// and the book synced (if not backed off).
+func (f *FullBook) applyMessage(ctx context.Context, message *coinbase.FullMessage) error {
+	if f.synced {
+		switch {
+		case message.Sequence <= f.book.Sequence():
+			// Already in the snapshot.
+			return nil
+		case message.Sequence == f.book.Sequence()+1:
+			if err := f.apply(message); err == nil {
+				f.book.sequence = message.Sequence
+
+				return nil
+			}
+		}
+
+		// A sequence gap, or the book has drifted.
+		f.synced = false
+	}
+
+	f.pending = append(f.pending, *message)
+	if len(f.pending) > maxPending {
+		f.pending = f.pending[len(f.pending)-maxPending:]
+	}
+
+	if f.now().Before(f.nextSync) {
+		return nil
+	}
+
+	if err := f.sync(ctx); err != nil {
+		f.syncBackoff *= 2
+		switch {
+		case f.syncBackoff < minSyncBackoff:
+			f.syncBackoff = minSyncBackoff
+		case f.syncBackoff > maxSyncBackoff:
+			f.syncBackoff = maxSyncBackoff
+		}
+
+		f.nextSync = f.now().Add(f.syncBackoff)
+
+		return err
+	}
+
+	f.syncBackoff = 0
+	f.nextSync = time.Time{}
+
+	return nil
+}
+
+// apply applies message to the book. An error means the book is inconsistent with
+// the message.
+func (f *FullBook) apply(message *coinbase.FullMessage) error {
+	switch message.Type {
+	case coinbase.MessageTypeOpen:
+		side, err := toSide(message.Side)
+		if err != nil {
+			return fmt.Errorf("open: %w", err)
+		}
+
+		price, size, err := parsePriceAndSize(message.Price, message.RemainingSize)
+		if err != nil {
+			return fmt.Errorf("open: %w", err)
+		}
+
+		if err := f.book.Add(Order{ID: message.OrderID, Side: side, Price: price, Size: size}); err != nil {
+			return fmt.Errorf("open: %w", err)
+		}
+	case coinbase.MessageTypeDone:
+		// Orders that never opened (e.g. market orders) aren't on the book.
+		if _, err := f.book.Remove(message.OrderID); err != nil {
+			return fmt.Errorf("done: %w", err)
+		}
+	case coinbase.MessageTypeMatch:
+		order, ok := f.book.Order(message.MakerOrderID)
+		if !ok {
+			return fmt.Errorf("match: maker order %s is not on the book", message.MakerOrderID)
+		}
+
+		matched, err := decimal.Parse(message.Size)
+		if err != nil {
+			return fmt.Errorf("match: parse size: %w", err)
+		}
+
+		remaining := order.Size.Sub(matched)
+		if remaining.Sign() < 0 {
+			return fmt.Errorf("match: size %v matched exceeds the remaining size %v", matched, order.Size)
+		}
+
+		if _, err := f.book.Resize(order.ID, remaining); err != nil {
+			return fmt.Errorf("match: %w", err)
+		}
+	case coinbase.MessageTypeChange:
+		// Changes to orders that aren't on the book (e.g. not yet open) don't apply.
+		if _, ok := f.book.Order(message.OrderID); !ok || message.NewSize == "" {
+			return nil
+		}
+
+		size, err := decimal.Parse(message.NewSize)
+		if err != nil {
+			return fmt.Errorf("change: parse new size: %w", err)
+		}
+
+		if _, err := f.book.Resize(message.OrderID, size); err != nil {
+			return fmt.Errorf("change: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// sync resets the book from a new snapshot, then replays the messages buffered
+// after it. If the snapshot is older than the messages buffered (or they can't be
+// replayed onto it) the book isn't synced, and the messages are kept for the next.
+func (f *FullBook) sync(ctx context.Context) error {
+	snapshot, err := f.snapshots.GetLevel3Book(ctx, f.productID)
+	if err != nil {
+		return fmt.Errorf("sync: %w", err)
+	}
+
+	if len(f.pending) > 0 && snapshot.Sequence < f.pending[0].Sequence-1 {
+		return fmt.Errorf("sync: snapshot sequence %d is before buffered sequence %d", snapshot.Sequence, f.pending[0].Sequence)
+	}
+
+	orders := make([]Order, 0, len(snapshot.Bids)+len(snapshot.Asks))
+
+	for _, side := range []struct {
+		side   Side
+		orders []coinbase.Level3Order
+	}{
+		{side: SideBid, orders: snapshot.Bids},
+		{side: SideAsk, orders: snapshot.Asks},
+	} {
+		for _, order := range side.orders {
+			price, size, err := parsePriceAndSize(order.Price, order.Size)
+			if err != nil {
+				return fmt.Errorf("sync: order %s: %w", order.OrderID, err)
+			}
+
+			orders = append(orders, Order{ID: order.OrderID, Side: side.side, Price: price, Size: size})
+		}
+	}
+
+	f.book.Reset(snapshot.Sequence, orders)
+
+	for a := range f.pending {
+		message := &f.pending[a]
+
+		switch {
+		case message.Sequence <= f.book.Sequence():
+			// Already in the snapshot.
+			continue
+		case message.Sequence != f.book.Sequence()+1:
+			f.pending = f.pending[a:]
+
+			return fmt.Errorf("sync: buffered sequence %d is after sequence %d", message.Sequence, f.book.Sequence())
+		}
+
+		if err := f.apply(message); err != nil {
+			f.pending = f.pending[a:]
+
+			return fmt.Errorf("sync: replay sequence %d onto snapshot sequence %d: %w", message.Sequence, snapshot.Sequence, err)
+		}
+
+		f.book.sequence = message.Sequence
+	}
+
+	f.pending = nil
+	f.synced = true
+	f.syncs++
+
+	return nil
+}
+
+// parsePriceAndSize parses a price and size.
+func parsePriceAndSize(price, size string) (decimal.Decimal, decimal.Decimal, error) {
+	parsedPrice, err := decimal.Parse(price)
+	if err != nil {
+		return decimal.Decimal{}, decimal.Decimal{}, fmt.Errorf("parse price: %w", err)
+	}
+
+	parsedSize, err := decimal.Parse(size)
+	if err != nil {
+		return decimal.Decimal{}, decimal.Decimal{}, fmt.Errorf("parse size: %w", err)
+	}
+
+	return parsedPrice, parsedSize, nil
+}
@@ -0,0 +1,310 @@
+package orderbook
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+	"github.com/byatesrae/coinbase_vwap/internal/vwap"
+)
+
+func TestFullBookMatches(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(ctxCancel)
+
+	var snapshotRequests int32
+	server := newLevel3BookServerStub(t, &snapshotRequests, "testdata/btcusd_level3_book_1.json", "testdata/btcusd_level3_book_2.json")
+
+	fullBook := NewFullBook(coinbase.ProductIDBtcUsd, &coinbase.RESTClient{BaseURL: server.URL})
+
+	messages := readFullFixture(t, "testdata/btcusd_full.jsonl")
+
+	// Do (up to the sequence gap)
+
+	read := make(chan *coinbase.FullResponse)
+	matches := fullBook.Matches(ctx, read)
+
+	var actualMatches []coinbase.Match
+
+	// There are fewer matches than the matches buffer, so this doesn't block.
+	for _, message := range messages[:11] {
+		read <- &coinbase.FullResponse{Message: message}
+	}
+
+	// Once this is read, all messages before it have been applied.
+	read <- &coinbase.FullResponse{Notification: &coinbase.StaleNotification{ProductID: coinbase.ProductIDBtcUsd}}
+
+	for matchResponse := range matches {
+		if matchResponse.Notification != nil {
+			break
+		}
+
+		require.NoError(t, matchResponse.Err, "Match response err")
+		actualMatches = append(actualMatches, matchResponse.Match)
+	}
+
+	// Assert (up to the sequence gap)
+
+	book := fullBook.Book()
+
+	assert.Equal(t, 1, fullBook.Syncs(), "Syncs")
+	assert.Equal(t, int64(109), book.Sequence(), "Sequence")
+	assert.Equal(t, []string{"1.5@99.00", "3.0@98.50"}, formatLevels(book.Levels().Levels(SideBid, 0)), "Bids")
+	assert.Equal(t, []string{"0.5@100.00", "2.0@100.50"}, formatLevels(book.Levels().Levels(SideAsk, 0)), "Asks")
+
+	_, ok := book.Order("a1000000-0000-0000-0000-000000000001")
+	assert.False(t, ok, "Filled order on book")
+
+	position, ok := book.QueuePosition("b1000000-0000-0000-0000-000000000002")
+	assert.True(t, ok, "Queue position ok")
+	assert.Equal(t, 0, position.Index, "Queue position index after cancel ahead")
+	assert.True(t, position.SizeAhead.IsZero(), "Queue position size ahead after cancel ahead")
+
+	// Do (the sequence gap and after)
+
+	for _, message := range messages[11:] {
+		read <- &coinbase.FullResponse{Message: message}
+	}
+
+	close(read)
+
+	for matchResponse := range matches {
+		require.NoError(t, matchResponse.Err, "Match response err")
+		actualMatches = append(actualMatches, matchResponse.Match)
+	}
+
+	// Assert (the sequence gap and after)
+
+	assert.Equal(t, 2, fullBook.Syncs(), "Syncs")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&snapshotRequests), "Snapshot requests")
+	assert.True(t, fullBook.Synced(), "Synced")
+	assert.Equal(t, int64(112), book.Sequence(), "Sequence")
+	assert.Equal(t, []string{"1.5@99.00", "4.0@98.50"}, formatLevels(book.Levels().Levels(SideBid, 0)), "Bids")
+	assert.Equal(t, []string{"0.3@100.00", "2.0@100.50"}, formatLevels(book.Levels().Levels(SideAsk, 0)), "Asks")
+	assert.Equal(t, 2, book.OrderCount(SideBid, decimal.MustParse("98.5")), "Order count")
+
+	position, ok = book.QueuePosition("b1000000-0000-0000-0000-000000000004")
+	assert.True(t, ok, "Queue position ok")
+	assert.Equal(t, SideBid, position.Side, "Queue position side")
+	assert.Equal(t, "98.50", position.Price.String(), "Queue position price")
+	assert.Equal(t, 1, position.Index, "Queue position index")
+	assert.Equal(t, "3.0", position.SizeAhead.String(), "Queue position size ahead")
+
+	if assert.Len(t, actualMatches, 3, "Matches") {
+		assert.Equal(t, []int64{5001, 5002, 5003}, []int64{actualMatches[0].TradeID, actualMatches[1].TradeID, actualMatches[2].TradeID}, "Match trade IDs")
+	}
+
+	vwapCalculator := vwap.NewSlidingWindowVWAP(10)
+
+	var actualVWAP float64
+	for _, match := range actualMatches {
+		units, unitPrice, err := (&coinbase.MatchResponse{Match: match}).ToUnitsAndUnitPrice()
+		require.NoError(t, err, "ToUnitsAndUnitPrice")
+
+		actualVWAP = vwapCalculator.Add(units, unitPrice)
+	}
+
+	assert.Equal(t, 100.0, actualVWAP, "VWAP")
+}
+
+func TestFullBookApplyDrift(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	var snapshotRequests int32
+	server := newLevel3BookServerStub(t, &snapshotRequests, "testdata/btcusd_level3_book_1.json")
+
+	fullBook := NewFullBook(coinbase.ProductIDBtcUsd, &coinbase.RESTClient{BaseURL: server.URL})
+
+	// Do
+
+	match, err := fullBook.Apply(context.Background(), &coinbase.FullResponse{Message: coinbase.FullMessage{
+		Type:         coinbase.MessageTypeMatch,
+		ProductID:    coinbase.ProductIDBtcUsd,
+		Sequence:     101,
+		MakerOrderID: "TestABC",
+		Size:         "1",
+		Price:        "100",
+	}})
+
+	// Assert
+
+	assert.EqualError(t, err, "sync: replay sequence 101 onto snapshot sequence 100: match: maker order TestABC is not on the book", "Apply err")
+	assert.NotNil(t, match, "Match")
+	assert.Zero(t, fullBook.Syncs(), "Syncs")
+	assert.False(t, fullBook.Synced(), "Synced")
+
+	// Backed off, so the next message is buffered without another snapshot.
+	_, err = fullBook.Apply(context.Background(), &coinbase.FullResponse{Message: coinbase.FullMessage{Type: coinbase.MessageTypeReceived, Sequence: 102}})
+	assert.NoError(t, err, "Apply backed off err")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&snapshotRequests), "Snapshot requests")
+
+	_, err = fullBook.Apply(context.Background(), &coinbase.FullResponse{Notification: &coinbase.ReconnectingNotification{Attempt: 1}})
+	assert.NoError(t, err, "Apply reconnecting err")
+	assert.False(t, fullBook.Synced(), "Synced after reconnecting")
+	assert.Empty(t, fullBook.pending, "Buffered after reconnecting")
+}
+
+func TestFullBookApplyBuffered(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	var snapshotRequests int32
+	server := newLevel3BookServerStub(t, &snapshotRequests, "testdata/btcusd_level3_book_1.json", "testdata/btcusd_level3_book_2.json")
+
+	now := time.Date(2022, 10, 20, 1, 0, 0, 0, time.UTC)
+
+	fullBook := NewFullBook(coinbase.ProductIDBtcUsd, &coinbase.RESTClient{BaseURL: server.URL})
+	fullBook.now = func() time.Time { return now }
+
+	messages := readFullFixture(t, "testdata/btcusd_full.jsonl")
+
+	// Do (the first snapshot, at sequence 100, is before the first message at 102)
+
+	var actualErrs []string
+	var actualTradeIDs []int64
+
+	apply := func(messages []coinbase.FullMessage) {
+		for _, message := range messages {
+			match, err := fullBook.Apply(context.Background(), &coinbase.FullResponse{Message: message})
+			if err != nil {
+				actualErrs = append(actualErrs, err.Error())
+			}
+
+			if match != nil {
+				actualTradeIDs = append(actualTradeIDs, match.TradeID)
+			}
+		}
+	}
+
+	apply(messages[3:12])
+
+	// Assert (backed off, so the messages until 111 are buffered)
+
+	assert.Equal(t, []string{"sync: snapshot sequence 100 is before buffered sequence 102"}, actualErrs, "Errs")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&snapshotRequests), "Snapshot requests")
+	assert.False(t, fullBook.Synced(), "Synced")
+
+	// Do (the second snapshot, at sequence 111, once the backoff has passed)
+
+	now = now.Add(minSyncBackoff)
+
+	apply(messages[12:])
+
+	// Assert (112 is replayed onto the snapshot)
+
+	book := fullBook.Book()
+
+	assert.Len(t, actualErrs, 1, "Errs")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&snapshotRequests), "Snapshot requests")
+	assert.True(t, fullBook.Synced(), "Synced")
+	assert.Equal(t, 1, fullBook.Syncs(), "Syncs")
+	assert.Equal(t, int64(112), book.Sequence(), "Sequence")
+	assert.Equal(t, []string{"0.3@100.00", "2.0@100.50"}, formatLevels(book.Levels().Levels(SideAsk, 0)), "Asks")
+	assert.Equal(t, []int64{5001, 5002, 5003}, actualTradeIDs, "Match trade IDs")
+}
+
+func TestFullBookApplyMatchFills(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	var snapshotRequests int32
+	server := newLevel3BookServerStub(t, &snapshotRequests, "testdata/btcusd_level3_book_1.json")
+
+	fullBook := NewFullBook(coinbase.ProductIDBtcUsd, &coinbase.RESTClient{BaseURL: server.URL})
+
+	// Do
+
+	// In float64, 1.5 - 1.4 - 0.1 would leave a residue of ~1e-16.
+	for a, size := range []string{"1.4", "0.1"} {
+		_, err := fullBook.Apply(context.Background(), &coinbase.FullResponse{Message: coinbase.FullMessage{
+			Type:         coinbase.MessageTypeMatch,
+			ProductID:    coinbase.ProductIDBtcUsd,
+			Sequence:     int64(101 + a),
+			MakerOrderID: "a1000000-0000-0000-0000-000000000001",
+			Size:         size,
+			Price:        "100",
+		}})
+		require.NoError(t, err, "Apply %s", size)
+	}
+
+	// Assert
+
+	order, ok := fullBook.Book().Order("a1000000-0000-0000-0000-000000000001")
+	assert.True(t, ok, "Order on book")
+	assert.True(t, order.Size.IsZero(), "Order size")
+	assert.Equal(t, []string{"2.0@100.50"}, formatLevels(fullBook.Book().Levels().Levels(SideAsk, 0)), "Asks")
+}
+
+// newLevel3BookServerStub creates a stub of the REST API, serving the level3 book
+// snapshots from files in turn (repeating the last). requests is incremented for
+// each request.
+func newLevel3BookServerStub(t *testing.T, requests *int32, files ...string) *httptest.Server {
+	t.Helper()
+
+	snapshots := make([][]byte, len(files))
+	for a, file := range files {
+		b, err := os.ReadFile(file)
+		require.NoError(t, err, "read snapshot %s", file)
+
+		snapshots[a] = b
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/products/BTC-USD/book" || r.URL.Query().Get("level") != "3" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		count := int(atomic.AddInt32(requests, 1))
+		if count > len(snapshots) {
+			count = len(snapshots)
+		}
+
+		_, _ = w.Write(snapshots[count-1])
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// readFullFixture reads the full channel messages (one per line) from file.
+func readFullFixture(t *testing.T, file string) []coinbase.FullMessage {
+	t.Helper()
+
+	f, err := os.Open(file)
+	require.NoError(t, err, "open fixture")
+	t.Cleanup(func() { _ = f.Close() })
+
+	var messages []coinbase.FullMessage
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		message := coinbase.FullMessage{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &message), "unmarshal fixture message")
+
+		messages = append(messages, message)
+	}
+
+	require.NoError(t, scanner.Err(), "scan fixture")
+
+	return messages
+}
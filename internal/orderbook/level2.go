@@ -0,0 +1,63 @@
+package orderbook
+
+import (
+	"fmt"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+)
+
+// ApplyLevel2 applies a response read from a coinbase.Level2Subscription (for the
+// book's product) to the book. A snapshot resets the book, an update sets each
+// changed level. Reconnecting invalidates the book until the next snapshot, as
+// updates may be missed.
+func (b *Book) ApplyLevel2(response *coinbase.Level2Response) error {
+	switch {
+	case response.Err != nil:
+		return fmt.Errorf("level2 response: %w", response.Err)
+	case response.Notification != nil:
+		if _, ok := response.Notification.(*coinbase.ReconnectingNotification); ok {
+			b.Invalidate()
+		}
+	case response.Snapshot != nil:
+		b.Reset(toLevels(response.Snapshot.Bids), toLevels(response.Snapshot.Asks))
+	case response.Update != nil:
+		if !b.synced {
+			return fmt.Errorf("level2 update received before snapshot")
+		}
+
+		for _, change := range response.Update.Changes {
+			side, err := toSide(change.Side)
+			if err != nil {
+				return err
+			}
+
+			if err := b.Set(side, change.Price, change.Size); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// toLevels converts priceLevels to Levels.
+func toLevels(priceLevels []coinbase.PriceLevel) []Level {
+	levels := make([]Level, len(priceLevels))
+	for a, priceLevel := range priceLevels {
+		levels[a] = Level{Price: priceLevel.Price, Size: priceLevel.Size}
+	}
+
+	return levels
+}
+
+// toSide converts the side of a coinbase order to the side of the book it rests on.
+func toSide(side coinbase.Side) (Side, error) {
+	switch side {
+	case coinbase.SideBuy:
+		return SideBid, nil
+	case coinbase.SideSell:
+		return SideAsk, nil
+	default:
+		return "", fmt.Errorf("unknown side %q", side)
+	}
+}
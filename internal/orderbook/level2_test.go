@@ -0,0 +1,50 @@
+package orderbook
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/byatesrae/coinbase_vwap/internal/coinbase"
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+func TestBookApplyLevel2(t *testing.T) {
+	t.Parallel()
+
+	snapshot := &coinbase.Level2Response{Snapshot: &coinbase.Level2Snapshot{
+		ProductID: coinbase.ProductIDBtcUsd,
+		Bids: []coinbase.PriceLevel{
+			{Price: decimal.MustParse("99"), Size: decimal.MustParse("1")},
+			{Price: decimal.MustParse("98"), Size: decimal.MustParse("2")},
+		},
+		Asks: []coinbase.PriceLevel{{Price: decimal.MustParse("101"), Size: decimal.MustParse("1")}},
+	}}
+	update := &coinbase.Level2Response{Update: &coinbase.Level2Update{
+		ProductID: coinbase.ProductIDBtcUsd,
+		Changes: []coinbase.Level2Change{
+			{Side: coinbase.SideBuy, PriceLevel: coinbase.PriceLevel{Price: decimal.MustParse("99.00"), Size: decimal.MustParse("0")}},
+			{Side: coinbase.SideSell, PriceLevel: coinbase.PriceLevel{Price: decimal.MustParse("100"), Size: decimal.MustParse("3")}},
+		},
+	}}
+
+	b := New()
+
+	assert.EqualError(t, b.ApplyLevel2(update), "level2 update received before snapshot", "Update before snapshot")
+
+	assert.NoError(t, b.ApplyLevel2(snapshot), "Snapshot")
+	assert.NoError(t, b.ApplyLevel2(update), "Update")
+
+	assert.Equal(t, []string{"2@98"}, formatLevels(b.Levels(SideBid, 0)), "Bids")
+	assert.Equal(t, []string{"3@100", "1@101"}, formatLevels(b.Levels(SideAsk, 0)), "Asks")
+
+	assert.NoError(t, b.ApplyLevel2(&coinbase.Level2Response{Notification: &coinbase.ReconnectingNotification{Attempt: 1}}), "Reconnecting")
+	assert.False(t, b.Synced(), "Synced after reconnecting")
+	assert.Error(t, b.ApplyLevel2(update), "Update after reconnecting")
+
+	assert.NoError(t, b.ApplyLevel2(snapshot), "Snapshot after reconnecting")
+	assert.True(t, b.Synced(), "Synced after snapshot")
+
+	assert.EqualError(t, b.ApplyLevel2(&coinbase.Level2Response{Err: fmt.Errorf("TestABC")}), "level2 response: TestABC", "Err")
+}
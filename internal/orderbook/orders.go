@@ -0,0 +1,234 @@
+package orderbook
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+// Order is an order resting on an OrderBook.
+type Order struct {
+	ID    string
+	Side  Side
+	Price decimal.Decimal
+
+	// The remaining size of the order.
+	Size decimal.Decimal
+}
+
+// QueuePosition is the position of an order in the queue of its price level.
+type QueuePosition struct {
+	Side  Side
+	Price decimal.Decimal
+
+	// The number of orders ahead in the queue (i.e. 0 is the front).
+	Index int
+
+	// The total size of the orders ahead in the queue.
+	SizeAhead decimal.Decimal
+}
+
+// queue is the orders at a price level, oldest (highest priority) first.
+type queue struct {
+	orders []*Order
+}
+
+// OrderBook is an order by order (level 3) book, for a single product. Price levels
+// are aggregated (see Levels) as they are in a Book. It is not safe for concurrent
+// use.
+//
+// The zero-value of this type is not usable, see NewOrderBook.
+type OrderBook struct {
+	// Aggregated price levels.
+	levels *Book
+
+	// Orders by ID.
+	orders map[string]*Order
+
+	// Queues by side, then price (see priceKey).
+	queues map[Side]map[string]*queue
+
+	// The sequence of the last message applied.
+	sequence int64
+}
+
+// NewOrderBook creates a new, empty OrderBook.
+func NewOrderBook() *OrderBook {
+	o := &OrderBook{}
+	o.Reset(0, nil)
+
+	return o
+}
+
+// Reset replaces all orders of the book with orders (e.g. from a snapshot as of
+// sequence), which should be in priority order. The book is then synced.
+func (o *OrderBook) Reset(sequence int64, orders []Order) {
+	o.levels = New()
+	o.levels.Reset(nil, nil)
+	o.orders = make(map[string]*Order, len(orders))
+	o.queues = map[Side]map[string]*queue{SideBid: {}, SideAsk: {}}
+	o.sequence = sequence
+
+	for a := range orders {
+		// Unknown sides are dropped by Add.
+		_ = o.Add(orders[a])
+	}
+}
+
+// Sequence returns the sequence of the last message applied to the book.
+func (o *OrderBook) Sequence() int64 {
+	return o.sequence
+}
+
+// Add adds order to the back of the queue of its price level.
+func (o *OrderBook) Add(order Order) error {
+	if _, ok := o.queues[order.Side]; !ok {
+		return fmt.Errorf("unknown side %q", order.Side)
+	}
+
+	if _, ok := o.orders[order.ID]; ok {
+		return fmt.Errorf("order %s already exists", order.ID)
+	}
+
+	q, ok := o.queues[order.Side][priceKey(order.Price)]
+	if !ok {
+		q = &queue{}
+		o.queues[order.Side][priceKey(order.Price)] = q
+	}
+
+	added := order
+	q.orders = append(q.orders, &added)
+	o.orders[order.ID] = &added
+
+	return o.updateLevel(order.Side, order.Price)
+}
+
+// Remove removes the order by orderID. Returns false if the book doesn't have it.
+func (o *OrderBook) Remove(orderID string) (bool, error) {
+	order, ok := o.orders[orderID]
+	if !ok {
+		return false, nil
+	}
+
+	delete(o.orders, orderID)
+
+	q := o.queues[order.Side][priceKey(order.Price)]
+	for a := range q.orders {
+		if q.orders[a].ID == orderID {
+			q.orders = append(q.orders[:a], q.orders[a+1:]...)
+			break
+		}
+	}
+
+	if len(q.orders) == 0 {
+		delete(o.queues[order.Side], priceKey(order.Price))
+	}
+
+	return true, o.updateLevel(order.Side, order.Price)
+}
+
+// Resize sets the remaining size of the order by orderID, keeping its position in
+// the queue. Returns false if the book doesn't have it.
+func (o *OrderBook) Resize(orderID string, size decimal.Decimal) (bool, error) {
+	order, ok := o.orders[orderID]
+	if !ok {
+		return false, nil
+	}
+
+	order.Size = size
+
+	return true, o.updateLevel(order.Side, order.Price)
+}
+
+// Order returns the order by orderID, or false if the book doesn't have it.
+func (o *OrderBook) Order(orderID string) (Order, bool) {
+	order, ok := o.orders[orderID]
+	if !ok {
+		return Order{}, false
+	}
+
+	return *order, true
+}
+
+// Len returns the number of orders on the book.
+func (o *OrderBook) Len() int {
+	return len(o.orders)
+}
+
+// QueuePosition returns the position of the order by orderID in the queue of its
+// price level, or false if the book doesn't have it.
+func (o *OrderBook) QueuePosition(orderID string) (QueuePosition, bool) {
+	order, ok := o.orders[orderID]
+	if !ok {
+		return QueuePosition{}, false
+	}
+
+	position := QueuePosition{Side: order.Side, Price: order.Price}
+
+	for _, queued := range o.queues[order.Side][priceKey(order.Price)].orders {
+		if queued.ID == orderID {
+			break
+		}
+
+		position.Index++
+		position.SizeAhead = position.SizeAhead.Add(queued.Size)
+	}
+
+	return position, true
+}
+
+// OrderCount returns the number of orders at price on side.
+func (o *OrderBook) OrderCount(side Side, price decimal.Decimal) int {
+	q, ok := o.queues[side][priceKey(price)]
+	if !ok {
+		return 0
+	}
+
+	return len(q.orders)
+}
+
+// Orders returns a copy of the orders at price on side, in priority order.
+func (o *OrderBook) Orders(side Side, price decimal.Decimal) []Order {
+	q, ok := o.queues[side][priceKey(price)]
+	if !ok {
+		return nil
+	}
+
+	orders := make([]Order, len(q.orders))
+	for a, order := range q.orders {
+		orders[a] = *order
+	}
+
+	return orders
+}
+
+// Levels returns the aggregated price levels of the book. The returned Book must
+// not be modified.
+func (o *OrderBook) Levels() *Book {
+	return o.levels
+}
+
+// updateLevel updates the aggregated level at price on side from its queue.
+func (o *OrderBook) updateLevel(side Side, price decimal.Decimal) error {
+	var size decimal.Decimal
+
+	if q, ok := o.queues[side][priceKey(price)]; ok {
+		for _, order := range q.orders {
+			size = size.Add(order.Size)
+		}
+	}
+
+	return o.levels.Set(side, price, size)
+}
+
+// priceKey returns the canonical string of price, without trailing zeros, so
+// prices equal in value (e.g. "1.50" and "1.5") key the same queue.
+func priceKey(price decimal.Decimal) string {
+	key := price.String()
+	if strings.Contains(key, ".") {
+		key = strings.TrimSuffix(strings.TrimRight(key, "0"), ".")
+	}
+
+	return key
+}
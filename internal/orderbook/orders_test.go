@@ -0,0 +1,87 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+func TestOrderBook(t *testing.T) {
+	t.Parallel()
+
+	o := NewOrderBook()
+	o.Reset(5, []Order{
+		{ID: "b1", Side: SideBid, Price: decimal.MustParse("99"), Size: decimal.MustParse("1")},
+		{ID: "b2", Side: SideBid, Price: decimal.MustParse("99"), Size: decimal.MustParse("2")},
+		{ID: "a1", Side: SideAsk, Price: decimal.MustParse("101"), Size: decimal.MustParse("3")},
+	})
+
+	assert.Equal(t, int64(5), o.Sequence(), "Sequence")
+	assert.Equal(t, 3, o.Len(), "Len")
+	assert.True(t, o.Levels().Synced(), "Levels synced")
+
+	assert.EqualError(t, o.Add(Order{ID: "b1", Side: SideBid, Price: decimal.MustParse("98"), Size: decimal.MustParse("1")}), "order b1 already exists", "Add duplicate")
+	assert.EqualError(t, o.Add(Order{ID: "x1", Side: "TestABC", Price: decimal.MustParse("98"), Size: decimal.MustParse("1")}), `unknown side "TestABC"`, "Add unknown side")
+	assert.NoError(t, o.Add(Order{ID: "b3", Side: SideBid, Price: decimal.MustParse("99"), Size: decimal.MustParse("4")}), "Add")
+
+	position, ok := o.QueuePosition("b3")
+	assert.True(t, ok, "Queue position ok")
+	assert.Equal(t, SideBid, position.Side, "Queue position side")
+	assert.Equal(t, "99", position.Price.String(), "Queue position price")
+	assert.Equal(t, 2, position.Index, "Queue position index")
+	assert.Equal(t, "3", position.SizeAhead.String(), "Queue position size ahead")
+
+	resized, err := o.Resize("b1", decimal.MustParse("0.5"))
+	assert.True(t, resized, "Resized")
+	assert.NoError(t, err, "Resize err")
+
+	removed, err := o.Remove("b2")
+	assert.True(t, removed, "Removed")
+	assert.NoError(t, err, "Remove err")
+
+	removed, err = o.Remove("TestABC")
+	assert.False(t, removed, "Removed missing")
+	assert.NoError(t, err, "Remove missing err")
+
+	assert.Equal(
+		t,
+		[]Order{
+			{ID: "b1", Side: SideBid, Price: decimal.MustParse("99"), Size: decimal.MustParse("0.5")},
+			{ID: "b3", Side: SideBid, Price: decimal.MustParse("99"), Size: decimal.MustParse("4")},
+		},
+		o.Orders(SideBid, decimal.MustParse("99")),
+		"Orders",
+	)
+	assert.Equal(t, 2, o.OrderCount(SideBid, decimal.MustParse("99.00")), "Order count")
+	assert.Equal(t, []string{"4.5@99"}, formatLevels(o.Levels().Levels(SideBid, 0)), "Bid levels")
+
+	removed, err = o.Remove("a1")
+	assert.True(t, removed, "Removed last ask")
+	assert.NoError(t, err, "Remove last ask err")
+	assert.Zero(t, o.OrderCount(SideAsk, decimal.MustParse("101")), "Ask order count")
+	assert.Empty(t, o.Levels().Levels(SideAsk, 0), "Ask levels")
+}
+
+func TestOrderBookPricesEqualInValue(t *testing.T) {
+	t.Parallel()
+
+	o := NewOrderBook()
+	o.Reset(1, []Order{
+		{ID: "b1", Side: SideBid, Price: decimal.MustParse("99.50"), Size: decimal.MustParse("1.25")},
+		{ID: "b2", Side: SideBid, Price: decimal.MustParse("99.5"), Size: decimal.MustParse("0.75")},
+	})
+
+	assert.Equal(t, 2, o.OrderCount(SideBid, decimal.MustParse("99.500")), "Order count")
+	assert.Equal(t, []string{"2.00@99.50"}, formatLevels(o.Levels().Levels(SideBid, 0)), "Bid levels")
+
+	removed, err := o.Remove("b1")
+	assert.True(t, removed, "Removed")
+	assert.NoError(t, err, "Remove err")
+
+	position, ok := o.QueuePosition("b2")
+	assert.True(t, ok, "Queue position ok")
+	assert.Equal(t, 0, position.Index, "Queue position index")
+	assert.Equal(t, []string{"0.75@99.50"}, formatLevels(o.Levels().Levels(SideBid, 0)), "Bid levels after remove")
+}
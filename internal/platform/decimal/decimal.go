@@ -0,0 +1,327 @@
+// package decimal provides an arbitrary-precision decimal number, for exact
+// arithmetic on prices and sizes.
+package decimal
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Decimal is an immutable arbitrary-precision decimal number, an integer coefficient
+// scaled by a negative power of ten (i.e. coefficient * 10^-scale). Addition,
+// subtraction and multiplication are exact. Division and rounding round half to
+// even, so results are reproducible bit-for-bit.
+//
+// The scale is kept as parsed or computed (e.g. "1.50" has a scale of 2), so it
+// determines the number of decimal places formatted. Use Cmp to compare values.
+//
+// The zero-value of this type is 0.
+type Decimal struct {
+	// If nil, the coefficient is 0. Never modified once set.
+	coef *big.Int
+
+	// Never negative.
+	scale int32
+}
+
+var (
+	bigOne = big.NewInt(1)
+	bigTen = big.NewInt(10)
+)
+
+// New creates a new Decimal of coef * 10^-scale.
+func New(coef int64, scale int32) Decimal {
+	return newDecimal(big.NewInt(coef), scale)
+}
+
+// newDecimal creates a new Decimal of coef * 10^-scale, taking ownership of coef.
+func newDecimal(coef *big.Int, scale int32) Decimal {
+	if scale < 0 {
+		return Decimal{coef: coef.Mul(coef, pow10(-int64(scale)))}
+	}
+
+	return Decimal{coef: coef, scale: scale}
+}
+
+// Parse parses s, a decimal number such as "-12.3450", "0.5" or "7". Exponents
+// aren't supported.
+func Parse(s string) (Decimal, error) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	if len(s)-len(digits) > 1 {
+		return Decimal{}, fmt.Errorf("parse decimal %q: invalid syntax", s)
+	}
+
+	whole, fraction, _ := strings.Cut(digits, ".")
+	if whole == "" && fraction == "" {
+		return Decimal{}, fmt.Errorf("parse decimal %q: invalid syntax", s)
+	}
+
+	for _, r := range whole + fraction {
+		if r < '0' || r > '9' {
+			return Decimal{}, fmt.Errorf("parse decimal %q: invalid syntax", s)
+		}
+	}
+
+	if len(fraction) > math.MaxInt32 {
+		return Decimal{}, fmt.Errorf("parse decimal %q: too many decimal places", s)
+	}
+
+	coef, ok := new(big.Int).SetString(whole+fraction, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("parse decimal %q: invalid syntax", s)
+	}
+
+	if strings.HasPrefix(s, "-") {
+		coef.Neg(coef)
+	}
+
+	return Decimal{coef: coef, scale: int32(len(fraction))}, nil
+}
+
+// MustParse parses s as Parse does, but panics if s is invalid. It's intended
+// for constants.
+func MustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return d
+}
+
+// Scale returns the number of decimal places of d.
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// Sign returns -1, 0 or +1 as d is negative, zero or positive.
+func (d Decimal) Sign() int {
+	if d.coef == nil {
+		return 0
+	}
+
+	return d.coef.Sign()
+}
+
+// IsZero returns true if d is 0 (at any scale).
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Cmp returns -1, 0 or +1 as d is less than, equal to or greater than d2. The
+// scale doesn't matter, e.g. "1.5" and "1.50" are equal.
+func (d Decimal) Cmp(d2 Decimal) int {
+	a, b := align(d, d2)
+
+	return a.Cmp(b)
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{coef: new(big.Int).Neg(d.coefOrZero()), scale: d.scale}
+}
+
+// Add returns d + d2, at the greater of their scales.
+func (d Decimal) Add(d2 Decimal) Decimal {
+	a, b := align(d, d2)
+
+	return Decimal{coef: a.Add(a, b), scale: maxScale(d, d2)}
+}
+
+// Sub returns d - d2, at the greater of their scales.
+func (d Decimal) Sub(d2 Decimal) Decimal {
+	a, b := align(d, d2)
+
+	return Decimal{coef: a.Sub(a, b), scale: maxScale(d, d2)}
+}
+
+// Mul returns d * d2, at the sum of their scales.
+func (d Decimal) Mul(d2 Decimal) Decimal {
+	return Decimal{coef: new(big.Int).Mul(d.coefOrZero(), d2.coefOrZero()), scale: d.scale + d2.scale}
+}
+
+// DivRound returns d / d2 rounded half to even to scale decimal places.
+//
+// Panics if d2 is 0.
+func (d Decimal) DivRound(d2 Decimal, scale int32) Decimal {
+	if d2.IsZero() {
+		panic("decimal division by zero")
+	}
+
+	// d / d2 * 10^scale = d.coef * 10^(scale - d.scale + d2.scale) / d2.coef
+	num := new(big.Int).Set(d.coefOrZero())
+	den := new(big.Int).Set(d2.coef)
+
+	if exp := int64(scale) - int64(d.scale) + int64(d2.scale); exp >= 0 {
+		num.Mul(num, pow10(exp))
+	} else {
+		den.Mul(den, pow10(-exp))
+	}
+
+	return newDecimal(quoRoundHalfEven(num, den), scale)
+}
+
+// Round returns d rounded half to even to scale decimal places. If d has fewer,
+// it's padded with zeros.
+func (d Decimal) Round(scale int32) Decimal {
+	if scale >= d.scale {
+		return d.rescale(scale)
+	}
+
+	return d.DivRound(New(1, 0), scale)
+}
+
+// Quantize returns d rounded half to even to a multiple of increment (e.g. a
+// price to a product's quote increment), at the scale of increment.
+//
+// Panics if increment is 0.
+func (d Decimal) Quantize(increment Decimal) Decimal {
+	return d.DivRound(increment, 0).Mul(increment)
+}
+
+// Sqrt returns the square root of d rounded half to even to scale decimal places.
+//
+// Panics if d is negative.
+func (d Decimal) Sqrt(scale int32) Decimal {
+	if d.Sign() < 0 {
+		panic("decimal square root of negative number")
+	}
+
+	// sqrt(d) * 10^scale = sqrt(d.coef * 10^(2*scale - d.scale)) = sqrt(num / den)
+	num := new(big.Int).Set(d.coefOrZero())
+	den := big.NewInt(1)
+
+	if exp := 2*int64(scale) - int64(d.scale); exp >= 0 {
+		num.Mul(num, pow10(exp))
+	} else {
+		den.Mul(den, pow10(-exp))
+	}
+
+	// floor(sqrt(num / den)) = floor(sqrt(floor(num / den))).
+	root := new(big.Int).Sqrt(new(big.Int).Quo(num, den))
+
+	// Round up if sqrt(num / den) > root + 0.5, i.e. 4 * num > (2 * root + 1)^2 * den.
+	half := new(big.Int).Lsh(root, 1)
+	half.Add(half, bigOne)
+	half.Mul(half, half)
+	half.Mul(half, den)
+
+	switch new(big.Int).Lsh(num, 2).Cmp(half) {
+	case 1:
+		root.Add(root, bigOne)
+	case 0:
+		if root.Bit(0) == 1 {
+			root.Add(root, bigOne)
+		}
+	}
+
+	return newDecimal(root, scale)
+}
+
+// String formats d with exactly its scale of decimal places, e.g. "-1.50".
+func (d Decimal) String() string {
+	coef := d.coefOrZero()
+
+	digits := new(big.Int).Abs(coef).String()
+	if d.scale > 0 {
+		if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+
+		digits = digits[:len(digits)-int(d.scale)] + "." + digits[len(digits)-int(d.scale):]
+	}
+
+	if coef.Sign() < 0 {
+		return "-" + digits
+	}
+
+	return digits
+}
+
+// MarshalText formats d as String does, so it's encoded as a string (e.g. in JSON).
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText parses text as Parse does, so d can be decoded from a string (e.g.
+// a JSON string, as Coinbase encodes prices and sizes).
+func (d *Decimal) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+
+	return nil
+}
+
+// coefOrZero returns the coefficient of d, which must not be modified.
+func (d Decimal) coefOrZero() *big.Int {
+	if d.coef == nil {
+		return new(big.Int)
+	}
+
+	return d.coef
+}
+
+// rescale returns d at scale, which must not be less than the scale of d.
+func (d Decimal) rescale(scale int32) Decimal {
+	if scale == d.scale {
+		return d
+	}
+
+	return Decimal{coef: new(big.Int).Mul(d.coefOrZero(), pow10(int64(scale-d.scale))), scale: scale}
+}
+
+// align returns copies of the coefficients of d and d2, at the greater of their
+// scales.
+func align(d, d2 Decimal) (*big.Int, *big.Int) {
+	scale := maxScale(d, d2)
+
+	return new(big.Int).Set(d.rescale(scale).coefOrZero()), new(big.Int).Set(d2.rescale(scale).coefOrZero())
+}
+
+// maxScale returns the greater of the scales of d and d2.
+func maxScale(d, d2 Decimal) int32 {
+	if d.scale > d2.scale {
+		return d.scale
+	}
+
+	return d2.scale
+}
+
+// pow10 returns 10^exp.
+func pow10(exp int64) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(exp), nil)
+}
+
+// quoRoundHalfEven returns num / den rounded half to even.
+func quoRoundHalfEven(num, den *big.Int) *big.Int {
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() == 0 {
+		return quo
+	}
+
+	// Compare the remainder to half the divisor, by magnitude.
+	half := new(big.Int).Abs(rem)
+	half.Lsh(half, 1)
+
+	switch half.Cmp(new(big.Int).Abs(den)) {
+	case -1:
+		return quo
+	case 0:
+		if quo.Bit(0) == 0 {
+			return quo
+		}
+	}
+
+	// Round away from zero, in the direction of the exact quotient.
+	if num.Sign() != den.Sign() {
+		return quo.Sub(quo, bigOne)
+	}
+
+	return quo.Add(quo, bigOne)
+}
@@ -0,0 +1,211 @@
+package decimal
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name          string
+		give          string
+		expected      string
+		expectedScale int32
+		expectedErr   string
+	}{
+		{name: "integer", give: "7", expected: "7"},
+		{name: "fraction", give: "0.05088265", expected: "0.05088265", expectedScale: 8},
+		{name: "trailing_zeros", give: "1.50", expected: "1.50", expectedScale: 2},
+		{name: "negative", give: "-12.345", expected: "-12.345", expectedScale: 3},
+		{name: "positive", give: "+1.5", expected: "1.5", expectedScale: 1},
+		{name: "no_whole", give: ".5", expected: "0.5", expectedScale: 1},
+		{name: "no_fraction", give: "5.", expected: "5"},
+		{name: "negative_zero", give: "-0.0", expected: "0.0", expectedScale: 1},
+		{name: "large", give: "123456789012345678901234567890.123456789", expected: "123456789012345678901234567890.123456789", expectedScale: 9},
+		{name: "empty", give: "", expectedErr: `parse decimal "": invalid syntax`},
+		{name: "sign_only", give: "-", expectedErr: `parse decimal "-": invalid syntax`},
+		{name: "point_only", give: ".", expectedErr: `parse decimal ".": invalid syntax`},
+		{name: "double_sign", give: "--1", expectedErr: `parse decimal "--1": invalid syntax`},
+		{name: "double_point", give: "1.2.3", expectedErr: `parse decimal "1.2.3": invalid syntax`},
+		{name: "exponent", give: "1e5", expectedErr: `parse decimal "1e5": invalid syntax`},
+		{name: "letters", give: "TestABC", expectedErr: `parse decimal "TestABC": invalid syntax`},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Do
+
+			actual, actualErr := Parse(tc.give)
+
+			// Assert
+
+			if tc.expectedErr != "" {
+				assert.EqualError(t, actualErr, tc.expectedErr, "Actual err")
+
+				return
+			}
+
+			assert.NoError(t, actualErr, "Actual err")
+			assert.Equal(t, tc.expected, actual.String(), "Actual")
+			assert.Equal(t, tc.expectedScale, actual.Scale(), "Actual scale")
+		})
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		do       func() Decimal
+		expected string
+	}{
+		{name: "zero_value", do: func() Decimal { return Decimal{} }, expected: "0"},
+		{name: "new", do: func() Decimal { return New(-105, 2) }, expected: "-1.05"},
+		{name: "new_negative_scale", do: func() Decimal { return New(15, -2) }, expected: "1500"},
+		{name: "add", do: func() Decimal { return MustParse("0.1").Add(MustParse("0.2")) }, expected: "0.3"},
+		{name: "add_scales", do: func() Decimal { return MustParse("1.5").Add(MustParse("0.005")) }, expected: "1.505"},
+		{name: "add_zero_value", do: func() Decimal { return Decimal{}.Add(MustParse("2.50")) }, expected: "2.50"},
+		{name: "sub", do: func() Decimal { return MustParse("0.3").Sub(MustParse("0.1")) }, expected: "0.2"},
+		{name: "sub_negative", do: func() Decimal { return MustParse("1").Sub(MustParse("1.25")) }, expected: "-0.25"},
+		{name: "mul", do: func() Decimal { return MustParse("0.05088265").Mul(MustParse("295.96")) }, expected: "15.0592290940"},
+		{name: "neg", do: func() Decimal { return MustParse("1.5").Neg() }, expected: "-1.5"},
+		{name: "div_round", do: func() Decimal { return MustParse("2").DivRound(MustParse("3"), 4) }, expected: "0.6667"},
+		{name: "div_round_exact", do: func() Decimal { return MustParse("1.5").DivRound(MustParse("0.5"), 2) }, expected: "3.00"},
+		{name: "div_round_fewer_places", do: func() Decimal { return MustParse("1.23456").DivRound(MustParse("1"), 2) }, expected: "1.23"},
+		{name: "div_round_negative", do: func() Decimal { return MustParse("-2").DivRound(MustParse("3"), 2) }, expected: "-0.67"},
+		{name: "div_round_half_even_down", do: func() Decimal { return MustParse("1").DivRound(MustParse("8"), 2) }, expected: "0.12"},
+		{name: "div_round_half_even_up", do: func() Decimal { return MustParse("3").DivRound(MustParse("8"), 2) }, expected: "0.38"},
+		{name: "div_round_half_even_negative", do: func() Decimal { return MustParse("-3").DivRound(MustParse("8"), 2) }, expected: "-0.38"},
+		{name: "round", do: func() Decimal { return MustParse("2.675").Round(2) }, expected: "2.68"},
+		{name: "round_half_even", do: func() Decimal { return MustParse("2.665").Round(2) }, expected: "2.66"},
+		{name: "round_pads", do: func() Decimal { return MustParse("2.6").Round(3) }, expected: "2.600"},
+		{name: "round_whole", do: func() Decimal { return MustParse("-2.5").Round(0) }, expected: "-2"},
+		{name: "quantize", do: func() Decimal { return MustParse("4.666666").Quantize(MustParse("0.01")) }, expected: "4.67"},
+		{name: "quantize_pads", do: func() Decimal { return MustParse("6").Quantize(MustParse("0.00001")) }, expected: "6.00000"},
+		{name: "quantize_non_power_of_ten", do: func() Decimal { return MustParse("1.13").Quantize(MustParse("0.05")) }, expected: "1.15"},
+		{name: "quantize_whole", do: func() Decimal { return MustParse("1234.5").Quantize(MustParse("10")) }, expected: "1230"},
+		{name: "sqrt", do: func() Decimal { return MustParse("2").Sqrt(4) }, expected: "1.4142"},
+		{name: "sqrt_exact", do: func() Decimal { return MustParse("4").Sqrt(2) }, expected: "2.00"},
+		{name: "sqrt_zero_value", do: func() Decimal { return Decimal{}.Sqrt(2) }, expected: "0.00"},
+		{name: "sqrt_round_up", do: func() Decimal { return MustParse("3").Sqrt(0) }, expected: "2"},
+		{name: "sqrt_half_even_up", do: func() Decimal { return MustParse("2.25").Sqrt(0) }, expected: "2"},
+		{name: "sqrt_half_even_down", do: func() Decimal { return MustParse("6.25").Sqrt(0) }, expected: "2"},
+		{name: "sqrt_more_places", do: func() Decimal { return MustParse("0.001").Sqrt(3) }, expected: "0.032"},
+		{name: "sqrt_fewer_places", do: func() Decimal { return MustParse("0.0036").Sqrt(1) }, expected: "0.1"},
+		{name: "sqrt_fewer_places_down", do: func() Decimal { return MustParse("0.0009").Sqrt(1) }, expected: "0.0"},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Do
+
+			actual := tc.do()
+
+			// Assert
+
+			assert.Equal(t, tc.expected, actual.String(), "Actual")
+		})
+	}
+}
+
+func TestDecimalDivRoundByZero(t *testing.T) {
+	t.Parallel()
+
+	assert.PanicsWithValue(t, "decimal division by zero", func() { MustParse("1").DivRound(Decimal{}, 2) }, "DivRound")
+}
+
+func TestDecimalSqrtNegative(t *testing.T) {
+	t.Parallel()
+
+	assert.PanicsWithValue(t, "decimal square root of negative number", func() { MustParse("-1").Sqrt(2) }, "Sqrt")
+}
+
+func TestDecimalCmp(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		give     Decimal
+		giveWith Decimal
+		expected int
+	}{
+		{name: "less", give: MustParse("1.49"), giveWith: MustParse("1.5"), expected: -1},
+		{name: "equal_scales", give: MustParse("1.50"), giveWith: MustParse("1.5"), expected: 0},
+		{name: "greater", give: MustParse("-1"), giveWith: MustParse("-1.01"), expected: 1},
+		{name: "zero_value", give: Decimal{}, giveWith: MustParse("0.000"), expected: 0},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Do
+
+			actual := tc.give.Cmp(tc.giveWith)
+
+			// Assert
+
+			assert.Equal(t, tc.expected, actual, "Actual")
+		})
+	}
+}
+
+func TestDecimalImmutable(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	d := MustParse("1.5")
+	d2 := MustParse("2.25")
+
+	// Do
+
+	_ = d.Add(d2)
+	_ = d.Sub(d2)
+	_ = d.Mul(d2)
+	_ = d.Neg()
+	_ = d.DivRound(d2, 4)
+	_ = d.Round(0)
+	_ = d.Quantize(d2)
+
+	// Assert
+
+	assert.Equal(t, "1.5", d.String(), "d")
+	assert.Equal(t, "2.25", d2.String(), "d2")
+}
+
+func TestDecimalJSON(t *testing.T) {
+	t.Parallel()
+
+	type prices struct {
+		Price Decimal `json:"price"`
+	}
+
+	// Do
+
+	var actual prices
+	err := json.Unmarshal([]byte(`{"price":"19000.010"}`), &actual)
+
+	// Assert
+
+	if assert.NoError(t, err, "Unmarshal") {
+		assert.Equal(t, "19000.010", actual.Price.String(), "Unmarshalled")
+	}
+
+	encoded, err := json.Marshal(actual)
+	if assert.NoError(t, err, "Marshal") {
+		assert.Equal(t, `{"price":"19000.010"}`, string(encoded), "Marshalled")
+	}
+
+	err = json.Unmarshal([]byte(`{"price":"TestABC"}`), &actual)
+	assert.EqualError(t, err, `parse decimal "TestABC": invalid syntax`, "Unmarshal invalid")
+}
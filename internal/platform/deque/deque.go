@@ -0,0 +1,127 @@
+// package deque provides a double-ended queue, backed by a ring buffer that grows
+// as needed.
+package deque
+
+// Deque is a double-ended queue of values. Pushing to the back and popping from
+// the front (or back) are amortised O(1).
+//
+// The zero-value of this type is an empty Deque, ready to use.
+type Deque[T any] struct {
+	values     []T
+	len        int
+	startIndex int
+}
+
+// New creates a new Deque with room for capacity values before it grows.
+func New[T any](capacity int) *Deque[T] {
+	return &Deque[T]{
+		values: make([]T, capacity),
+	}
+}
+
+// Len returns the number of values in the Deque.
+func (d *Deque[T]) Len() int {
+	return d.len
+}
+
+// At returns the value at index a, where 0 is the front.
+//
+// Panics if a is out of range with respect to the length of the Deque.
+func (d *Deque[T]) At(a int) T {
+	if a >= d.len || a < 0 {
+		panic("index out of range")
+	}
+
+	return d.values[d.index(a)]
+}
+
+// Front returns the value at the front of the Deque.
+//
+// Panics if the Deque is empty.
+func (d *Deque[T]) Front() T {
+	return d.At(0)
+}
+
+// Back returns the value at the back of the Deque.
+//
+// Panics if the Deque is empty.
+func (d *Deque[T]) Back() T {
+	return d.At(d.len - 1)
+}
+
+// PushBack appends v to the back of the Deque.
+func (d *Deque[T]) PushBack(v T) {
+	d.grow()
+
+	d.values[d.index(d.len)] = v
+	d.len++
+}
+
+// Insert inserts v at index a, moving the values from a onwards back by one. a
+// may be the length of the Deque, to push v to the back. Inserting near the back
+// is cheapest.
+//
+// Panics if a is out of range with respect to the length of the Deque.
+func (d *Deque[T]) Insert(a int, v T) {
+	if a > d.len || a < 0 {
+		panic("index out of range")
+	}
+
+	d.grow()
+	d.len++
+
+	for b := d.len - 1; b > a; b-- {
+		d.values[d.index(b)] = d.values[d.index(b-1)]
+	}
+
+	d.values[d.index(a)] = v
+}
+
+// PopFront removes and returns the value at the front of the Deque.
+//
+// Panics if the Deque is empty.
+func (d *Deque[T]) PopFront() T {
+	v := d.Front()
+
+	var zero T
+	d.values[d.startIndex] = zero // Don't retain the value.
+
+	d.startIndex = d.index(1)
+	d.len--
+
+	return v
+}
+
+// PopBack removes and returns the value at the back of the Deque.
+//
+// Panics if the Deque is empty.
+func (d *Deque[T]) PopBack() T {
+	v := d.Back()
+
+	var zero T
+	d.values[d.index(d.len-1)] = zero // Don't retain the value.
+
+	d.len--
+
+	return v
+}
+
+// index returns the index in d.values of index a of the Deque.
+func (d *Deque[T]) index(a int) int {
+	return (d.startIndex + a) % len(d.values)
+}
+
+// grow makes room for at least one more value.
+func (d *Deque[T]) grow() {
+	if d.len < len(d.values) {
+		return
+	}
+
+	values := make([]T, 2*len(d.values)+1)
+	for a := 0; a < d.len; a++ {
+		values[a] = d.values[d.index(a)]
+	}
+
+	d.values = values
+	d.startIndex = 0
+}
@@ -0,0 +1,180 @@
+package deque
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// values returns the values of d, front to back.
+func values(d *Deque[int]) []int {
+	v := make([]int, d.Len())
+	for a := range v {
+		v[a] = d.At(a)
+	}
+
+	return v
+}
+
+func TestDeque(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		with     *Deque[int]
+		do       func(d *Deque[int])
+		expected []int
+	}{
+		{
+			name:     "zero_value",
+			with:     &Deque[int]{},
+			do:       func(d *Deque[int]) {},
+			expected: []int{},
+		},
+		{
+			name: "push_back_zero_value",
+			with: &Deque[int]{},
+			do: func(d *Deque[int]) {
+				for a := 0; a < 5; a++ {
+					d.PushBack(a)
+				}
+			},
+			expected: []int{0, 1, 2, 3, 4},
+		},
+		{
+			name: "push_back_grows",
+			with: New[int](2),
+			do: func(d *Deque[int]) {
+				for a := 0; a < 5; a++ {
+					d.PushBack(a)
+				}
+			},
+			expected: []int{0, 1, 2, 3, 4},
+		},
+		{
+			name: "pop_front",
+			with: New[int](3),
+			do: func(d *Deque[int]) {
+				d.PushBack(1)
+				d.PushBack(2)
+				d.PushBack(3)
+				d.PopFront()
+			},
+			expected: []int{2, 3},
+		},
+		{
+			name: "pop_back",
+			with: New[int](3),
+			do: func(d *Deque[int]) {
+				d.PushBack(1)
+				d.PushBack(2)
+				d.PushBack(3)
+				d.PopBack()
+			},
+			expected: []int{1, 2},
+		},
+		{
+			name: "wraps_around",
+			with: New[int](3),
+			do: func(d *Deque[int]) {
+				for a := 0; a < 10; a++ {
+					d.PushBack(a)
+
+					if d.Len() > 2 {
+						d.PopFront()
+					}
+				}
+			},
+			expected: []int{8, 9},
+		},
+		{
+			name: "grows_wrapped_around",
+			with: New[int](3),
+			do: func(d *Deque[int]) {
+				d.PushBack(0)
+				d.PushBack(1)
+				d.PopFront()
+				d.PushBack(2)
+				d.PushBack(3) // Wrapped around.
+				d.PushBack(4) // Grows.
+			},
+			expected: []int{1, 2, 3, 4},
+		},
+		{
+			name: "insert",
+			with: New[int](3),
+			do: func(d *Deque[int]) {
+				d.PushBack(1)
+				d.PushBack(3)
+				d.Insert(1, 2)
+				d.Insert(0, 0)
+				d.Insert(4, 4)
+			},
+			expected: []int{0, 1, 2, 3, 4},
+		},
+		{
+			name: "insert_wrapped_around",
+			with: New[int](4),
+			do: func(d *Deque[int]) {
+				d.PushBack(0)
+				d.PushBack(0)
+				d.PushBack(1)
+				d.PopFront()
+				d.PopFront()
+				d.PushBack(3)
+				d.PushBack(4) // Wrapped around.
+				d.Insert(1, 2)
+			},
+			expected: []int{1, 2, 3, 4},
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.do(tc.with)
+
+			assert.Equal(t, tc.expected, values(tc.with))
+		})
+	}
+}
+
+func TestDequeFrontBack(t *testing.T) {
+	t.Parallel()
+
+	d := New[int](1)
+	d.PushBack(1)
+	d.PushBack(2)
+
+	assert.Equal(t, 1, d.Front(), "Front")
+	assert.Equal(t, 2, d.Back(), "Back")
+	assert.Equal(t, 1, d.PopFront(), "PopFront")
+	assert.Equal(t, 2, d.PopBack(), "PopBack")
+	assert.Equal(t, 0, d.Len(), "Len")
+}
+
+func TestDequePanics(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		do   func(d *Deque[int])
+	}{
+		{name: "at", do: func(d *Deque[int]) { d.At(0) }},
+		{name: "at_negative", do: func(d *Deque[int]) { d.At(-1) }},
+		{name: "front", do: func(d *Deque[int]) { d.Front() }},
+		{name: "back", do: func(d *Deque[int]) { d.Back() }},
+		{name: "pop_front", do: func(d *Deque[int]) { d.PopFront() }},
+		{name: "pop_back", do: func(d *Deque[int]) { d.PopBack() }},
+		{name: "insert", do: func(d *Deque[int]) { d.Insert(1, 1) }},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.PanicsWithValue(t, "index out of range", func() { tc.do(&Deque[int]{}) })
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package vwap
+
+import (
+	"time"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+// AnchorVWAP is the VWAP of the trades since an anchor time.
+type AnchorVWAP struct {
+	// When the VWAP started, e.g. the open of a session.
+	Anchor time.Time
+
+	// The VWAP, or 0 if no units have been traded since the anchor.
+	VWAP decimal.Decimal
+
+	// The number of trades since the anchor.
+	Trades int
+}
+
+// AnchoredVWAP calculates a VWAP over every trade since an anchor time (e.g. a
+// session open, or the time of an event), using exact decimal arithmetic. Unlike
+// a window, no trades are evicted, so none are stored.
+//
+// The zero-value of this type has no increment, and therefore no utility.
+type AnchoredVWAP struct {
+	// Trades before this are ignored.
+	anchor time.Time
+
+	// The VWAP is rounded to a multiple of this.
+	increment decimal.Decimal
+
+	// The number of trades since the anchor.
+	trades int
+
+	// a cumulative total for all units traded since the anchor.
+	totalUnits decimal.Decimal
+
+	// a cumulative price total traded since the anchor.
+	totalPrice decimal.Decimal
+}
+
+// NewAnchoredVWAP creates a new AnchoredVWAP over trades at or after anchor. Each
+// VWAP is rounded (half to even) to a multiple of increment, which must be greater
+// than 0.
+func NewAnchoredVWAP(anchor time.Time, increment decimal.Decimal) *AnchoredVWAP {
+	return &AnchoredVWAP{
+		anchor:    anchor,
+		increment: increment,
+	}
+}
+
+// Add records a new trade (the number of units traded and the price paid per unit)
+// that happened at. Trades before the anchor are ignored. The return value is the
+// new VWAP.
+func (a *AnchoredVWAP) Add(at time.Time, units, unitPrice decimal.Decimal) AnchorVWAP {
+	if a.increment.IsZero() {
+		return AnchorVWAP{}
+	}
+
+	if !at.Before(a.anchor) {
+		a.trades++
+		a.totalUnits = a.totalUnits.Add(units)
+		a.totalPrice = a.totalPrice.Add(units.Mul(unitPrice))
+	}
+
+	return a.VWAP()
+}
+
+// VWAP returns the VWAP of the trades since the anchor.
+func (a *AnchoredVWAP) VWAP() AnchorVWAP {
+	if a.increment.IsZero() {
+		return AnchorVWAP{}
+	}
+
+	return AnchorVWAP{
+		Anchor: a.anchor,
+		VWAP:   roundedVWAP(a.totalPrice, a.totalUnits, a.increment),
+		Trades: a.trades,
+	}
+}
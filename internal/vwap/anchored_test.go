@@ -0,0 +1,86 @@
+package vwap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+func TestAnchoredVWAPAdd(t *testing.T) {
+	t.Parallel()
+
+	anchor := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name           string
+		giveTrades     []timedTrade
+		expected       string
+		expectedTrades int
+	}{
+		{
+			name:           "one_trade",
+			giveTrades:     []timedTrade{{after: time.Minute, units: "2.5", unitPrice: "1.2"}},
+			expected:       "1.20",
+			expectedTrades: 1,
+		},
+		{
+			name: "at_anchor",
+			giveTrades: []timedTrade{
+				{after: 0, units: "1", unitPrice: "1"},
+				{after: time.Hour * 30, units: "3", unitPrice: "2"},
+			},
+			expected:       "1.75", // 7 total price / 4 total units
+			expectedTrades: 2,
+		},
+		{
+			name: "ignores_before_anchor",
+			giveTrades: []timedTrade{
+				{after: -time.Nanosecond, units: "10", unitPrice: "5"},
+				{after: time.Minute, units: "1", unitPrice: "2"},
+			},
+			expected:       "2.00",
+			expectedTrades: 1,
+		},
+		{
+			name:       "none",
+			giveTrades: []timedTrade{{after: -time.Minute, units: "1", unitPrice: "2"}},
+			expected:   "0.00",
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+
+			a := NewAnchoredVWAP(anchor, decimal.MustParse("0.01"))
+
+			// Do
+
+			var actual AnchorVWAP
+			for _, trade := range tc.giveTrades {
+				actual = a.Add(anchor.Add(trade.after), decimal.MustParse(trade.units), decimal.MustParse(trade.unitPrice))
+			}
+
+			// Assert
+
+			assert.Equal(t, anchor, actual.Anchor, "Actual anchor")
+			assert.Equal(t, tc.expected, actual.VWAP.String(), "Actual")
+			assert.Equal(t, tc.expectedTrades, actual.Trades, "Actual trades")
+			assert.Equal(t, actual, a.VWAP(), "VWAP")
+		})
+	}
+}
+
+func TestAnchoredVWAPZeroValue(t *testing.T) {
+	t.Parallel()
+
+	a := &AnchoredVWAP{}
+
+	assert.Equal(t, AnchorVWAP{}, a.Add(time.Now(), decimal.MustParse("1"), decimal.MustParse("1")), "Add")
+	assert.Equal(t, AnchorVWAP{}, a.VWAP(), "VWAP")
+}
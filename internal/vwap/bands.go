@@ -0,0 +1,80 @@
+package vwap
+
+import (
+	"math"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+// Bands are the volume-weighted standard deviation bands around a VWAP.
+type Bands struct {
+	// The volume-weighted variance of the prices traded.
+	Variance float64
+
+	// The volume-weighted standard deviation of the prices traded (σ).
+	StdDev float64
+
+	// The VWAP plus and minus 1σ, 2σ and 3σ, in that order.
+	Upper, Lower [3]float64
+}
+
+// newBands returns the bands of vwap, given the variance of the prices traded.
+func newBands(vwap, variance float64) Bands {
+	// Rounding error could otherwise make a variance of 0 negative.
+	if variance < 0 {
+		variance = 0
+	}
+
+	b := Bands{Variance: variance, StdDev: math.Sqrt(variance)}
+	for a := range b.Upper {
+		b.Upper[a] = vwap + float64(a+1)*b.StdDev
+		b.Lower[a] = vwap - float64(a+1)*b.StdDev
+	}
+
+	return b
+}
+
+// DecimalBands are Bands calculated with exact decimal arithmetic.
+type DecimalBands struct {
+	// The volume-weighted variance of the prices traded, rounded to twice the
+	// increment's decimal places.
+	Variance decimal.Decimal
+
+	// The volume-weighted standard deviation of the prices traded (σ).
+	StdDev decimal.Decimal
+
+	// The VWAP plus and minus 1σ, 2σ and 3σ, in that order.
+	Upper, Lower [3]decimal.Decimal
+}
+
+// bandsPrecision is the number of decimal places beyond those of the increment the
+// VWAP and standard deviation are calculated to, before the bands are rounded.
+const bandsPrecision = 8
+
+// roundedBands returns the bands of trades with the totals given, where totalPriceSquared
+// is the sum of units * unitPrice^2, each rounded (half to even) to a multiple of
+// increment. If totalUnits is 0, they're all 0.
+func roundedBands(totalPrice, totalPriceSquared, totalUnits, increment decimal.Decimal) DecimalBands {
+	scale := increment.Scale() + bandsPrecision
+
+	var vwap, variance decimal.Decimal
+
+	if !totalUnits.IsZero() {
+		vwap = totalPrice.DivRound(totalUnits, scale)
+
+		// The mean of the squares less the square of the mean, over one division.
+		variance = totalPriceSquared.Mul(totalUnits).Sub(totalPrice.Mul(totalPrice)).DivRound(totalUnits.Mul(totalUnits), 2*scale)
+	}
+
+	stdDev := variance.Sqrt(scale)
+
+	b := DecimalBands{Variance: variance.Round(2 * increment.Scale()), StdDev: stdDev.Quantize(increment)}
+	for a := range b.Upper {
+		offset := stdDev.Mul(decimal.New(int64(a+1), 0))
+
+		b.Upper[a] = vwap.Add(offset).Quantize(increment)
+		b.Lower[a] = vwap.Sub(offset).Quantize(increment)
+	}
+
+	return b
+}
@@ -0,0 +1,98 @@
+package vwap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+func TestRoundedBands(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name                  string
+		giveTotalPrice        string
+		giveTotalPriceSquared string
+		giveTotalUnits        string
+		giveIncrement         string
+		expected              []string // Variance, std dev, upper bands, lower bands.
+	}{
+		{
+			name:                  "no_units",
+			giveTotalPrice:        "0",
+			giveTotalPriceSquared: "0",
+			giveTotalUnits:        "0",
+			giveIncrement:         "0.01",
+			expected:              []string{"0.0000", "0.00", "0.00", "0.00", "0.00", "0.00", "0.00", "0.00"},
+		},
+		{
+			name:                  "one_price",
+			giveTotalPrice:        "3.00",
+			giveTotalPriceSquared: "4.5000",
+			giveTotalUnits:        "2",
+			giveIncrement:         "0.01",
+			expected:              []string{"0.0000", "0.00", "1.50", "1.50", "1.50", "1.50", "1.50", "1.50"},
+		},
+		{
+			name:                  "exact",
+			giveTotalPrice:        "3", // 1 unit at 1, 1 unit at 2.
+			giveTotalPriceSquared: "5",
+			giveTotalUnits:        "2",
+			giveIncrement:         "0.01",
+			expected:              []string{"0.2500", "0.50", "2.00", "2.50", "3.00", "1.00", "0.50", "0.00"},
+		},
+		{
+			name:                  "weighted",
+			giveTotalPrice:        "8", // 3 units at 1, 1 unit at 5.
+			giveTotalPriceSquared: "28",
+			giveTotalUnits:        "4",
+			giveIncrement:         "0.01",
+			expected:              []string{"3.0000", "1.73", "3.73", "5.46", "7.20", "0.27", "-1.46", "-3.20"},
+		},
+		{
+			name:                  "non_power_of_ten_increment",
+			giveTotalPrice:        "8",
+			giveTotalPriceSquared: "28",
+			giveTotalUnits:        "4",
+			giveIncrement:         "0.05",
+			expected:              []string{"3.0000", "1.75", "3.75", "5.45", "7.20", "0.25", "-1.45", "-3.20"},
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Do
+
+			actual := roundedBands(
+				decimal.MustParse(tc.giveTotalPrice),
+				decimal.MustParse(tc.giveTotalPriceSquared),
+				decimal.MustParse(tc.giveTotalUnits),
+				decimal.MustParse(tc.giveIncrement),
+			)
+
+			// Assert
+
+			assert.Equal(t, tc.expected, bandsStrings(actual), "Actual")
+		})
+	}
+}
+
+// bandsStrings formats the variance, standard deviation, upper bands and lower
+// bands of b.
+func bandsStrings(b DecimalBands) []string {
+	s := []string{b.Variance.String(), b.StdDev.String()}
+
+	for _, band := range b.Upper {
+		s = append(s, band.String())
+	}
+
+	for _, band := range b.Lower {
+		s = append(s, band.String())
+	}
+
+	return s
+}
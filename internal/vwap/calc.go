@@ -1,8 +1,13 @@
-// package vwap provides a way to calculate a volume-weighted average price in a
-// sliding window of trades.
+// package vwap provides ways to calculate a volume-weighted average price in a
+// sliding window of trades, of a number of trades or a duration of time, or since
+// an anchor time such as the start of a session.
 package vwap
 
-import "github.com/byatesrae/coinbase_vwap/internal/platform/slidingslice"
+import (
+	"math"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/slidingslice"
+)
 
 // position represents a single trade (buy or sell).
 type position struct {
@@ -18,7 +23,41 @@ func (p position) TotalPrice() float64 {
 	return p.units * p.unitPrice
 }
 
-// SlidingWindowVWAP uses a sliding window of positions to calculate a VWAP.
+// compensatedSum is a running float64 sum that keeps track of the rounding error of
+// each addition (Kahan-Babuška-Neumaier summation), so that error doesn't build
+// up over many additions and subtractions.
+type compensatedSum struct {
+	sum float64
+
+	// The rounding error lost from sum so far.
+	compensation float64
+}
+
+// add adds v to the sum.
+func (c *compensatedSum) add(v float64) {
+	t := c.sum + v
+
+	// The low-order digits of the smaller operand are the ones lost.
+	if math.Abs(c.sum) >= math.Abs(v) {
+		c.compensation += (c.sum - t) + v
+	} else {
+		c.compensation += (v - t) + c.sum
+	}
+
+	c.sum = t
+}
+
+// value returns the sum.
+func (c *compensatedSum) value() float64 {
+	return c.sum + c.compensation
+}
+
+// SlidingWindowVWAP uses a sliding window of positions to calculate a VWAP, and
+// its standard deviation bands.
+//
+// Its running totals are kept with compensated summation, and recomputed from
+// the positions in the window each time it has slid its whole capacity, so
+// floating point error doesn't accumulate however many trades are added.
 //
 // The zero-value of this type has no capacity, and therefore no utility.
 type SlidingWindowVWAP struct {
@@ -26,10 +65,24 @@ type SlidingWindowVWAP struct {
 	positions *slidingslice.SlidingSlice[*position]
 
 	// a cumulative total for all units traded in the window.
-	totalUnits float64
+	totalUnits compensatedSum
 
 	// a cumulative price total traded in the window.
-	totalPrice float64
+	totalPrice compensatedSum
+
+	// Prices deviate from this for the variance, near the VWAP, so its precision
+	// isn't lost to the difference of large squares. Updated as the totals are
+	// recomputed.
+	shift float64
+
+	// a cumulative total of units * (unitPrice - shift) traded in the window.
+	totalDeviation compensatedSum
+
+	// a cumulative total of units * (unitPrice - shift)^2 traded in the window.
+	totalDeviationSquared compensatedSum
+
+	// The number of positions popped since the totals were last recomputed.
+	poppedSinceRecompute int
 }
 
 // NewSlidingWindowVWAP creates a new SlidingWindowVWAP with the specified capacity.
@@ -53,16 +106,67 @@ func (s *SlidingWindowVWAP) Add(units, unitPrice float64) float64 {
 		poppedValue = s.positions.At(0)
 	}
 
+	if s.positions.Len() == 0 {
+		s.shift = unitPrice
+	}
+
 	pushedValue := &position{units: units, unitPrice: unitPrice}
 	s.positions.Push(pushedValue)
 
 	if poppedValue != nil {
-		s.totalUnits -= poppedValue.units
-		s.totalPrice -= poppedValue.TotalPrice()
+		s.poppedSinceRecompute++
 	}
 
-	s.totalUnits += pushedValue.units
-	s.totalPrice += pushedValue.TotalPrice()
+	if s.poppedSinceRecompute >= s.positions.Cap() {
+		s.recompute()
+	} else {
+		if poppedValue != nil {
+			s.addToTotals(poppedValue, -1)
+		}
+
+		s.addToTotals(pushedValue, 1)
+	}
+
+	return s.totalPrice.value() / s.totalUnits.value()
+}
+
+// Bands returns the standard deviation bands of the VWAP of the window.
+func (s *SlidingWindowVWAP) Bands() Bands {
+	totalUnits := s.totalUnits.value()
+	if s.positions == nil || totalUnits == 0 {
+		return Bands{}
+	}
+
+	meanDeviation := s.totalDeviation.value() / totalUnits
+
+	return newBands(s.totalPrice.value()/totalUnits, s.totalDeviationSquared.value()/totalUnits-meanDeviation*meanDeviation)
+}
+
+// addToTotals adds p to the totals, or subtracts it if sign is -1.
+func (s *SlidingWindowVWAP) addToTotals(p *position, sign float64) {
+	deviation := p.unitPrice - s.shift
+
+	s.totalUnits.add(sign * p.units)
+	s.totalPrice.add(sign * p.TotalPrice())
+	s.totalDeviation.add(sign * p.units * deviation)
+	s.totalDeviationSquared.add(sign * p.units * deviation * deviation)
+}
+
+// recompute recomputes the totals from the positions in the window, discarding
+// any error accumulated by adding and subtracting them.
+func (s *SlidingWindowVWAP) recompute() {
+	if totalUnits := s.totalUnits.value(); totalUnits != 0 {
+		s.shift = s.totalPrice.value() / totalUnits
+	}
+
+	s.totalUnits = compensatedSum{}
+	s.totalPrice = compensatedSum{}
+	s.totalDeviation = compensatedSum{}
+	s.totalDeviationSquared = compensatedSum{}
+
+	for a := 0; a < s.positions.Len(); a++ {
+		s.addToTotals(s.positions.At(a), 1)
+	}
 
-	return s.totalPrice / s.totalUnits
+	s.poppedSinceRecompute = 0
 }
@@ -1,9 +1,12 @@
 package vwap
 
 import (
+	"math"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSlidingWindowVWAPAdd(t *testing.T) {
@@ -106,3 +109,199 @@ func TestSlidingWindowVWAPAdd(t *testing.T) {
 		})
 	}
 }
+
+func TestSlidingWindowVWAPDrift(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip("Adds 10M trades")
+	}
+
+	// Setup
+
+	const (
+		windowCapacity = 200
+		trades         = 10_000_000
+
+		// Relative to the VWAP, and the standard deviation.
+		tolerance       = 1e-12
+		stdDevTolerance = 1e-9
+	)
+
+	s := NewSlidingWindowVWAP(windowCapacity)
+
+	// The same positions as the window, to recompute from naively.
+	window := make([]position, windowCapacity)
+
+	random := rand.New(rand.NewSource(1))
+
+	// Do & Assert
+
+	for a := 0; a < trades; a++ {
+		// Mostly from 0.0001 to 1 units, with the occasional much larger trade that
+		// leaves rounding error behind in the totals when it slides out the window.
+		units := math.Pow(10, random.Float64()*4-4)
+		if random.Intn(10_000) == 0 {
+			units = 100_000 + random.Float64()
+		}
+
+		unitPrice := 20000 + random.NormFloat64()*100
+
+		window[a%windowCapacity] = position{units: units, unitPrice: unitPrice}
+
+		actual := s.Add(units, unitPrice)
+
+		if a%100_000 != 0 && a != trades-1 {
+			continue
+		}
+
+		var totalUnits, totalPrice float64
+		for _, p := range window[:int(math.Min(float64(a+1), windowCapacity))] {
+			totalUnits += p.units
+			totalPrice += p.TotalPrice()
+		}
+
+		expected := totalPrice / totalUnits
+
+		require.LessOrEqual(t, math.Abs(actual-expected)/expected, tolerance, "Trade %d: %v, expected %v", a, actual, expected)
+
+		var totalSquaredDeviation float64
+		for _, p := range window[:int(math.Min(float64(a+1), windowCapacity))] {
+			totalSquaredDeviation += p.units * (p.unitPrice - expected) * (p.unitPrice - expected)
+		}
+
+		actualStdDev := s.Bands().StdDev
+		expectedStdDev := math.Sqrt(totalSquaredDeviation / totalUnits)
+
+		require.InDelta(t, expectedStdDev, actualStdDev, expectedStdDev*stdDevTolerance, "Trade %d: stddev", a)
+	}
+}
+
+func TestSlidingWindowVWAPBands(t *testing.T) {
+	t.Parallel()
+
+	newWithAdds := func(windowCapacity int, positions []position) *SlidingWindowVWAP {
+		s := NewSlidingWindowVWAP(windowCapacity)
+
+		for _, position := range positions {
+			s.Add(position.units, position.unitPrice)
+		}
+
+		return s
+	}
+
+	for _, tc := range []struct {
+		name     string
+		with     *SlidingWindowVWAP
+		expected Bands
+	}{
+		{
+			name:     "zero_value",
+			with:     &SlidingWindowVWAP{},
+			expected: Bands{},
+		},
+		{
+			name:     "empty",
+			with:     NewSlidingWindowVWAP(5),
+			expected: Bands{},
+		},
+		{
+			name:     "one_trade",
+			with:     newWithAdds(5, []position{{units: 2.5, unitPrice: 1.2}}),
+			expected: Bands{Upper: [3]float64{1.2, 1.2, 1.2}, Lower: [3]float64{1.2, 1.2, 1.2}},
+		},
+		{
+			name: "weighted",
+			with: newWithAdds(5, []position{{units: 3, unitPrice: 1}, {units: 1, unitPrice: 5}}),
+			expected: Bands{ // VWAP 2, variance (3*1 + 1*25) / 4 - 2^2
+				Variance: 3,
+				StdDev:   math.Sqrt(3),
+				Upper:    [3]float64{2 + math.Sqrt(3), 2 + 2*math.Sqrt(3), 2 + 3*math.Sqrt(3)},
+				Lower:    [3]float64{2 - math.Sqrt(3), 2 - 2*math.Sqrt(3), 2 - 3*math.Sqrt(3)},
+			},
+		},
+		{
+			name: "slides",
+			with: newWithAdds(3, []position{
+				{units: 100, unitPrice: 50},
+				{units: 1, unitPrice: 1},
+				{units: 1, unitPrice: 2},
+				{units: 1, unitPrice: 3},
+			}),
+			expected: Bands{ // VWAP 2, variance (1 + 0 + 1) / 3
+				Variance: 2.0 / 3,
+				StdDev:   math.Sqrt(2.0 / 3),
+				Upper:    [3]float64{2 + math.Sqrt(2.0/3), 2 + 2*math.Sqrt(2.0/3), 2 + 3*math.Sqrt(2.0/3)},
+				Lower:    [3]float64{2 - math.Sqrt(2.0/3), 2 - 2*math.Sqrt(2.0/3), 2 - 3*math.Sqrt(2.0/3)},
+			},
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := tc.with.Bands()
+
+			assert.InDelta(t, tc.expected.Variance, actual.Variance, 1e-12, "Variance")
+			assert.InDelta(t, tc.expected.StdDev, actual.StdDev, 1e-12, "StdDev")
+			assert.InDeltaSlice(t, tc.expected.Upper[:], actual.Upper[:], 1e-12, "Upper")
+			assert.InDeltaSlice(t, tc.expected.Lower[:], actual.Lower[:], 1e-12, "Lower")
+		})
+	}
+}
+
+func TestSlidingWindowVWAPBandsLargePrices(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	s := NewSlidingWindowVWAP(200)
+
+	// Do
+
+	// Enough to recompute the totals a few times.
+	for a := 0; a < 1000; a++ {
+		s.Add(1, 20000.01+0.01*float64(a%2))
+	}
+
+	// Assert
+
+	// The squares of the prices are large enough to lose much of this to the
+	// difference of them.
+	assert.InDelta(t, 0.005, s.Bands().StdDev, 1e-9, "StdDev")
+}
+
+func TestCompensatedSum(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		give     []float64
+		expected float64
+	}{
+		{name: "empty", expected: 0},
+		{name: "small_after_large", give: []float64{1e16, 1, -1e16}, expected: 1},
+		{name: "large_after_small", give: []float64{1, 1e16, -1e16}, expected: 1},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+
+			var sum compensatedSum
+
+			// Do
+
+			for _, v := range tc.give {
+				sum.add(v)
+			}
+
+			// Assert
+
+			assert.Equal(t, tc.expected, sum.value(), "Value")
+		})
+	}
+}
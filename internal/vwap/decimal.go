@@ -0,0 +1,115 @@
+package vwap
+
+import (
+	"fmt"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+	"github.com/byatesrae/coinbase_vwap/internal/platform/slidingslice"
+)
+
+// decimalPosition represents a single trade (buy or sell), with exact values.
+type decimalPosition struct {
+	// The number of units traded.
+	units decimal.Decimal
+
+	// The total price of the trade.
+	totalPrice decimal.Decimal
+
+	// The total price of the trade multiplied by the price per unit, for the variance.
+	totalPriceSquared decimal.Decimal
+}
+
+// newDecimalPosition creates the decimalPosition of a trade of units at unitPrice.
+func newDecimalPosition(units, unitPrice decimal.Decimal) decimalPosition {
+	totalPrice := units.Mul(unitPrice)
+
+	return decimalPosition{units: units, totalPrice: totalPrice, totalPriceSquared: totalPrice.Mul(unitPrice)}
+}
+
+// DecimalSlidingWindowVWAP is a SlidingWindowVWAP that uses exact decimal arithmetic,
+// so the same trades always give the same VWAP, bit-for-bit. Totals are exact,
+// only the VWAP (and bands) returned are rounded.
+//
+// The zero-value of this type has no capacity, and therefore no utility.
+type DecimalSlidingWindowVWAP struct {
+	// all positions
+	positions *slidingslice.SlidingSlice[*decimalPosition]
+
+	// The VWAP is rounded to a multiple of this.
+	increment decimal.Decimal
+
+	// a cumulative total for all units traded in the window.
+	totalUnits decimal.Decimal
+
+	// a cumulative price total traded in the window.
+	totalPrice decimal.Decimal
+
+	// a cumulative total of price * unit price traded in the window.
+	totalPriceSquared decimal.Decimal
+}
+
+// NewDecimalSlidingWindowVWAP creates a new DecimalSlidingWindowVWAP with the specified
+// capacity. Each VWAP is rounded (half to even) to a multiple of increment, e.g.
+// the product's quote increment, which must be greater than 0.
+func NewDecimalSlidingWindowVWAP(windowCapacity int, increment decimal.Decimal) (*DecimalSlidingWindowVWAP, error) {
+	if increment.Sign() <= 0 {
+		return nil, fmt.Errorf("increment %v must be positive", increment)
+	}
+
+	return &DecimalSlidingWindowVWAP{
+		positions: slidingslice.New[*decimalPosition](windowCapacity),
+		increment: increment,
+	}, nil
+}
+
+// Add records a new trade (the number of units traded and the price paid per unit)
+// in the window. The return value is the new VWAP, or 0 if no units are in the
+// window.
+func (s *DecimalSlidingWindowVWAP) Add(units, unitPrice decimal.Decimal) decimal.Decimal {
+	if s.positions == nil {
+		return decimal.Decimal{}
+	}
+
+	var poppedValue *decimalPosition
+
+	// If len == cap, pushing will pop the first element. Keep track of it.
+	if s.positions.Len() == s.positions.Cap() {
+		poppedValue = s.positions.At(0)
+	}
+
+	pushedValue := newDecimalPosition(units, unitPrice)
+	s.positions.Push(&pushedValue)
+
+	if poppedValue != nil {
+		s.totalUnits = s.totalUnits.Sub(poppedValue.units)
+		s.totalPrice = s.totalPrice.Sub(poppedValue.totalPrice)
+		s.totalPriceSquared = s.totalPriceSquared.Sub(poppedValue.totalPriceSquared)
+	}
+
+	s.totalUnits = s.totalUnits.Add(pushedValue.units)
+	s.totalPrice = s.totalPrice.Add(pushedValue.totalPrice)
+	s.totalPriceSquared = s.totalPriceSquared.Add(pushedValue.totalPriceSquared)
+
+	return roundedVWAP(s.totalPrice, s.totalUnits, s.increment)
+}
+
+// Bands returns the standard deviation bands of the VWAP of the window, rounded
+// as it is. If no units are in the window, they're 0.
+func (s *DecimalSlidingWindowVWAP) Bands() DecimalBands {
+	if s.positions == nil {
+		return DecimalBands{}
+	}
+
+	return roundedBands(s.totalPrice, s.totalPriceSquared, s.totalUnits, s.increment)
+}
+
+// roundedVWAP returns totalPrice / totalUnits rounded (half to even) to a multiple
+// of increment, or 0 if totalUnits is 0.
+func roundedVWAP(totalPrice, totalUnits, increment decimal.Decimal) decimal.Decimal {
+	if totalUnits.IsZero() {
+		return decimal.Decimal{}.Quantize(increment)
+	}
+
+	// Divided by units * increment then multiplied by increment, to round once.
+	return totalPrice.DivRound(totalUnits.Mul(increment), 0).Mul(increment)
+}
@@ -0,0 +1,175 @@
+package vwap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+func TestNewDecimalSlidingWindowVWAPErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name          string
+		giveIncrement decimal.Decimal
+		expectedErr   string
+	}{
+		{
+			name:          "zero_increment",
+			giveIncrement: decimal.MustParse("0.00"),
+			expectedErr:   "increment 0.00 must be positive",
+		},
+		{
+			name:          "negative_increment",
+			giveIncrement: decimal.MustParse("-0.01"),
+			expectedErr:   "increment -0.01 must be positive",
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := NewDecimalSlidingWindowVWAP(5, tc.giveIncrement)
+
+			assert.Nil(t, actual, "Actual")
+			assert.EqualError(t, actualErr, tc.expectedErr, "Actual err")
+		})
+	}
+}
+
+func TestDecimalSlidingWindowVWAPAdd(t *testing.T) {
+	t.Parallel()
+
+	cents := decimal.MustParse("0.01")
+
+	newVWAP := func(windowCapacity int) *DecimalSlidingWindowVWAP {
+		s, err := NewDecimalSlidingWindowVWAP(windowCapacity, cents)
+		require.NoError(t, err, "NewDecimalSlidingWindowVWAP")
+
+		return s
+	}
+
+	newWithAdds := func(windowCapacity int, positions [][2]string) *DecimalSlidingWindowVWAP {
+		s := newVWAP(windowCapacity)
+
+		for _, position := range positions {
+			s.Add(decimal.MustParse(position[0]), decimal.MustParse(position[1]))
+		}
+
+		return s
+	}
+
+	for _, tc := range []struct {
+		name          string
+		with          *DecimalSlidingWindowVWAP
+		giveUnits     string
+		giveUnitPrice string
+		expected      string
+	}{
+		{
+			name:          "add_to_empty_5capacity",
+			with:          newVWAP(5),
+			giveUnits:     "2.5",
+			giveUnitPrice: "1.2",
+			expected:      "1.20",
+		},
+		{
+			name:          "add_to_zero_value",
+			with:          &DecimalSlidingWindowVWAP{},
+			giveUnits:     "2.5",
+			giveUnitPrice: "1.2",
+			expected:      "0",
+		},
+		{
+			name:          "add_to_full_1capacity",
+			with:          newWithAdds(1, [][2]string{{"10", "5"}}),
+			giveUnits:     "2.5",
+			giveUnitPrice: "1.2",
+			expected:      "1.20",
+		},
+		{
+			name:          "add_reaches_capacity",
+			with:          newWithAdds(3, [][2]string{{"1", "1"}, {"2", "1"}}),
+			giveUnits:     "1",
+			giveUnitPrice: "2",
+			expected:      "1.25", // 5 total price / 4 total units
+		},
+		{
+			name:          "add_zero_units",
+			with:          newVWAP(5),
+			giveUnits:     "0",
+			giveUnitPrice: "1.2",
+			expected:      "0.00",
+		},
+		{
+			name:          "rounds_half_to_even",
+			with:          newWithAdds(3, [][2]string{{"1", "1.00"}}),
+			giveUnits:     "1",
+			giveUnitPrice: "1.05",
+			expected:      "1.02", // 2.05 total price / 2 total units
+		},
+		{
+			name:          "no_float_drift",
+			with:          newWithAdds(2, [][2]string{{"0.1", "0.1"}, {"0.2", "0.2"}}),
+			giveUnits:     "0.3",
+			giveUnitPrice: "0.3",
+			expected:      "0.26", // 0.13 total price / 0.5 total units
+		},
+		{
+			name: "slide_lots",
+			with: newWithAdds(
+				3,
+				[][2]string{
+					{"1", "1"}, {"2", "2"}, {"3", "3"}, {"4", "4"}, {"5", "5"},
+					{"6", "6"}, {"7", "7"}, {"8", "8"}, {"9", "9"}, {"10", "10"},
+				}),
+			giveUnits:     "11",
+			giveUnitPrice: "11",
+			expected:      "10.07", // 302 total price / 30 total units
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := tc.with.Add(decimal.MustParse(tc.giveUnits), decimal.MustParse(tc.giveUnitPrice))
+			assert.Equal(t, tc.expected, actual.String())
+		})
+	}
+}
+
+func TestDecimalSlidingWindowVWAPBands(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	s, err := NewDecimalSlidingWindowVWAP(3, decimal.MustParse("0.01"))
+	require.NoError(t, err, "NewDecimalSlidingWindowVWAP")
+
+	for _, position := range [][2]string{{"100", "50"}, {"1", "1"}, {"1", "2"}, {"1", "3"}} {
+		s.Add(decimal.MustParse(position[0]), decimal.MustParse(position[1]))
+	}
+
+	// Do
+
+	actual := s.Bands()
+
+	// Assert
+
+	// VWAP 2, variance (1 + 0 + 1) / 3.
+	assert.Equal(t, []string{"0.6667", "0.82", "2.82", "3.63", "4.45", "1.18", "0.37", "-0.45"}, bandsStrings(actual), "Actual")
+}
+
+func TestDecimalSlidingWindowVWAPZeroValue(t *testing.T) {
+	t.Parallel()
+
+	s := &DecimalSlidingWindowVWAP{}
+
+	assert.Equal(t, "0", s.Add(decimal.MustParse("1"), decimal.MustParse("1")).String(), "Add")
+	assert.Equal(t, DecimalBands{}, s.Bands(), "Bands")
+}
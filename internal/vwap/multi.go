@@ -0,0 +1,335 @@
+package vwap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+	"github.com/byatesrae/coinbase_vwap/internal/platform/deque"
+)
+
+// Window is a window of trades a VWAP is calculated over, either the last Count
+// trades or those in the last Duration of time. Exactly one must be set.
+type Window struct {
+	Count    int
+	Duration time.Duration
+}
+
+// ParseWindow parses s, either a number of trades (e.g. "200") or a duration
+// (e.g. "5m", see time.ParseDuration).
+func ParseWindow(s string) (Window, error) {
+	var w Window
+
+	if count, err := strconv.Atoi(s); err == nil {
+		w.Count = count
+	} else if duration, err := time.ParseDuration(s); err == nil {
+		w.Duration = duration
+	} else {
+		return Window{}, fmt.Errorf("parse window %q: expected a number of trades (e.g. 200) or a duration (e.g. 5m)", s)
+	}
+
+	if err := w.validate(); err != nil {
+		return Window{}, fmt.Errorf("parse window %q: %w", s, err)
+	}
+
+	return w, nil
+}
+
+// String formats the window as it's parsed by ParseWindow, e.g. "200" or "5m".
+func (w Window) String() string {
+	if w.Count > 0 {
+		return strconv.Itoa(w.Count)
+	}
+
+	s := w.Duration.String()
+
+	// E.g. "1h0m0s" is formatted as "1h".
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+
+	return s
+}
+
+// validate returns an error if w is invalid.
+func (w Window) validate() error {
+	switch {
+	case w.Count < 0 || w.Duration < 0:
+		return fmt.Errorf("window must be positive")
+	case w.Count > 0 && w.Duration > 0:
+		return fmt.Errorf("window must be a number of trades or a duration, not both")
+	case w.Count == 0 && w.Duration == 0:
+		return fmt.Errorf("window must be positive")
+	default:
+		return nil
+	}
+}
+
+// Side is the side of the aggressor of a trade, i.e. the taker who matched a
+// resting order.
+type Side string
+
+// Sides of a trade.
+const (
+	SideUnknown Side = ""
+	SideBuy     Side = "buy"
+	SideSell    Side = "sell"
+)
+
+// WindowVWAP is the VWAP of a Window.
+type WindowVWAP struct {
+	Window Window
+
+	// The VWAP, or 0 if no units are in the window.
+	VWAP decimal.Decimal
+
+	// The number of trades in the window.
+	Trades int
+
+	// The standard deviation bands of the VWAP.
+	Bands DecimalBands
+
+	// The VWAPs and volumes of the trades in the window by aggressor side. Trades
+	// of an unknown side are in neither.
+	Buy, Sell SideVWAP
+}
+
+// Imbalance returns the signed volume imbalance of the window, the buy volume
+// less the sell volume. It is positive when buyers are the aggressors.
+func (w WindowVWAP) Imbalance() decimal.Decimal {
+	return w.Buy.Volume.Sub(w.Sell.Volume)
+}
+
+// SideVWAP is the VWAP of one side of the trades in a Window.
+type SideVWAP struct {
+	// The VWAP, or 0 if no units of the side are in the window.
+	VWAP decimal.Decimal
+
+	// The number of units traded.
+	Volume decimal.Decimal
+}
+
+// multiWindow is a Window of a MultiWindowVWAP, with its running totals.
+type multiWindow struct {
+	Window
+
+	// The number of trades in the window, the last of those stored.
+	len int
+
+	// a cumulative total for all units traded in the window.
+	totalUnits decimal.Decimal
+
+	// a cumulative price total traded in the window.
+	totalPrice decimal.Decimal
+
+	// a cumulative total of price * unit price traded in the window.
+	totalPriceSquared decimal.Decimal
+
+	// The cumulative totals of each side traded in the window.
+	buy, sell decimalPosition
+}
+
+// add adds p to the totals of the window.
+func (w *multiWindow) add(p *timedPosition) {
+	w.len++
+	w.totalUnits = w.totalUnits.Add(p.units)
+	w.totalPrice = w.totalPrice.Add(p.totalPrice)
+	w.totalPriceSquared = w.totalPriceSquared.Add(p.totalPriceSquared)
+
+	if side := w.side(p.side); side != nil {
+		side.units = side.units.Add(p.units)
+		side.totalPrice = side.totalPrice.Add(p.totalPrice)
+	}
+}
+
+// sub removes p from the totals of the window.
+func (w *multiWindow) sub(p *timedPosition) {
+	w.len--
+	w.totalUnits = w.totalUnits.Sub(p.units)
+	w.totalPrice = w.totalPrice.Sub(p.totalPrice)
+	w.totalPriceSquared = w.totalPriceSquared.Sub(p.totalPriceSquared)
+
+	if side := w.side(p.side); side != nil {
+		side.units = side.units.Sub(p.units)
+		side.totalPrice = side.totalPrice.Sub(p.totalPrice)
+	}
+}
+
+// side returns the totals of side, or nil if it's unknown.
+func (w *multiWindow) side(side Side) *decimalPosition {
+	switch side {
+	case SideBuy:
+		return &w.buy
+	case SideSell:
+		return &w.sell
+	default:
+		return nil
+	}
+}
+
+// MultiWindowVWAP calculates the VWAPs (and their bands) of several windows (by
+// count and by time) in one pass, using exact decimal arithmetic. Only one history
+// of trades is stored, as long as the longest window needs them, and each window
+// keeps running totals over the latest part of it.
+//
+// Trades are ordered by their (exchange) timestamp, and windows of time are evicted
+// from as TimeWindowVWAP does.
+//
+// The zero-value of this type has no windows, and therefore no utility.
+type MultiWindowVWAP struct {
+	windows []*multiWindow
+
+	// The VWAPs are rounded to a multiple of this.
+	increment decimal.Decimal
+
+	// Tells the current time.
+	now func() time.Time
+
+	// The positions needed by any window, oldest first.
+	positions deque.Deque[*timedPosition]
+}
+
+// NewMultiWindowVWAP creates a new MultiWindowVWAP for windows, with time told by
+// now (if nil, time.Now is used). Each VWAP is rounded (half to even) to a multiple
+// of increment, which must be greater than 0.
+func NewMultiWindowVWAP(windows []Window, increment decimal.Decimal, now func() time.Time) (*MultiWindowVWAP, error) {
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("window is required")
+	}
+
+	if increment.Sign() <= 0 {
+		return nil, fmt.Errorf("increment %v must be positive", increment)
+	}
+
+	if now == nil {
+		now = time.Now
+	}
+
+	m := &MultiWindowVWAP{
+		windows:   make([]*multiWindow, len(windows)),
+		increment: increment,
+		now:       now,
+	}
+
+	for a, window := range windows {
+		if err := window.validate(); err != nil {
+			return nil, fmt.Errorf("window %d: %w", a, err)
+		}
+
+		m.windows[a] = &multiWindow{Window: window}
+	}
+
+	return m, nil
+}
+
+// Add records a new trade (the number of units traded and the price paid per unit)
+// that happened at, with side the side of its aggressor. The return value is the
+// new VWAP of each window, in the order the windows were given.
+func (m *MultiWindowVWAP) Add(at time.Time, side Side, units, unitPrice decimal.Decimal) []WindowVWAP {
+	if len(m.windows) == 0 {
+		return nil
+	}
+
+	pushedValue := &timedPosition{
+		decimalPosition: newDecimalPosition(units, unitPrice),
+		at:              at,
+		side:            side,
+	}
+
+	if insertPosition(&m.positions, pushedValue) == m.positions.Len()-1 {
+		for _, w := range m.windows {
+			w.add(pushedValue)
+		}
+	} else {
+		// Out of order, which may move the start of any window.
+		m.recompute()
+	}
+
+	m.evict()
+
+	return m.vwaps()
+}
+
+// VWAPs returns the VWAP of each window as of now, in the order the windows were
+// given, evicting trades that have since left windows of time.
+func (m *MultiWindowVWAP) VWAPs() []WindowVWAP {
+	if len(m.windows) == 0 {
+		return nil
+	}
+
+	m.evict()
+
+	return m.vwaps()
+}
+
+// vwaps returns the VWAP of each window.
+func (m *MultiWindowVWAP) vwaps() []WindowVWAP {
+	vwaps := make([]WindowVWAP, len(m.windows))
+	for a, w := range m.windows {
+		vwaps[a] = WindowVWAP{
+			Window: w.Window,
+			VWAP:   roundedVWAP(w.totalPrice, w.totalUnits, m.increment),
+			Trades: w.len,
+			Bands:  roundedBands(w.totalPrice, w.totalPriceSquared, w.totalUnits, m.increment),
+			Buy:    SideVWAP{VWAP: roundedVWAP(w.buy.totalPrice, w.buy.units, m.increment), Volume: w.buy.units},
+			Sell:   SideVWAP{VWAP: roundedVWAP(w.sell.totalPrice, w.sell.units, m.increment), Volume: w.sell.units},
+		}
+	}
+
+	return vwaps
+}
+
+// recompute recomputes each window from every position stored. The windows then
+// need evicting from.
+func (m *MultiWindowVWAP) recompute() {
+	for _, w := range m.windows {
+		*w = multiWindow{Window: w.Window}
+
+		for a := 0; a < m.positions.Len(); a++ {
+			w.add(m.positions.At(a))
+		}
+	}
+}
+
+// evict removes the oldest trades from each window until it's within its count or
+// duration, and then stops storing the trades no window needs.
+func (m *MultiWindowVWAP) evict() {
+	end := m.now()
+	if m.positions.Len() > 0 && m.positions.Back().at.After(end) {
+		end = m.positions.Back().at
+	}
+
+	keep := 0
+
+	for _, w := range m.windows {
+		cutoff := end.Add(-w.Duration)
+
+		for w.len > 0 {
+			oldest := m.positions.At(m.positions.Len() - w.len)
+
+			if w.Count > 0 && w.len <= w.Count {
+				break
+			}
+
+			if w.Duration > 0 && oldest.at.After(cutoff) {
+				break
+			}
+
+			w.sub(oldest)
+		}
+
+		if w.len > keep {
+			keep = w.len
+		}
+	}
+
+	for m.positions.Len() > keep {
+		m.positions.PopFront()
+	}
+}
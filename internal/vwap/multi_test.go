@@ -0,0 +1,287 @@
+package vwap
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+func TestParseWindow(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name           string
+		give           string
+		expected       Window
+		expectedString string
+		expectedErr    string
+	}{
+		{name: "count", give: "200", expected: Window{Count: 200}, expectedString: "200"},
+		{name: "minutes", give: "5m", expected: Window{Duration: time.Minute * 5}, expectedString: "5m"},
+		{name: "hours", give: "1h", expected: Window{Duration: time.Hour}, expectedString: "1h"},
+		{name: "mixed", give: "1h30m", expected: Window{Duration: time.Minute * 90}, expectedString: "1h30m"},
+		{name: "seconds", give: "90s", expected: Window{Duration: time.Second * 90}, expectedString: "1m30s"},
+		{name: "zero", give: "0", expectedErr: `parse window "0": window must be positive`},
+		{name: "negative", give: "-5m", expectedErr: `parse window "-5m": window must be positive`},
+		{name: "invalid", give: "TestABC", expectedErr: `parse window "TestABC": expected a number of trades (e.g. 200) or a duration (e.g. 5m)`},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Do
+
+			actual, actualErr := ParseWindow(tc.give)
+
+			// Assert
+
+			if tc.expectedErr != "" {
+				assert.EqualError(t, actualErr, tc.expectedErr, "Actual err")
+
+				return
+			}
+
+			assert.NoError(t, actualErr, "Actual err")
+			assert.Equal(t, tc.expected, actual, "Actual")
+			assert.Equal(t, tc.expectedString, actual.String(), "Actual string")
+		})
+	}
+}
+
+func TestNewMultiWindowVWAPErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name          string
+		giveWindows   []Window
+		giveIncrement decimal.Decimal
+		expectedErr   string
+	}{
+		{
+			name:          "no_windows",
+			giveIncrement: decimal.MustParse("0.01"),
+			expectedErr:   "window is required",
+		},
+		{
+			name:          "both",
+			giveWindows:   []Window{{Count: 5}, {Count: 5, Duration: time.Minute}},
+			giveIncrement: decimal.MustParse("0.01"),
+			expectedErr:   "window 1: window must be a number of trades or a duration, not both",
+		},
+		{
+			name:          "zero_window",
+			giveWindows:   []Window{{}},
+			giveIncrement: decimal.MustParse("0.01"),
+			expectedErr:   "window 0: window must be positive",
+		},
+		{
+			name:          "zero_increment",
+			giveWindows:   []Window{{Count: 5}},
+			giveIncrement: decimal.MustParse("0.00"),
+			expectedErr:   "increment 0.00 must be positive",
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := NewMultiWindowVWAP(tc.giveWindows, tc.giveIncrement, nil)
+
+			assert.Nil(t, actual, "Actual")
+			assert.EqualError(t, actualErr, tc.expectedErr, "Actual err")
+		})
+	}
+}
+
+func TestMultiWindowVWAPAdd(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := &clockFake{now: start}
+
+	m, err := NewMultiWindowVWAP([]Window{{Count: 2}, {Duration: time.Minute}}, decimal.MustParse("0.01"), clock.Now)
+	require.NoError(t, err, "NewMultiWindowVWAP")
+
+	add := func(after time.Duration, units, unitPrice string) []WindowVWAP {
+		return m.Add(start.Add(after), SideUnknown, decimal.MustParse(units), decimal.MustParse(unitPrice))
+	}
+
+	// Do & Assert
+
+	first := add(0, "1", "1")
+
+	assert.Equal(t, []string{"1.00", "1.00"}, vwapStrings(first), "First")
+	assert.Equal(t, Window{Count: 2}, first[0].Window, "First window 0")
+	assert.Equal(t, 1, first[0].Trades, "First window 0 trades")
+	assert.Equal(t, Window{Duration: time.Minute}, first[1].Window, "First window 1")
+	assert.Equal(t, 1, first[1].Trades, "First window 1 trades")
+
+	add(time.Second*20, "1", "2")
+	add(time.Second*40, "2", "4")
+
+	assert.Equal(
+		t,
+		[]string{"3.33", "2.75"}, // (2+8)/3 and (1+2+8)/4
+		vwapStrings(m.VWAPs()),
+		"After third",
+	)
+	assert.Equal(t, 3, m.positions.Len(), "Positions stored after third")
+
+	// Out of order, so now the last 2 trades by time are the last 2 added.
+	add(time.Second*30, "1", "6")
+
+	assert.Equal(t, []string{"4.67", "3.40"}, vwapStrings(m.VWAPs()), "After out of order") // (6+8)/3 and (1+2+6+8)/5
+
+	clock.now = start.Add(time.Second * 75)
+
+	assert.Equal(t, []string{"4.67", "4.00"}, vwapStrings(m.VWAPs()), "After decay") // (2+6+8)/4
+	assert.Equal(t, 3, m.positions.Len(), "Positions stored after decay")
+
+	clock.now = start.Add(time.Minute * 2)
+
+	assert.Equal(t, []string{"4.67", "0.00"}, vwapStrings(m.VWAPs()), "After time window empty")
+	assert.Equal(t, 2, m.positions.Len(), "Positions stored after time window empty")
+}
+
+func TestMultiWindowVWAPMatchesSingleWindows(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := &clockFake{now: start}
+	increment := decimal.MustParse("0.00001")
+
+	windows := []Window{{Count: 5}, {Count: 50}, {Duration: time.Second * 10}, {Duration: time.Minute}}
+
+	m, err := NewMultiWindowVWAP(windows, increment, clock.Now)
+	require.NoError(t, err, "NewMultiWindowVWAP")
+
+	counts := make([]*DecimalSlidingWindowVWAP, 2)
+	for a, windowCapacity := range []int{5, 50} {
+		counts[a], err = NewDecimalSlidingWindowVWAP(windowCapacity, increment)
+		require.NoError(t, err, "NewDecimalSlidingWindowVWAP %d", windowCapacity)
+	}
+
+	times := make([]*TimeWindowVWAP, 2)
+	for a, window := range []time.Duration{time.Second * 10, time.Minute} {
+		times[a], err = NewTimeWindowVWAP(window, increment, clock.Now)
+		require.NoError(t, err, "NewTimeWindowVWAP %v", window)
+	}
+
+	random := rand.New(rand.NewSource(1))
+
+	// Do & Assert
+
+	for a := 0; a < 1000; a++ {
+		clock.now = clock.now.Add(time.Millisecond * time.Duration(random.Intn(2000)))
+
+		units := decimal.New(int64(random.Intn(100000)+1), 4)
+		unitPrice := decimal.New(int64(random.Intn(1000000)+1), 2)
+
+		actual := m.Add(clock.now, SideUnknown, units, unitPrice)
+
+		expected := []string{
+			counts[0].Add(units, unitPrice).String(),
+			counts[1].Add(units, unitPrice).String(),
+			times[0].Add(clock.now, units, unitPrice).String(),
+			times[1].Add(clock.now, units, unitPrice).String(),
+		}
+
+		require.Equal(t, expected, vwapStrings(actual), "Trade %d", a)
+
+		expectedBands := [][]string{
+			bandsStrings(counts[0].Bands()),
+			bandsStrings(counts[1].Bands()),
+			bandsStrings(times[0].Bands()),
+			bandsStrings(times[1].Bands()),
+		}
+
+		for b := range actual {
+			require.Equal(t, expectedBands[b], bandsStrings(actual[b].Bands), "Trade %d, window %d bands", a, b)
+		}
+
+		// Only as many as the longest window needs.
+		expectedStored := a + 1
+		if expectedStored > 50 {
+			expectedStored = 50
+		}
+
+		if times[1].Len() > expectedStored {
+			expectedStored = times[1].Len()
+		}
+
+		require.Equal(t, expectedStored, m.positions.Len(), "Positions stored after trade %d", a)
+	}
+}
+
+func TestMultiWindowVWAPSides(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := &clockFake{now: start}
+
+	m, err := NewMultiWindowVWAP([]Window{{Count: 3}}, decimal.MustParse("0.01"), clock.Now)
+	require.NoError(t, err, "NewMultiWindowVWAP")
+
+	add := func(after time.Duration, side Side, units, unitPrice string) []string {
+		return sideStrings(m.Add(start.Add(after), side, decimal.MustParse(units), decimal.MustParse(unitPrice))[0])
+	}
+
+	// Do & Assert
+
+	add(0, SideBuy, "1", "10")
+	add(time.Second, SideSell, "2", "20")
+
+	// VWAP, buy VWAP, buy volume, sell VWAP, sell volume, imbalance.
+	assert.Equal(t, []string{"20.00", "10.00", "1", "20.00", "2", "-1"}, add(time.Second*2, SideUnknown, "1", "30"), "Unknown side")
+	assert.Equal(t, []string{"17.67", "12.00", "3", "20.00", "2", "1"}, add(time.Second*3, SideBuy, "3", "12"), "Evicts buy") // 106/6
+
+	// Out of order, so recomputed, and outside the window of the last 3.
+	assert.Equal(t, []string{"17.67", "12.00", "3", "20.00", "2", "1"}, add(-time.Second, SideSell, "1", "8"), "Out of order")
+
+	assert.Equal(t, []string{"12.89", "12.00", "3", "10.00", "5", "-2"}, add(time.Second*4, SideSell, "5", "10"), "Evicts sell") // 116/9
+}
+
+// sideStrings formats the VWAP of vwap, then the VWAP and volume of each side, then
+// the imbalance.
+func sideStrings(vwap WindowVWAP) []string {
+	return []string{
+		vwap.VWAP.String(),
+		vwap.Buy.VWAP.String(),
+		vwap.Buy.Volume.String(),
+		vwap.Sell.VWAP.String(),
+		vwap.Sell.Volume.String(),
+		vwap.Imbalance().String(),
+	}
+}
+
+// vwapStrings formats the VWAPs of vwaps.
+func vwapStrings(vwaps []WindowVWAP) []string {
+	s := make([]string, len(vwaps))
+	for a, vwap := range vwaps {
+		s[a] = vwap.VWAP.String()
+	}
+
+	return s
+}
+
+func TestMultiWindowVWAPZeroValue(t *testing.T) {
+	t.Parallel()
+
+	m := &MultiWindowVWAP{}
+
+	assert.Nil(t, m.Add(time.Now(), SideBuy, decimal.MustParse("1"), decimal.MustParse("1")), "Add")
+	assert.Nil(t, m.VWAPs(), "VWAPs")
+}
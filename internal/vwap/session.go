@@ -0,0 +1,143 @@
+package vwap
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+// DailyAnchor is the time of day sessions start at, in a location, e.g. 00:00 UTC
+// or the 09:30 open of an exchange in America/New_York.
+type DailyAnchor struct {
+	Hour, Minute int
+
+	// If nil, UTC.
+	Location *time.Location
+}
+
+// ParseDailyAnchor parses s, a time of day ("15:04") optionally followed by the
+// name of its location (see time.LoadLocation), e.g. "09:30 America/New_York". If
+// there's no location, it's UTC.
+func ParseDailyAnchor(s string) (DailyAnchor, error) {
+	clock, name, _ := strings.Cut(strings.TrimSpace(s), " ")
+
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return DailyAnchor{}, fmt.Errorf("parse daily anchor %q: expected a time of day (e.g. 09:30) and optional location (e.g. America/New_York)", s)
+	}
+
+	d := DailyAnchor{Hour: t.Hour(), Minute: t.Minute()}
+
+	if name = strings.TrimSpace(name); name != "" {
+		d.Location, err = time.LoadLocation(name)
+		if err != nil {
+			return DailyAnchor{}, fmt.Errorf("parse daily anchor %q: %w", s, err)
+		}
+	}
+
+	return d, nil
+}
+
+// String formats the anchor as it's parsed by ParseDailyAnchor, e.g. "09:30 America/New_York".
+func (d DailyAnchor) String() string {
+	return fmt.Sprintf("%02d:%02d %v", d.Hour, d.Minute, d.location())
+}
+
+// Previous returns the start of the session t is in, the latest anchor at or before t.
+func (d DailyAnchor) Previous(t time.Time) time.Time {
+	local := t.In(d.location())
+
+	anchor := time.Date(local.Year(), local.Month(), local.Day(), d.Hour, d.Minute, 0, 0, d.location())
+	if anchor.After(t) {
+		anchor = time.Date(local.Year(), local.Month(), local.Day()-1, d.Hour, d.Minute, 0, 0, d.location())
+	}
+
+	return anchor
+}
+
+// location returns the location of the anchor.
+func (d DailyAnchor) location() *time.Location {
+	if d.Location == nil {
+		return time.UTC
+	}
+
+	return d.Location
+}
+
+// SessionVWAP calculates the VWAP of the current session, see AnchoredVWAP, which
+// resets at each daily anchor.
+//
+// The session is reset when it's next used after its end, as told by the later of
+// the clock's time and the time of the trade being added (as TimeWindowVWAP evicts).
+// Trades before the current session are ignored.
+//
+// The zero-value of this type has no increment, and therefore no utility.
+type SessionVWAP struct {
+	// When each session starts.
+	anchor DailyAnchor
+
+	// The VWAP is rounded to a multiple of this.
+	increment decimal.Decimal
+
+	// Tells the current time.
+	now func() time.Time
+
+	// The current session, or nil if it's yet to start.
+	session *AnchoredVWAP
+}
+
+// NewSessionVWAP creates a new SessionVWAP over sessions starting at anchor each
+// day, with time told by now (if nil, time.Now is used). Each VWAP is rounded (half
+// to even) to a multiple of increment, which must be greater than 0.
+func NewSessionVWAP(anchor DailyAnchor, increment decimal.Decimal, now func() time.Time) *SessionVWAP {
+	if now == nil {
+		now = time.Now
+	}
+
+	return &SessionVWAP{
+		anchor:    anchor,
+		increment: increment,
+		now:       now,
+	}
+}
+
+// Add records a new trade (the number of units traded and the price paid per unit)
+// that happened at. The return value is the new VWAP of the session, labelled with
+// the session's start.
+func (s *SessionVWAP) Add(at time.Time, units, unitPrice decimal.Decimal) AnchorVWAP {
+	if s.increment.IsZero() {
+		return AnchorVWAP{}
+	}
+
+	s.reset(at)
+
+	return s.session.Add(at, units, unitPrice)
+}
+
+// VWAP returns the VWAP of the session as of now, starting the next if it's due.
+func (s *SessionVWAP) VWAP() AnchorVWAP {
+	if s.increment.IsZero() {
+		return AnchorVWAP{}
+	}
+
+	s.reset(time.Time{})
+
+	return s.session.VWAP()
+}
+
+// reset starts a new session if the current one has ended, given a trade at (which
+// may be zero) is being added.
+func (s *SessionVWAP) reset(at time.Time) {
+	end := s.now()
+	if at.After(end) {
+		end = at
+	}
+
+	start := s.anchor.Previous(end)
+
+	if s.session == nil || start.After(s.session.anchor) {
+		s.session = NewAnchoredVWAP(start, s.increment)
+	}
+}
@@ -0,0 +1,190 @@
+package vwap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+func TestParseDailyAnchor(t *testing.T) {
+	t.Parallel()
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err, "LoadLocation")
+
+	for _, tc := range []struct {
+		name           string
+		give           string
+		expected       DailyAnchor
+		expectedString string
+		expectedErr    string
+	}{
+		{name: "utc", give: "00:00", expected: DailyAnchor{}, expectedString: "00:00 UTC"},
+		{name: "location", give: "09:30 America/New_York", expected: DailyAnchor{Hour: 9, Minute: 30, Location: newYork}, expectedString: "09:30 America/New_York"},
+		{name: "invalid_time", give: "25:00", expectedErr: `parse daily anchor "25:00": expected a time of day (e.g. 09:30) and optional location (e.g. America/New_York)`},
+		{name: "invalid_location", give: "09:30 TestABC", expectedErr: `parse daily anchor "09:30 TestABC": unknown time zone TestABC`},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Do
+
+			actual, actualErr := ParseDailyAnchor(tc.give)
+
+			// Assert
+
+			if tc.expectedErr != "" {
+				assert.EqualError(t, actualErr, tc.expectedErr, "Actual err")
+
+				return
+			}
+
+			assert.NoError(t, actualErr, "Actual err")
+			assert.Equal(t, tc.expected, actual, "Actual")
+			assert.Equal(t, tc.expectedString, actual.String(), "Actual string")
+		})
+	}
+}
+
+func TestDailyAnchorPrevious(t *testing.T) {
+	t.Parallel()
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err, "LoadLocation")
+
+	for _, tc := range []struct {
+		name     string
+		with     DailyAnchor
+		give     time.Time
+		expected time.Time
+	}{
+		{
+			name:     "utc_same_day",
+			with:     DailyAnchor{},
+			give:     time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC),
+			expected: time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "at_anchor",
+			with:     DailyAnchor{Hour: 12},
+			give:     time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC),
+			expected: time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "previous_day",
+			with:     DailyAnchor{Hour: 12, Minute: 30},
+			give:     time.Date(2022, 10, 1, 12, 29, 0, 0, time.UTC),
+			expected: time.Date(2022, 9, 30, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "location",
+			with:     DailyAnchor{Hour: 9, Minute: 30, Location: newYork},
+			give:     time.Date(2022, 10, 1, 14, 0, 0, 0, time.UTC),
+			expected: time.Date(2022, 10, 1, 13, 30, 0, 0, time.UTC), // EDT is UTC-4.
+		},
+		{
+			name:     "location_standard_time",
+			with:     DailyAnchor{Hour: 9, Minute: 30, Location: newYork},
+			give:     time.Date(2022, 12, 1, 14, 0, 0, 0, time.UTC),
+			expected: time.Date(2022, 11, 30, 14, 30, 0, 0, time.UTC), // EST is UTC-5.
+		},
+		{
+			name:     "location_different_day",
+			with:     DailyAnchor{Hour: 18, Location: newYork},
+			give:     time.Date(2022, 10, 2, 1, 0, 0, 0, time.UTC),
+			expected: time.Date(2022, 10, 1, 22, 0, 0, 0, time.UTC),
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := tc.with.Previous(tc.give)
+
+			assert.True(t, tc.expected.Equal(actual), "Actual %v, expected %v", actual, tc.expected)
+		})
+	}
+}
+
+func TestSessionVWAPResets(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	start := time.Date(2022, 10, 1, 22, 0, 0, 0, time.UTC)
+	firstAnchor := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	secondAnchor := time.Date(2022, 10, 2, 0, 0, 0, 0, time.UTC)
+
+	clock := &clockFake{now: start}
+	s := NewSessionVWAP(DailyAnchor{}, decimal.MustParse("0.01"), clock.Now)
+
+	add := func(after time.Duration, units, unitPrice string) AnchorVWAP {
+		return s.Add(start.Add(after), decimal.MustParse(units), decimal.MustParse(unitPrice))
+	}
+
+	// Do & Assert
+
+	add(0, "1", "1")
+
+	actual := add(time.Hour, "1", "2")
+	assert.Equal(t, firstAnchor, actual.Anchor, "Anchor of first session")
+	assert.Equal(t, "1.50", actual.VWAP.String(), "VWAP of first session")
+	assert.Equal(t, 2, actual.Trades, "Trades of first session")
+
+	// The clock passes the next anchor.
+	clock.now = start.Add(time.Hour * 3)
+
+	actual = s.VWAP()
+	assert.Equal(t, secondAnchor, actual.Anchor, "Anchor of second session")
+	assert.Equal(t, "0.00", actual.VWAP.String(), "VWAP of second session")
+	assert.Equal(t, 0, actual.Trades, "Trades of second session")
+
+	// A trade late from the first session is ignored.
+	actual = add(time.Hour, "1", "5")
+	assert.Equal(t, secondAnchor, actual.Anchor, "Anchor after late trade")
+	assert.Equal(t, 0, actual.Trades, "Trades after late trade")
+
+	actual = add(time.Hour*3, "2", "3")
+	assert.Equal(t, secondAnchor, actual.Anchor, "Anchor after trade")
+	assert.Equal(t, "3.00", actual.VWAP.String(), "VWAP after trade")
+	assert.Equal(t, 1, actual.Trades, "Trades after trade")
+}
+
+func TestSessionVWAPResetsByTradeTime(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	start := time.Date(2022, 10, 1, 23, 59, 0, 0, time.UTC)
+
+	// The clock is behind the exchange's.
+	clock := &clockFake{now: start}
+	s := NewSessionVWAP(DailyAnchor{}, decimal.MustParse("0.01"), clock.Now)
+
+	s.Add(start, decimal.MustParse("1"), decimal.MustParse("1"))
+
+	// Do
+
+	actual := s.Add(start.Add(time.Minute), decimal.MustParse("1"), decimal.MustParse("2"))
+
+	// Assert
+
+	assert.Equal(t, time.Date(2022, 10, 2, 0, 0, 0, 0, time.UTC), actual.Anchor, "Anchor")
+	assert.Equal(t, "2.00", actual.VWAP.String(), "VWAP")
+}
+
+func TestSessionVWAPZeroValue(t *testing.T) {
+	t.Parallel()
+
+	s := &SessionVWAP{}
+
+	assert.Equal(t, AnchorVWAP{}, s.Add(time.Now(), decimal.MustParse("1"), decimal.MustParse("1")), "Add")
+	assert.Equal(t, AnchorVWAP{}, s.VWAP(), "VWAP")
+}
@@ -0,0 +1,177 @@
+package vwap
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+	"github.com/byatesrae/coinbase_vwap/internal/platform/deque"
+)
+
+// timedPosition is a decimalPosition traded at a time.
+type timedPosition struct {
+	decimalPosition
+
+	// When the trade happened, as timestamped by the exchange.
+	at time.Time
+
+	// The side of the trade's aggressor, if known.
+	side Side
+}
+
+// TimeWindowVWAP calculates a VWAP, and its standard deviation bands, over the
+// trades of a window of time (e.g. the last 5 minutes) using exact decimal
+// arithmetic, see DecimalSlidingWindowVWAP.
+//
+// Trades are evicted by their (exchange) timestamp. The window ends at the later
+// of the clock's time and the latest trade's timestamp, so trades are evicted as
+// time passes even if no more are added, and a clock behind the exchange's doesn't
+// keep them for longer.
+//
+// The zero-value of this type has no window, and therefore no utility.
+type TimeWindowVWAP struct {
+	// The duration of the window.
+	window time.Duration
+
+	// The VWAP is rounded to a multiple of this.
+	increment decimal.Decimal
+
+	// Tells the current time.
+	now func() time.Time
+
+	// The positions in the window, oldest first.
+	positions deque.Deque[*timedPosition]
+
+	// a cumulative total for all units traded in the window.
+	totalUnits decimal.Decimal
+
+	// a cumulative price total traded in the window.
+	totalPrice decimal.Decimal
+
+	// a cumulative total of price * unit price traded in the window.
+	totalPriceSquared decimal.Decimal
+}
+
+// NewTimeWindowVWAP creates a new TimeWindowVWAP over trades in the last window
+// of time (which must be greater than 0), as told by now (if nil, time.Now is
+// used). Each VWAP is rounded (half to even) to a multiple of increment, which must
+// be greater than 0.
+func NewTimeWindowVWAP(window time.Duration, increment decimal.Decimal, now func() time.Time) (*TimeWindowVWAP, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("window %v must be positive", window)
+	}
+
+	if increment.Sign() <= 0 {
+		return nil, fmt.Errorf("increment %v must be positive", increment)
+	}
+
+	if now == nil {
+		now = time.Now
+	}
+
+	return &TimeWindowVWAP{
+		window:    window,
+		increment: increment,
+		now:       now,
+	}, nil
+}
+
+// Add records a new trade (the number of units traded and the price paid per unit)
+// that happened at, in the window. Trades already outside the window are ignored.
+// The return value is the new VWAP, or 0 if no units are in the window.
+func (t *TimeWindowVWAP) Add(at time.Time, units, unitPrice decimal.Decimal) decimal.Decimal {
+	if t.window <= 0 {
+		return decimal.Decimal{}
+	}
+
+	cutoff := t.cutoff(at)
+	t.evict(cutoff)
+
+	if !at.After(cutoff) {
+		return roundedVWAP(t.totalPrice, t.totalUnits, t.increment)
+	}
+
+	pushedValue := &timedPosition{
+		decimalPosition: newDecimalPosition(units, unitPrice),
+		at:              at,
+	}
+
+	insertPosition(&t.positions, pushedValue)
+
+	t.totalUnits = t.totalUnits.Add(pushedValue.units)
+	t.totalPrice = t.totalPrice.Add(pushedValue.totalPrice)
+	t.totalPriceSquared = t.totalPriceSquared.Add(pushedValue.totalPriceSquared)
+
+	return roundedVWAP(t.totalPrice, t.totalUnits, t.increment)
+}
+
+// VWAP returns the VWAP of the trades in the window as of now, evicting those
+// that have since left it. Returns 0 if no units are in the window.
+func (t *TimeWindowVWAP) VWAP() decimal.Decimal {
+	if t.window <= 0 {
+		return decimal.Decimal{}
+	}
+
+	t.evict(t.cutoff(time.Time{}))
+
+	return roundedVWAP(t.totalPrice, t.totalUnits, t.increment)
+}
+
+// Bands returns the standard deviation bands of the VWAP of the trades in the
+// window as of now, evicting those that have since left it. If no units are in
+// the window, they're 0.
+func (t *TimeWindowVWAP) Bands() DecimalBands {
+	if t.window <= 0 {
+		return DecimalBands{}
+	}
+
+	t.evict(t.cutoff(time.Time{}))
+
+	return roundedBands(t.totalPrice, t.totalPriceSquared, t.totalUnits, t.increment)
+}
+
+// Len returns the number of trades in the window, as of the last Add or VWAP.
+func (t *TimeWindowVWAP) Len() int {
+	return t.positions.Len()
+}
+
+// cutoff returns the time trades must be after to be in the window, given a trade
+// at (which may be zero) is being added.
+func (t *TimeWindowVWAP) cutoff(at time.Time) time.Time {
+	end := t.now()
+
+	if at.After(end) {
+		end = at
+	}
+
+	if t.positions.Len() > 0 && t.positions.Back().at.After(end) {
+		end = t.positions.Back().at
+	}
+
+	return end.Add(-t.window)
+}
+
+// evict removes the trades that aren't after cutoff.
+func (t *TimeWindowVWAP) evict(cutoff time.Time) {
+	for t.positions.Len() > 0 && !t.positions.Front().at.After(cutoff) {
+		poppedValue := t.positions.PopFront()
+
+		t.totalUnits = t.totalUnits.Sub(poppedValue.units)
+		t.totalPrice = t.totalPrice.Sub(poppedValue.totalPrice)
+		t.totalPriceSquared = t.totalPriceSquared.Sub(poppedValue.totalPriceSquared)
+	}
+}
+
+// insertPosition inserts p into positions (oldest first) after those at or before
+// its time, and returns its index.
+func insertPosition(positions *deque.Deque[*timedPosition], p *timedPosition) int {
+	// Trades typically arrive in order, so this is usually found straight away.
+	a := positions.Len()
+	for a > 0 && positions.At(a-1).at.After(p.at) {
+		a--
+	}
+
+	positions.Insert(a, p)
+
+	return a
+}
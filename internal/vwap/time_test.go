@@ -0,0 +1,211 @@
+package vwap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/byatesrae/coinbase_vwap/internal/platform/decimal"
+)
+
+// clockFake is a clock that only moves when set.
+type clockFake struct {
+	now time.Time
+}
+
+func (c *clockFake) Now() time.Time {
+	return c.now
+}
+
+// timedTrade is a trade added to a TimeWindowVWAP in tests.
+type timedTrade struct {
+	after     time.Duration // Since the start of the test.
+	units     string
+	unitPrice string
+}
+
+func TestNewTimeWindowVWAPErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name          string
+		giveWindow    time.Duration
+		giveIncrement decimal.Decimal
+		expectedErr   string
+	}{
+		{
+			name:          "zero_window",
+			giveIncrement: decimal.MustParse("0.01"),
+			expectedErr:   "window 0s must be positive",
+		},
+		{
+			name:          "negative_window",
+			giveWindow:    -time.Minute,
+			giveIncrement: decimal.MustParse("0.01"),
+			expectedErr:   "window -1m0s must be positive",
+		},
+		{
+			name:          "zero_increment",
+			giveWindow:    time.Minute,
+			giveIncrement: decimal.MustParse("0"),
+			expectedErr:   "increment 0 must be positive",
+		},
+		{
+			name:          "negative_increment",
+			giveWindow:    time.Minute,
+			giveIncrement: decimal.MustParse("-0.01"),
+			expectedErr:   "increment -0.01 must be positive",
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := NewTimeWindowVWAP(tc.giveWindow, tc.giveIncrement, nil)
+
+			assert.Nil(t, actual, "Actual")
+			assert.EqualError(t, actualErr, tc.expectedErr, "Actual err")
+		})
+	}
+}
+
+func TestTimeWindowVWAPAdd(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name          string
+		giveTrades    []timedTrade
+		giveClock     time.Duration // Since the start of the test, when the last trade is added.
+		expected      string
+		expectedCount int
+	}{
+		{
+			name:          "one_trade",
+			giveTrades:    []timedTrade{{after: 0, units: "2.5", unitPrice: "1.2"}},
+			expected:      "1.20",
+			expectedCount: 1,
+		},
+		{
+			name: "within_window",
+			giveTrades: []timedTrade{
+				{after: 0, units: "1", unitPrice: "1"},
+				{after: time.Minute, units: "2", unitPrice: "1"},
+				{after: time.Minute * 4, units: "1", unitPrice: "2"},
+			},
+			giveClock:     time.Minute * 4,
+			expected:      "1.25", // 5 total price / 4 total units
+			expectedCount: 3,
+		},
+		{
+			name: "evicts_by_trade_time",
+			giveTrades: []timedTrade{
+				{after: 0, units: "10", unitPrice: "5"},
+				{after: time.Minute * 5, units: "1", unitPrice: "2"},
+			},
+			expected:      "2.00", // The first trade is exactly 5 minutes old.
+			expectedCount: 1,
+		},
+		{
+			name: "evicts_by_clock",
+			giveTrades: []timedTrade{
+				{after: 0, units: "10", unitPrice: "5"},
+				{after: time.Minute * 3, units: "1", unitPrice: "2"},
+			},
+			giveClock:     time.Minute * 8,
+			expected:      "0.00", // The clock is ahead, both are at least 5 minutes old.
+			expectedCount: 0,
+		},
+		{
+			name: "out_of_order",
+			giveTrades: []timedTrade{
+				{after: time.Minute * 2, units: "1", unitPrice: "3"},
+				{after: time.Minute, units: "1", unitPrice: "1"},
+				{after: time.Minute * 3, units: "2", unitPrice: "2"},
+			},
+			expected:      "2.00", // 8 total price / 4 total units
+			expectedCount: 3,
+		},
+		{
+			name: "ignores_outside_window",
+			giveTrades: []timedTrade{
+				{after: time.Minute * 10, units: "1", unitPrice: "3"},
+				{after: time.Minute * 4, units: "10", unitPrice: "1"},
+			},
+			expected:      "3.00",
+			expectedCount: 1,
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Setup
+
+			clock := &clockFake{now: start}
+			s, err := NewTimeWindowVWAP(time.Minute*5, decimal.MustParse("0.01"), clock.Now)
+			require.NoError(t, err, "NewTimeWindowVWAP")
+
+			// Do
+
+			var actual decimal.Decimal
+			for a, trade := range tc.giveTrades {
+				if a == len(tc.giveTrades)-1 {
+					clock.now = start.Add(tc.giveClock)
+				}
+
+				actual = s.Add(start.Add(trade.after), decimal.MustParse(trade.units), decimal.MustParse(trade.unitPrice))
+			}
+
+			// Assert
+
+			assert.Equal(t, tc.expected, actual.String(), "Actual")
+			assert.Equal(t, tc.expectedCount, s.Len(), "Len")
+		})
+	}
+}
+
+func TestTimeWindowVWAPDecays(t *testing.T) {
+	t.Parallel()
+
+	// Setup
+
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	clock := &clockFake{now: start}
+	s, err := NewTimeWindowVWAP(time.Minute, decimal.MustParse("0.01"), clock.Now)
+	require.NoError(t, err, "NewTimeWindowVWAP")
+
+	s.Add(start, decimal.MustParse("1"), decimal.MustParse("1"))
+	s.Add(start.Add(time.Second*30), decimal.MustParse("1"), decimal.MustParse("2"))
+
+	// Do & Assert
+
+	clock.now = start.Add(time.Second * 45)
+	assert.Equal(t, "1.50", s.VWAP().String(), "VWAP after 45s")
+	assert.Equal(t, []string{"0.2500", "0.50", "2.00", "2.50", "3.00", "1.00", "0.50", "0.00"}, bandsStrings(s.Bands()), "Bands after 45s")
+
+	clock.now = start.Add(time.Minute)
+	assert.Equal(t, "2.00", s.VWAP().String(), "VWAP after 1m")
+	assert.Equal(t, 1, s.Len(), "Len after 1m")
+	assert.Equal(t, []string{"0.0000", "0.00", "2.00", "2.00", "2.00", "2.00", "2.00", "2.00"}, bandsStrings(s.Bands()), "Bands after 1m")
+
+	clock.now = start.Add(time.Minute + time.Second*30)
+	assert.Equal(t, "0.00", s.VWAP().String(), "VWAP after 1m30s")
+	assert.Equal(t, 0, s.Len(), "Len after 1m30s")
+}
+
+func TestTimeWindowVWAPZeroValue(t *testing.T) {
+	t.Parallel()
+
+	s := &TimeWindowVWAP{}
+
+	assert.Equal(t, "0", s.Add(time.Now(), decimal.MustParse("1"), decimal.MustParse("1")).String(), "Add")
+	assert.Equal(t, "0", s.VWAP().String(), "VWAP")
+	assert.Equal(t, DecimalBands{}, s.Bands(), "Bands")
+}